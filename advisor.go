@@ -0,0 +1,145 @@
+package jsontrim
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+)
+
+// Advisor aggregates the sizes of observed payloads and the outcomes of
+// their trims, and suggests FieldLimit and TotalLimit values that would leave
+// a given share of payloads intact. It keeps a fixed-size uniform sample, so
+// memory doesn't grow with traffic. It is safe for concurrent use.
+//
+//	advisor := jsontrim.NewAdvisor(0)
+//	advisor.Observe(raw)
+//	out, res, err := trimmer.TrimWithResult(raw)
+//	advisor.ObserveResult(res)
+//	...
+//	trimmer = advisor.Tune(trimmer, 0.99, jsontrim.LimitBounds{MaxTotal: 64 << 10})
+type Advisor struct {
+	mu      sync.Mutex
+	size    int // Sample capacity
+	seen    int // Payloads observed
+	samples []sizeSample
+	results int // Trim results observed
+	trimmed int // Of which changed something
+}
+
+// sizeSample is one payload sampled by an Advisor.
+type sizeSample struct {
+	total int
+	field int // Largest top-level field, or -1 if the payload isn't an object
+}
+
+// Suggestion is the limits an Advisor recommends.
+type Suggestion struct {
+	FieldLimit  int     // Largest top-level field size at the quantile
+	TotalLimit  int     // Document size at the quantile
+	Samples     int     // Payloads the suggestion is based on
+	TrimmedRate float64 // Share of observed trims that changed something
+}
+
+// LimitBounds keeps Advisor.Tune within a range. Zero bounds are ignored.
+type LimitBounds struct {
+	MinField, MaxField int
+	MinTotal, MaxTotal int
+}
+
+// NewAdvisor returns an Advisor sampling up to size payloads (default: 1024).
+func NewAdvisor(size int) *Advisor {
+	if size <= 0 {
+		size = 1024
+	}
+	return &Advisor{size: size}
+}
+
+// Observe records the size of raw and of its largest top-level field, as
+// given. Field sizes are only known for JSON objects; nested fields aren't
+// measured separately.
+func (a *Advisor) Observe(raw []byte) {
+	largest := -1
+	ScanFields(raw, func(_, value []byte) bool {
+		largest = max(largest, len(value))
+		return true
+	})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.seen++
+	sample := sizeSample{total: len(raw), field: largest}
+	if len(a.samples) < a.size {
+		a.samples = append(a.samples, sample)
+		return
+	}
+	// Reservoir sampling: the n-th payload replaces a sample with probability size/n
+	if i := rand.IntN(a.seen); i < a.size {
+		a.samples[i] = sample
+	}
+}
+
+// ObserveResult records the outcome of a trim.
+func (a *Advisor) ObserveResult(res TrimResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.results++
+	if len(res.PathsAffected) > 0 {
+		a.trimmed++
+	}
+}
+
+// Suggest returns limits that would have left the share q (e.g. 0.99) of the
+// sampled payloads untouched. Limits are zero while nothing was observed.
+func (a *Advisor) Suggest(q float64) Suggestion {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	totals := make([]int, 0, len(a.samples))
+	var fields []int
+	for _, sample := range a.samples {
+		totals = append(totals, sample.total)
+		if sample.field >= 0 {
+			fields = append(fields, sample.field)
+		}
+	}
+	s := Suggestion{Samples: len(a.samples), TotalLimit: quantile(totals, q), FieldLimit: quantile(fields, q)}
+	if a.results > 0 {
+		s.TrimmedRate = float64(a.trimmed) / float64(a.results)
+	}
+	return s
+}
+
+// Tune returns t with FieldLimit and TotalLimit set from Suggest(q), clamped
+// to bounds, or t itself while nothing was observed.
+func (a *Advisor) Tune(t *Trimmer, q float64, bounds LimitBounds) *Trimmer {
+	s := a.Suggest(q)
+	if s.Samples == 0 {
+		return t
+	}
+	opts := []Option{WithTotalLimit(clampLimit(s.TotalLimit, bounds.MinTotal, bounds.MaxTotal))}
+	if s.FieldLimit > 0 {
+		opts = append(opts, WithFieldLimit(clampLimit(s.FieldLimit, bounds.MinField, bounds.MaxField)))
+	}
+	return t.With(opts...)
+}
+
+// quantile returns the nearest-rank q-quantile of sizes, or 0 if empty.
+func quantile(sizes []int, q float64) int {
+	if len(sizes) == 0 {
+		return 0
+	}
+	sorted := slices.Sorted(slices.Values(sizes))
+	q = min(max(q, 0), 1)
+	i := int(q*float64(len(sorted))+0.999999) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// clampLimit keeps n within lo and hi, ignoring zero bounds.
+func clampLimit(n, lo, hi int) int {
+	if lo > 0 && n < lo {
+		n = lo
+	}
+	if hi > 0 && n > hi {
+		n = hi
+	}
+	return n
+}
@@ -0,0 +1,33 @@
+package jsontrim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdvisor(t *testing.T) {
+	a := NewAdvisor(0)
+	tr := New(Config{})
+	for i := 1; i <= 100; i++ {
+		raw := []byte(`{"id":1,"body":"` + strings.Repeat("x", i*10) + `"}`)
+		a.Observe(raw)
+		_, res, _ := tr.TrimWithResult(raw)
+		a.ObserveResult(res)
+	}
+
+	s := a.Suggest(0.99)
+	if s.Samples != 100 || s.FieldLimit != 992 || s.TotalLimit != 1008 {
+		t.Errorf("Expected the 99th percentile of 100 payloads, got %+v", s)
+	}
+	if s.TrimmedRate <= 0 || s.TrimmedRate >= 1 {
+		t.Errorf("Expected some trims to have changed something, got %v", s.TrimmedRate)
+	}
+
+	tuned := a.Tune(tr, 0.99, LimitBounds{MaxTotal: 1000})
+	if cfg := tuned.Config(); cfg.FieldLimit != 992 || cfg.TotalLimit != 1000 {
+		t.Errorf("Expected limits tuned within bounds, got %d/%d", cfg.FieldLimit, cfg.TotalLimit)
+	}
+	if NewAdvisor(0).Tune(tr, 0.99, LimitBounds{}) != tr {
+		t.Error("Expected no change without observations")
+	}
+}
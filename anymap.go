@@ -0,0 +1,33 @@
+package jsontrim
+
+import "fmt"
+
+// stringKeys returns v with every map[interface{}]interface{} in it, as
+// produced by YAML decoders and some legacy code, converted to
+// map[string]interface{} so it's trimmed like any other object. Keys that
+// aren't strings are formatted with fmt.Sprint, as encoding a YAML key 1 or
+// true gives "1" or "true"; when two keys format alike, one of them wins.
+// Other containers are rewritten in place.
+func stringKeys(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for k, val := range vv {
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			out[key] = stringKeys(val)
+		}
+		return out
+	case map[string]interface{}:
+		for k, val := range vv {
+			vv[k] = stringKeys(val)
+		}
+	case []interface{}:
+		for i, val := range vv {
+			vv[i] = stringKeys(val)
+		}
+	}
+	return v
+}
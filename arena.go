@@ -0,0 +1,60 @@
+package jsontrim
+
+import "sync"
+
+// arenaChunk is the number of elements in each nodeArena chunk. Arrays over a
+// quarter of it get their own allocation.
+const arenaChunk = 4096
+
+// nodeArena hands out the backing arrays of the []interface{} values decoded
+// for one trim, carved from a few large chunks instead of allocated one by
+// one, and is reused by later trims once the tree is dead (see Config.Arena).
+type nodeArena struct {
+	chunk []interface{} // Current chunk; len is what's handed out
+	stack []interface{} // Elements of the arrays being decoded, innermost last
+}
+
+var arenaPool = sync.Pool{New: func() interface{} { return &nodeArena{} }}
+
+// take returns the elements pushed since start as an array in the arena, and
+// pops them. The array's capacity is its length, so appending to it later
+// reallocates rather than overwriting a neighbour.
+func (a *nodeArena) take(start int) []interface{} {
+	items := a.stack[start:]
+	var out []interface{}
+	switch n := len(items); {
+	case n == 0:
+		out = []interface{}{}
+	case n > arenaChunk/4:
+		out = append([]interface{}(nil), items...)
+	default:
+		if cap(a.chunk)-len(a.chunk) < n {
+			a.chunk = make([]interface{}, 0, arenaChunk) // The full chunk is left to the GC
+		}
+		i := len(a.chunk)
+		a.chunk = append(a.chunk, items...)
+		out = a.chunk[i:len(a.chunk):len(a.chunk)]
+	}
+	clear(items)
+	a.stack = a.stack[:start]
+	return out
+}
+
+// release clears the arena, so it doesn't keep the tree's values alive, and
+// returns it to the pool.
+func (a *nodeArena) release() {
+	clear(a.chunk)
+	a.chunk = a.chunk[:0]
+	clear(a.stack[:cap(a.stack)])
+	a.stack = a.stack[:0]
+	arenaPool.Put(a)
+}
+
+// releaseArena returns the arena the call decoded into, if any. The tree must
+// not be used afterwards.
+func (t *Trimmer) releaseArena() {
+	if t.arena != nil {
+		t.arena.release()
+		t.arena = nil
+	}
+}
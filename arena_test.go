@@ -0,0 +1,71 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestArena(t *testing.T) {
+	rows := make([]interface{}, 200)
+	for i := range rows {
+		rows[i] = []interface{}{i, "x", []interface{}{true, nil}}
+	}
+	raw, _ := json.Marshal(map[string]interface{}{"rows": rows, "empty": []interface{}{}})
+
+	plain := New(Config{TotalLimit: 1 << 20, FieldLimit: 1 << 20, Blacklist: []string{"none"}})
+	arena := New(Config{TotalLimit: 1 << 20, FieldLimit: 1 << 20, Blacklist: []string{"none"}, Arena: true})
+	want, err := plain.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ { // Reused arenas must not leak into later trims
+		got, err := arena.Trim(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Fatalf("Expected %s, got %s", want, got)
+		}
+	}
+
+	plainAllocs := testing.AllocsPerRun(20, func() { plain.Trim(raw) })
+	arenaAllocs := testing.AllocsPerRun(20, func() { arena.Trim(raw) })
+	if arenaAllocs >= plainAllocs {
+		t.Errorf("Expected fewer allocations with Arena, got %v vs %v", arenaAllocs, plainAllocs)
+	}
+
+	// Without a Blacklist, Arena must not cost more than the default decode
+	defaults := New(Config{TotalLimit: 1 << 20, FieldLimit: 1 << 20})
+	withArena := New(Config{TotalLimit: 1 << 20, FieldLimit: 1 << 20, Arena: true})
+	if got, want := testing.AllocsPerRun(20, func() { withArena.Trim(raw) }), testing.AllocsPerRun(20, func() { defaults.Trim(raw) }); got > want {
+		t.Errorf("Expected Arena no worse than the default decode, got %v vs %v allocations", got, want)
+	}
+}
+
+// BenchmarkArena compares Arena against the default decode and against the
+// token decode a Blacklist uses, on an object-heavy document.
+func BenchmarkArena(b *testing.B) {
+	rows := make([]interface{}, 200)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": "x", "tags": []interface{}{"a", "b"}, "ok": true}
+	}
+	raw, _ := json.Marshal(map[string]interface{}{"rows": rows})
+	for _, bm := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"default", Config{}},
+		{"default+arena", Config{Arena: true}},
+		{"blacklist", Config{Blacklist: []string{"none"}}},
+		{"blacklist+arena", Config{Blacklist: []string{"none"}, Arena: true}},
+	} {
+		bm.cfg.TotalLimit, bm.cfg.FieldLimit = 1<<20, 1<<20
+		tr := New(bm.cfg)
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tr.Trim(raw)
+			}
+		})
+	}
+}
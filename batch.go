@@ -0,0 +1,113 @@
+package jsontrim
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TrimAll trims several documents so their combined size stays within
+// TotalLimit. The budget is split fairly: documents smaller than their share
+// are kept whole and the unused remainder is redistributed to the larger ones.
+// Documents that cannot be trimmed get a nil entry and contribute to the
+// returned error; the others are still returned.
+func (t *Trimmer) TrimAll(docs [][]byte) ([][]byte, error) {
+	return t.TrimAllWeighted(docs, nil)
+}
+
+// TrimAllWeighted is TrimAll with per-document priority weights. A document
+// with weight 2 receives twice the share of one with weight 1. A nil or short
+// weights slice treats missing entries as 1.
+func (t *Trimmer) TrimAllWeighted(docs [][]byte, weights []int) ([][]byte, error) {
+	t = t.current()
+	needs := make([]int, len(docs))
+	for i, doc := range docs {
+		// Re-encode to measure the compact size the output would have untrimmed
+		needs[i] = t.measure(doc)
+		if v, err := t.cfg.Codec.Decode(doc); err == nil {
+			if encoded, err := t.encode(v); err == nil {
+				needs[i] = t.measure(encoded)
+			}
+		}
+	}
+
+	shares := allocate(t.cfg.TotalLimit, needs, weights)
+
+	out := make([][]byte, len(docs))
+	var errs []error
+	for i, doc := range docs {
+		trimmed, err := t.withTotalLimit(shares[i]).Trim(doc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("doc %d: %w", i, err))
+			continue
+		}
+		out[i] = trimmed
+	}
+	return out, errors.Join(errs...)
+}
+
+// allocate splits budget across needs by weight (water-filling). Entries whose
+// need fits in their share are satisfied exactly and drop out; the rest compete
+// for what's left until no more entries can be satisfied.
+func allocate(budget int, needs, weights []int) []int {
+	weight := func(i int) int {
+		if i < len(weights) && weights[i] > 0 {
+			return weights[i]
+		}
+		return 1
+	}
+
+	shares := make([]int, len(needs))
+	pending := make([]int, 0, len(needs))
+	for i := range needs {
+		pending = append(pending, i)
+	}
+
+	for len(pending) > 0 {
+		totalWeight := 0
+		for _, i := range pending {
+			totalWeight += weight(i)
+		}
+
+		roundBudget := budget
+		next := pending[:0:0]
+		for _, i := range pending {
+			if needs[i] <= roundBudget*weight(i)/totalWeight {
+				shares[i] = needs[i]
+				budget -= needs[i]
+			} else {
+				next = append(next, i)
+			}
+		}
+
+		if len(next) == len(pending) {
+			// Nobody fits: everyone left gets their proportional share.
+			for _, i := range next {
+				shares[i] = budget * weight(i) / totalWeight
+			}
+			break
+		}
+		pending = next
+	}
+	return shares
+}
+
+// TrimMap trims a set of named payloads (request, response, headers, ...)
+// each to its own budget. Keys missing from budgets use TotalLimit. Results
+// and errors are keyed by payload name; a key appears in exactly one of them.
+func (t *Trimmer) TrimMap(docs map[string][]byte, budgets map[string]int) (map[string][]byte, map[string]error) {
+	out := make(map[string][]byte, len(docs))
+	errs := make(map[string]error)
+	for name, doc := range docs {
+		tr := t
+		if limit, ok := budgets[name]; ok {
+			tr = t.withTotalLimit(limit)
+		}
+		trimmed, err := tr.Trim(doc)
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+		out[name] = trimmed
+	}
+	return out, errs
+}
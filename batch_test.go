@@ -0,0 +1,59 @@
+package jsontrim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrimAllSharesBudget(t *testing.T) {
+	small := []byte(`{"id":1}`)
+	big := []byte(`{"a":"` + strings.Repeat("x", 300) + `","b":"` + strings.Repeat("y", 300) + `"}`)
+	trimmer := New(Config{TotalLimit: 500})
+
+	out, err := trimmer.TrimAll([][]byte{small, big, big})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, doc := range out {
+		total += len(doc)
+	}
+	if total > 500 {
+		t.Errorf("Combined output over limit: %d > 500", total)
+	}
+	if string(out[0]) != string(small) {
+		t.Errorf("Small doc should be kept whole, got %s", out[0])
+	}
+}
+
+func TestAllocateWeighted(t *testing.T) {
+	shares := allocate(300, []int{1000, 1000, 50}, []int{2, 1})
+	if shares[2] != 50 {
+		t.Errorf("Expected small doc to get its full need, got %d", shares[2])
+	}
+	if shares[0] != 2*shares[1] && shares[0] != 2*shares[1]+1 {
+		t.Errorf("Expected 2:1 split, got %d:%d", shares[0], shares[1])
+	}
+}
+
+func TestTrimMap(t *testing.T) {
+	docs := map[string][]byte{
+		"request":  []byte(`{"a":"` + strings.Repeat("x", 100) + `","b":1}`),
+		"response": []byte(`{"ok":true}`),
+		"headers":  []byte(`"` + strings.Repeat("h", 100) + `"`),
+	}
+	out, errs := New(Config{}).TrimMap(docs, map[string]int{"request": 50, "headers": 10})
+
+	if len(out["request"]) > 50 {
+		t.Errorf("request over budget: %s", out["request"])
+	}
+	if string(out["response"]) != `{"ok":true}` {
+		t.Errorf("response should use default limit, got %s", out["response"])
+	}
+	if errs["headers"] != ErrCannotTrim {
+		t.Errorf("Expected ErrCannotTrim for headers, got %v", errs["headers"])
+	}
+	if _, ok := out["headers"]; ok {
+		t.Error("Failed key should not appear in results")
+	}
+}
@@ -0,0 +1,383 @@
+package jsontrim
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BSONMaxDocumentSize is MongoDB's maximum document size, a natural TotalLimit
+// for BSONCodec.
+const BSONMaxDocumentSize = 16 * 1024 * 1024
+
+// BSONCodec decodes and encodes BSON documents, so oversized MongoDB documents
+// or oplog entries can be trimmed with the usual strategies; limits are
+// measured against the BSON size.
+//
+// Doubles decode as float64, int32/int64 as int32/int64, binary as BSONBinary
+// and the BSON-specific types as BSONObjectID, BSONDecimal128, BSONDateTime,
+// BSONTimestamp, BSONRegex, BSONJavaScript, BSONMinKey and BSONMaxKey, all of
+// which round-trip unchanged and are treated as single opaque values.
+// Undefined decodes as nil. Field order is not preserved: "_id" is written
+// first and the remaining keys in sorted order.
+type BSONCodec struct{}
+
+// BSON-specific value types.
+type (
+	BSONObjectID   [12]byte
+	BSONDecimal128 [16]byte // Raw little-endian IEEE 754-2008 decimal128
+	BSONDateTime   int64    // Milliseconds since the Unix epoch
+	BSONTimestamp  struct{ T, I uint32 }
+	BSONBinary     struct {
+		Subtype byte
+		Data    []byte
+	}
+	BSONRegex struct {
+		Pattern string
+		Options string
+	}
+	BSONJavaScript string
+	BSONMinKey     struct{}
+	BSONMaxKey     struct{}
+)
+
+// String returns the ObjectID in its usual 24-digit hex form.
+func (id BSONObjectID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// MarshalJSON renders the ObjectID like MongoDB Extended JSON.
+func (id BSONObjectID) MarshalJSON() ([]byte, error) {
+	return []byte(`{"$oid":"` + id.String() + `"}`), nil
+}
+
+// ErrBSON indicates malformed BSON input or a value BSON can't represent.
+var ErrBSON = errors.New("invalid bson")
+
+// Decode implements Codec.
+func (BSONCodec) Decode(data []byte) (interface{}, error) {
+	d := &bsonDecoder{buf: data}
+	v, err := d.document(0, false)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.buf) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrBSON, len(d.buf)-d.pos)
+	}
+	return v, nil
+}
+
+// Encode implements Codec. The top-level value must be a map.
+func (BSONCodec) Encode(v interface{}) ([]byte, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: top-level value must be a document, got %T", ErrBSON, v)
+	}
+	return bsonAppendDoc(nil, m)
+}
+
+type bsonDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *bsonDecoder) take(n int) ([]byte, error) {
+	if n < 0 || n > len(d.buf)-d.pos {
+		return nil, fmt.Errorf("%w: unexpected end of input", ErrBSON)
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *bsonDecoder) int32() (int32, error) {
+	b, err := d.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(b)), nil
+}
+
+func (d *bsonDecoder) uint64() (uint64, error) {
+	b, err := d.take(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (d *bsonDecoder) cstring() (string, error) {
+	end := -1
+	for i := d.pos; i < len(d.buf); i++ {
+		if d.buf[i] == 0 {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return "", fmt.Errorf("%w: unterminated cstring", ErrBSON)
+	}
+	s := string(d.buf[d.pos:end])
+	d.pos = end + 1
+	return s, nil
+}
+
+func (d *bsonDecoder) str() (string, error) {
+	n, err := d.int32()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.take(int(n))
+	if err != nil || n < 1 || b[n-1] != 0 {
+		return "", fmt.Errorf("%w: bad string", ErrBSON)
+	}
+	return string(b[:n-1]), nil
+}
+
+// document decodes an embedded document; array documents decode to a slice.
+func (d *bsonDecoder) document(depth int, array bool) (interface{}, error) {
+	if depth > maxNesting {
+		return nil, fmt.Errorf("%w: nesting too deep", ErrBSON)
+	}
+	start := d.pos
+	size, err := d.int32()
+	if err != nil {
+		return nil, err
+	}
+	if size < 5 || int(size) > len(d.buf)-start {
+		return nil, fmt.Errorf("%w: bad document size %d", ErrBSON, size)
+	}
+	end := start + int(size)
+
+	var arr []interface{}
+	var doc map[string]interface{}
+	if array {
+		arr = []interface{}{}
+	} else {
+		doc = make(map[string]interface{})
+	}
+
+	for d.pos < end-1 {
+		tb, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		key, err := d.cstring()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.element(tb[0], depth)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if array {
+			arr = append(arr, v)
+		} else {
+			doc[key] = v
+		}
+	}
+	if d.pos != end-1 || d.buf[d.pos] != 0 {
+		return nil, fmt.Errorf("%w: document size mismatch", ErrBSON)
+	}
+	d.pos = end
+	if array {
+		return arr, nil
+	}
+	return doc, nil
+}
+
+func (d *bsonDecoder) element(typ byte, depth int) (interface{}, error) {
+	switch typ {
+	case 0x01:
+		u, err := d.uint64()
+		return math.Float64frombits(u), err
+	case 0x02:
+		return d.str()
+	case 0x03:
+		return d.document(depth+1, false)
+	case 0x04:
+		return d.document(depth+1, true)
+	case 0x05:
+		n, err := d.int32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.take(int(n) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return BSONBinary{Subtype: b[0], Data: append([]byte(nil), b[1:]...)}, nil
+	case 0x06, 0x0A: // undefined, null
+		return nil, nil
+	case 0x07:
+		b, err := d.take(12)
+		if err != nil {
+			return nil, err
+		}
+		var id BSONObjectID
+		copy(id[:], b)
+		return id, nil
+	case 0x08:
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case 0x09:
+		u, err := d.uint64()
+		return BSONDateTime(int64(u)), err
+	case 0x0B:
+		pattern, err := d.cstring()
+		if err != nil {
+			return nil, err
+		}
+		options, err := d.cstring()
+		return BSONRegex{Pattern: pattern, Options: options}, err
+	case 0x0D:
+		s, err := d.str()
+		return BSONJavaScript(s), err
+	case 0x10:
+		return d.int32()
+	case 0x11:
+		u, err := d.uint64()
+		return BSONTimestamp{T: uint32(u >> 32), I: uint32(u)}, err
+	case 0x12:
+		u, err := d.uint64()
+		return int64(u), err
+	case 0x13:
+		b, err := d.take(16)
+		if err != nil {
+			return nil, err
+		}
+		var dec BSONDecimal128
+		copy(dec[:], b)
+		return dec, nil
+	case 0xFF:
+		return BSONMinKey{}, nil
+	case 0x7F:
+		return BSONMaxKey{}, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported element type 0x%02x", ErrBSON, typ)
+}
+
+// bsonAppendDoc appends m as a BSON document ("_id" first, then sorted keys).
+func bsonAppendDoc(b []byte, m map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k != "_id" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := m["_id"]; ok {
+		keys = append([]string{"_id"}, keys...)
+	}
+
+	start := len(b)
+	b = append(b, 0, 0, 0, 0) // Size placeholder
+	var err error
+	for _, k := range keys {
+		if b, err = bsonAppendElem(b, k, m[k]); err != nil {
+			return nil, err
+		}
+	}
+	b = append(b, 0)
+	binary.LittleEndian.PutUint32(b[start:], uint32(len(b)-start))
+	return b, nil
+}
+
+func bsonAppendArray(b []byte, arr []interface{}) ([]byte, error) {
+	start := len(b)
+	b = append(b, 0, 0, 0, 0)
+	var err error
+	for i, item := range arr {
+		if b, err = bsonAppendElem(b, strconv.Itoa(i), item); err != nil {
+			return nil, err
+		}
+	}
+	b = append(b, 0)
+	binary.LittleEndian.PutUint32(b[start:], uint32(len(b)-start))
+	return b, nil
+}
+
+func bsonAppendStr(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s)+1))
+	return append(append(b, s...), 0)
+}
+
+// bsonAppendElem appends the element key: v.
+func bsonAppendElem(b []byte, key string, v interface{}) ([]byte, error) {
+	if strings.IndexByte(key, 0) >= 0 {
+		return nil, fmt.Errorf("%w: key %q contains NUL", ErrBSON, key)
+	}
+	head := func(typ byte) []byte {
+		return append(append(append(b, typ), key...), 0)
+	}
+
+	switch vv := v.(type) {
+	case nil:
+		return head(0x0A), nil
+	case bool:
+		if vv {
+			return append(head(0x08), 1), nil
+		}
+		return append(head(0x08), 0), nil
+	case float64:
+		return binary.LittleEndian.AppendUint64(head(0x01), math.Float64bits(vv)), nil
+	case float32:
+		return binary.LittleEndian.AppendUint64(head(0x01), math.Float64bits(float64(vv))), nil
+	case string:
+		return bsonAppendStr(head(0x02), vv), nil
+	case int32:
+		return binary.LittleEndian.AppendUint32(head(0x10), uint32(vv)), nil
+	case int64:
+		return binary.LittleEndian.AppendUint64(head(0x12), uint64(vv)), nil
+	case int:
+		if vv >= math.MinInt32 && vv <= math.MaxInt32 {
+			return binary.LittleEndian.AppendUint32(head(0x10), uint32(int32(vv))), nil
+		}
+		return binary.LittleEndian.AppendUint64(head(0x12), uint64(vv)), nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(vv), 10, 64); err == nil {
+			return bsonAppendElem(b, key, i)
+		}
+		f, err := vv.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return bsonAppendElem(b, key, f)
+	case []byte:
+		return bsonAppendElem(b, key, BSONBinary{Data: vv})
+	case BSONBinary:
+		b = binary.LittleEndian.AppendUint32(head(0x05), uint32(len(vv.Data)))
+		return append(append(b, vv.Subtype), vv.Data...), nil
+	case BSONObjectID:
+		return append(head(0x07), vv[:]...), nil
+	case BSONDateTime:
+		return binary.LittleEndian.AppendUint64(head(0x09), uint64(vv)), nil
+	case BSONRegex:
+		b = append(append(head(0x0B), vv.Pattern...), 0)
+		return append(append(b, vv.Options...), 0), nil
+	case BSONJavaScript:
+		return bsonAppendStr(head(0x0D), string(vv)), nil
+	case BSONTimestamp:
+		return binary.LittleEndian.AppendUint64(head(0x11), uint64(vv.T)<<32|uint64(vv.I)), nil
+	case BSONDecimal128:
+		return append(head(0x13), vv[:]...), nil
+	case BSONMinKey:
+		return head(0xFF), nil
+	case BSONMaxKey:
+		return head(0x7F), nil
+	case map[string]interface{}:
+		return bsonAppendDoc(head(0x03), vv)
+	case []interface{}:
+		return bsonAppendArray(head(0x04), vv)
+	}
+	return nil, fmt.Errorf("%w: unsupported type %T", ErrBSON, v)
+}
@@ -0,0 +1,91 @@
+package jsontrim
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBSONRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"_id":     BSONObjectID{0x65, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+		"nil":     nil,
+		"bool":    true,
+		"double":  1.5,
+		"int32":   int32(-7),
+		"int64":   int64(1 << 40),
+		"str":     "hello",
+		"arr":     []interface{}{"a", int32(1), map[string]interface{}{"x": false}},
+		"empty":   []interface{}{},
+		"bin":     BSONBinary{Subtype: 4, Data: []byte{1, 2, 3}},
+		"date":    BSONDateTime(1700000000000),
+		"ts":      BSONTimestamp{T: 1700000000, I: 3},
+		"dec":     BSONDecimal128{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x40, 0x30},
+		"re":      BSONRegex{Pattern: "^a", Options: "i"},
+		"code":    BSONJavaScript("return 1"),
+		"min":     BSONMinKey{},
+		"max":     BSONMaxKey{},
+		"nested":  map[string]interface{}{},
+		"nulbyte": "a\x00b",
+	}
+	encoded, err := BSONCodec{}.Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded[4] != 0x07 || string(encoded[5:8]) != "_id" {
+		t.Errorf("Expected _id as the first element, got % x", encoded[4:8])
+	}
+	out, err := BSONCodec{}.Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Round trip mismatch:\n in: %#v\nout: %#v", in, out)
+	}
+
+	// {"a": 1} as int32
+	raw := []byte{0x0c, 0, 0, 0, 0x10, 'a', 0, 1, 0, 0, 0, 0}
+	v, err := BSONCodec{}.Decode(raw)
+	if err != nil || !reflect.DeepEqual(v, map[string]interface{}{"a": int32(1)}) {
+		t.Errorf("Decode(% x) = %#v, %v", raw, v, err)
+	}
+	for _, bad := range [][]byte{raw[:11], {0x05, 0, 0, 0, 1}, {0x08, 0, 0, 0, 0x0c, 'a', 0, 0}} {
+		if _, err := (BSONCodec{}).Decode(bad); !errors.Is(err, ErrBSON) {
+			t.Errorf("Decode(% x): expected ErrBSON, got %v", bad, err)
+		}
+	}
+	if _, err := (BSONCodec{}).Encode([]interface{}{1}); !errors.Is(err, ErrBSON) {
+		t.Errorf("Expected ErrBSON for top-level array, got %v", err)
+	}
+}
+
+func TestTrimBSON(t *testing.T) {
+	id := BSONObjectID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	doc := map[string]interface{}{
+		"_id":      id,
+		"name":     "order-1",
+		"history":  []interface{}{strings.Repeat("h", 100), strings.Repeat("h", 100)},
+		"password": "secret",
+	}
+	raw, _ := BSONCodec{}.Encode(doc)
+
+	out, err := New(Config{TotalLimit: 80, Codec: BSONCodec{}, Blacklist: []string{"password"}, Required: []string{"_id"}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 80 {
+		t.Errorf("BSON output over limit: %d", len(out))
+	}
+	v, err := BSONCodec{}.Decode(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	if m["_id"] != id {
+		t.Errorf("ObjectID not preserved: %#v", m["_id"])
+	}
+	if _, ok := m["password"]; ok {
+		t.Error("Blacklist not applied to BSON input")
+	}
+}
@@ -0,0 +1,161 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// subtreeLimit returns the SubtreeLimits budget for path, if any. When several
+// rules match, the smallest wins.
+func (t *Trimmer) subtreeLimit(path []string) (int, bool) {
+	limit, found := 0, false
+	for _, r := range t.subtreeRules {
+		if matchParts(r.parts, path) && (!found || r.limit < limit) {
+			limit, found = r.limit, true
+		}
+	}
+	return limit, found
+}
+
+// shrinksLater reports whether v is a container at path with a SubtreeLimits
+// budget within limit, so enforceSubtreeLimits shrinks it inside rather than
+// FieldLimit dropping it whole.
+func (t *Trimmer) shrinksLater(v interface{}, path []string, limit int) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		sub, ok := t.subtreeLimit(path)
+		return ok && sub <= limit
+	}
+	return false
+}
+
+// enforceSubtreeLimits shrinks every subtree named in SubtreeLimits to its
+// budget, innermost first, so nested budgets are settled before the ones
+// around them. Subtrees holding Required fields are left to enforceRequired.
+func (t *Trimmer) enforceSubtreeLimits(v interface{}, path []string) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		before := len(vv)
+		for k, val := range vv {
+			childPath := append(path, k)
+			if shrunk := t.enforceSubtreeLimits(val, childPath); shrunk != removed {
+				vv[k] = shrunk
+			} else if t.cfg.ReplaceWithMarker {
+				vv[k] = marked
+			} else {
+				delete(vv, k)
+			}
+		}
+		if t.prunes(path, before, len(vv)) {
+			return removed
+		}
+	case []interface{}:
+		out := vv[:0]
+		for i, item := range vv {
+			if shrunk := t.enforceSubtreeLimits(item, append(path, strconv.Itoa(i))); shrunk != removed {
+				out = append(out, shrunk)
+			} else if t.cfg.ReplaceWithMarker {
+				out = append(out, marked)
+			}
+		}
+		if t.prunes(path, len(vv), len(out)) {
+			return removed
+		}
+		v = out
+	}
+
+	limit, ok := t.subtreeLimit(path)
+	if !ok || len(path) == 0 || t.protects(path) {
+		return v
+	}
+	return t.shrinkTo(v, limit, path, reasonSubtreeLimit)
+}
+
+// enforceWeighted splits TotalLimit across the top-level fields of an object
+// by Weights and trims each field to its own share, instead of letting the
+// strategy remove whole fields. Shares left unused by small fields go to the
+// larger ones (see allocate). Required fields are never shrunk here.
+func (t *Trimmer) enforceWeighted(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return v
+	}
+	if size, err := t.totalSize(m); err != nil || size <= t.cfg.TotalLimit {
+		return v
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Each field costs "key": plus its value plus a comma; the braces come off the top
+	needs := make([]int, len(keys))
+	keyCosts := make([]int, len(keys))
+	weights := make([]int, len(keys))
+	for i, k := range keys {
+		quoted, _ := json.Marshal(k)
+		keyCosts[i] = t.measure(quoted) + 2
+		needs[i] = keyCosts[i] + t.sizeOf(m[k])
+		weights[i] = t.cfg.Weights[k]
+	}
+	shares := allocate(t.cfg.TotalLimit-2, needs, weights)
+
+	for i, k := range keys {
+		if needs[i] <= shares[i] || t.protects([]string{k}) {
+			continue
+		}
+		if shrunk := t.shrinkTo(m[k], shares[i]-keyCosts[i], []string{k}, reasonWeight); shrunk != removed {
+			m[k] = shrunk
+		} else if t.cfg.ReplaceWithMarker {
+			m[k] = marked
+		} else {
+			delete(m, k)
+		}
+	}
+	return m
+}
+
+// shrinkTo trims the subtree at path to at most limit units, or SizeFunc's
+// measure when set: containers through the strategy, strings by truncation
+// when TruncateStrings is set. It returns removed if v can't be made to fit.
+func (t *Trimmer) shrinkTo(v interface{}, limit int, path []string, reason string) interface{} {
+	if size, err := t.totalSize(v); err == nil && size <= limit {
+		return v
+	}
+	t.record(path, reason, v)
+	if limit <= 0 {
+		return removed
+	}
+	sub := t.withTotalLimit(limit)
+	sub.requiredParts = nil // Required paths are rooted at the document, not this subtree
+	sub.strategyRules = nil
+	if s, ok := t.strategyAt(path); ok {
+		sub.cfg.Strategy = s
+	}
+	sub.stats = nil
+
+	switch vv := v.(type) {
+	case map[string]interface{}, []interface{}:
+		v = sub.enforceTotal(vv)
+	case string:
+		if t.cfg.TruncateStrings {
+			// Escaping can make the encoded string longer, so shrink until it fits
+			for n := limit - 5; n > 0; {
+				s := t.truncate(vv, n) + "..."
+				over := t.sizeOf(s) - limit
+				if over <= 0 {
+					return s
+				}
+				n -= over
+			}
+		}
+	}
+
+	if size, err := t.totalSize(v); err != nil || size > limit {
+		return removed
+	}
+	return v
+}
@@ -0,0 +1,85 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWeights(t *testing.T) {
+	in := map[string]interface{}{
+		"error":    strings.Repeat("e", 400),
+		"request":  []interface{}{strings.Repeat("q", 100), strings.Repeat("q", 100), strings.Repeat("q", 100)},
+		"response": strings.Repeat("r", 400),
+		"id":       "abc",
+	}
+	raw, _ := json.Marshal(in)
+
+	trimmer := New(Config{
+		TotalLimit:      500,
+		FieldLimit:      1000,
+		TruncateStrings: true,
+		Weights:         map[string]int{"error": 5, "request": 3, "response": 2},
+	})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 500 {
+		t.Errorf("Output over limit: %d", len(out))
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["id"] != "abc" {
+		t.Errorf("Small field should be kept whole, got %v", got["id"])
+	}
+	errLen, respLen := len(got["error"].(string)), len(got["response"].(string))
+	if errLen <= respLen || respLen == 0 {
+		t.Errorf("Expected every field to keep a weighted share, got error=%d response=%d", errLen, respLen)
+	}
+	if n := len(got["request"].([]interface{})); n == 0 || n == 3 {
+		t.Errorf("Expected request to be partially trimmed, got %d items", n)
+	}
+}
+
+func TestSubtreeLimits(t *testing.T) {
+	headers := map[string]interface{}{}
+	for _, h := range []string{"accept", "cookie", "user-agent", "x-trace"} {
+		headers[h] = strings.Repeat("h", 60)
+	}
+	in := map[string]interface{}{
+		"request":  map[string]interface{}{"method": "GET", "headers": headers},
+		"response": map[string]interface{}{"status": 200.0, "body": strings.Repeat("b", 300)},
+	}
+	raw, _ := json.Marshal(in)
+
+	trimmer := New(Config{
+		TotalLimit:      4096,
+		TruncateStrings: true,
+		SubtreeLimits:   map[string]int{"request.headers": 150, "*.body": 100},
+	})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Request  map[string]json.RawMessage
+		Response map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(got.Request["headers"]); n == 0 || n > 150 {
+		t.Errorf("headers should be trimmed to <= 150 bytes, got %d", n)
+	}
+	if n := len(got.Response["body"]); n == 0 || n > 100 {
+		t.Errorf("body should be truncated to <= 100 bytes, got %d", n)
+	}
+	if string(got.Request["method"]) != `"GET"` || string(got.Response["status"]) != "200" {
+		t.Errorf("Fields outside limited subtrees should be untouched: %s", out)
+	}
+}
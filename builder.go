@@ -0,0 +1,278 @@
+package jsontrim
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidConfig indicates a Config rejected by Builder.Build.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Builder assembles a Config step by step and validates it in Build, unlike
+// New, which accepts any Config:
+//
+//	t, err := jsontrim.NewBuilder().
+//		TotalLimit(4096).
+//		Blacklist("*.password").
+//		Build()
+type Builder struct {
+	cfg Config
+}
+
+// NewBuilder returns a Builder for an empty Config.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// FieldLimit sets Config.FieldLimit.
+func (b *Builder) FieldLimit(n int) *Builder { b.cfg.FieldLimit = n; return b }
+
+// TotalLimit sets Config.TotalLimit.
+func (b *Builder) TotalLimit(n int) *Builder { b.cfg.TotalLimit = n; return b }
+
+// MaxDepth sets Config.MaxDepth.
+func (b *Builder) MaxDepth(n int) *Builder { b.cfg.MaxDepth = n; return b }
+
+// Blacklist adds paths or queries to Config.Blacklist.
+func (b *Builder) Blacklist(paths ...string) *Builder {
+	b.cfg.Blacklist = append(b.cfg.Blacklist, paths...)
+	return b
+}
+
+// Whitelist adds paths or queries to Config.Whitelist.
+func (b *Builder) Whitelist(paths ...string) *Builder {
+	b.cfg.Whitelist = append(b.cfg.Whitelist, paths...)
+	return b
+}
+
+// Required adds paths to Config.Required.
+func (b *Builder) Required(paths ...string) *Builder {
+	b.cfg.Required = append(b.cfg.Required, paths...)
+	return b
+}
+
+// Atomic adds paths to Config.Atomic.
+func (b *Builder) Atomic(paths ...string) *Builder {
+	b.cfg.Atomic = append(b.cfg.Atomic, paths...)
+	return b
+}
+
+// SubtreeLimit adds a Config.SubtreeLimits entry.
+func (b *Builder) SubtreeLimit(path string, n int) *Builder {
+	if b.cfg.SubtreeLimits == nil {
+		b.cfg.SubtreeLimits = map[string]int{}
+	}
+	b.cfg.SubtreeLimits[path] = n
+	return b
+}
+
+// KeepLast adds a Config.KeepLast entry.
+func (b *Builder) KeepLast(path string, n int) *Builder {
+	if b.cfg.KeepLast == nil {
+		b.cfg.KeepLast = map[string]int{}
+	}
+	b.cfg.KeepLast[path] = n
+	return b
+}
+
+// Rename adds a Config.Rename entry.
+func (b *Builder) Rename(path, to string) *Builder {
+	if b.cfg.Rename == nil {
+		b.cfg.Rename = map[string]string{}
+	}
+	b.cfg.Rename[path] = to
+	return b
+}
+
+// DropIf adds a Config.DropIf entry.
+func (b *Builder) DropIf(path, cond string) *Builder {
+	if b.cfg.DropIf == nil {
+		b.cfg.DropIf = map[string]string{}
+	}
+	b.cfg.DropIf[path] = cond
+	return b
+}
+
+// Strategy sets Config.Strategy.
+func (b *Builder) Strategy(s TruncStrategy) *Builder { b.cfg.Strategy = s; return b }
+
+// Transformers adds to Config.Transformers.
+func (b *Builder) Transformers(tfs ...Transformer) *Builder {
+	b.cfg.Transformers = append(b.cfg.Transformers, tfs...)
+	return b
+}
+
+// Marker sets Config.ReplaceWithMarker.
+func (b *Builder) Marker(on bool) *Builder { b.cfg.ReplaceWithMarker = on; return b }
+
+// TruncateStrings sets Config.TruncateStrings.
+func (b *Builder) TruncateStrings(on bool) *Builder { b.cfg.TruncateStrings = on; return b }
+
+// Hooks sets Config.Hooks.
+func (b *Builder) Hooks(h Hooks) *Builder { b.cfg.Hooks = h; return b }
+
+// Codec sets Config.Codec.
+func (b *Builder) Codec(c Codec) *Builder { b.cfg.Codec = c; return b }
+
+// Apply runs opts against the Config, for fields without a Builder method.
+func (b *Builder) Apply(opts ...Option) *Builder {
+	for _, opt := range opts {
+		opt(&b.cfg)
+	}
+	return b
+}
+
+// Build validates the Config and compiles it. Every problem found is
+// reported, joined, in an error wrapping ErrInvalidConfig.
+func (b *Builder) Build() (*Trimmer, error) {
+	if err := validateConfig(b.cfg); err != nil {
+		return nil, err
+	}
+	t := New(b.cfg)
+	if t.err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, t.err)
+	}
+	return t, nil
+}
+
+// validateConfig checks the parts of cfg New can't reject: negative sizes,
+// malformed paths and nil callbacks. Queries and conditions are checked when
+// they're compiled.
+func validateConfig(cfg Config) error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	for _, f := range []struct {
+		name string
+		n    int
+	}{{"FieldLimit", cfg.FieldLimit}, {"TotalLimit", cfg.TotalLimit}, {"MaxDepth", cfg.MaxDepth}, {"MaxFields", cfg.MaxFields}, {"MaxMemory", cfg.MaxMemory}} {
+		if f.n < 0 {
+			fail("%s is negative (%d)", f.name, f.n)
+		}
+	}
+	if cfg.Unit != Bytes && cfg.Unit != UTF16 {
+		fail("unknown Unit %d", cfg.Unit)
+	}
+	if cfg.OversizePolicy < OversizeTrim || cfg.OversizePolicy > OversizePassThrough {
+		fail("unknown OversizePolicy %d", cfg.OversizePolicy)
+	}
+	switch c := cfg.Codec.(type) {
+	case JSONCodec:
+		if c.DuplicateKeys < LastWins || c.DuplicateKeys > MergeDuplicates {
+			fail("unknown DuplicateKeys policy %d", c.DuplicateKeys)
+		}
+	case LenientJSONCodec:
+		if c.DuplicateKeys < LastWins || c.DuplicateKeys > MergeDuplicates {
+			fail("unknown DuplicateKeys policy %d", c.DuplicateKeys)
+		}
+	}
+	if cfg.NonFinite < NonFiniteKeep || cfg.NonFinite > NonFiniteString {
+		fail("unknown NonFinite policy %d", cfg.NonFinite)
+	}
+
+	checkPath := func(field, p string) {
+		if p == "" || strings.Contains("."+p+".", "..") {
+			fail("%s: malformed path %q", field, p)
+		}
+	}
+	for _, p := range cfg.Blacklist {
+		if !isQuery(p) {
+			checkPath("Blacklist", p)
+		}
+	}
+	for _, p := range cfg.Whitelist {
+		if !isQuery(p) {
+			checkPath("Whitelist", p)
+		}
+	}
+	for _, p := range cfg.Required {
+		checkPath("Required", p)
+	}
+	for _, p := range cfg.Atomic {
+		checkPath("Atomic", p)
+	}
+	for p, n := range cfg.SubtreeLimits {
+		checkPath("SubtreeLimits", p)
+		if n < 0 {
+			fail("SubtreeLimits: negative limit for %q", p)
+		}
+	}
+	for p, n := range cfg.KeepLast {
+		checkPath("KeepLast", p)
+		if n < 0 {
+			fail("KeepLast: negative count for %q", p)
+		}
+	}
+	for p, to := range cfg.Rename {
+		checkPath("Rename", p)
+		if to == "" {
+			fail("Rename: empty new name for %q", p)
+		}
+	}
+	for p := range cfg.DropIf {
+		checkPath("DropIf", p)
+	}
+	for p, fn := range cfg.FieldHooks {
+		checkPath("FieldHooks", p)
+		if fn == nil {
+			fail("FieldHooks: nil hook for %q", p)
+		}
+	}
+	for k, w := range cfg.Weights {
+		if w < 0 {
+			fail("Weights: negative weight for %q", k)
+		}
+	}
+	for i, p := range cfg.Passes {
+		if p == nil {
+			fail("Passes: nil pass at %d", i)
+		}
+	}
+	for i, tf := range cfg.Transformers {
+		if tf == nil {
+			fail("Transformers: nil transformer at %d", i)
+		} else if sel, ok := tf.(Select); ok && sel.Transformer == nil {
+			fail("Transformers: Select at %d has no Transformer", i)
+		}
+	}
+
+	// Settings valid on their own that contradict each other
+	if cfg.Strict {
+		if cfg.TruncateStrings && cfg.FieldLimit > 0 && cfg.FieldLimit <= len(`"..."`) {
+			fail("TruncateStrings: FieldLimit %d leaves no room for any text before the \"...\" suffix", cfg.FieldLimit)
+		}
+		if cfg.TotalLimit > 0 && cfg.TotalLimit < len("{}") {
+			fail("TotalLimit %d is below the smallest document, {}", cfg.TotalLimit)
+		}
+		for _, r := range cfg.Required {
+			for _, b := range cfg.Blacklist {
+				if !isQuery(b) && covers(strings.Split(b, "."), strings.Split(r, ".")) {
+					fail("Required path %q is removed by Blacklist path %q", r, b)
+				}
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+}
+
+// covers reports whether rule matches path or one of its ancestors for
+// every key a wildcard in path stands for, so whatever path matches is
+// removed. A wildcard in path is only covered by one in rule.
+func covers(rule, path []string) bool {
+	if len(rule) > len(path) {
+		return false
+	}
+	for i, part := range rule {
+		if part != "*" && part != path[i] {
+			return false
+		}
+	}
+	return true
+}
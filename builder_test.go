@@ -0,0 +1,87 @@
+package jsontrim
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	trimmer, err := NewBuilder().
+		TotalLimit(4096).
+		Blacklist("user.password", `.events[] | select(.debug)`).
+		Rename("msg", "message").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := trimmer.Trim([]byte(`{"msg":"hi","user":{"password":"x"},"events":[{"debug":true},{"id":1}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"events":[{"id":1}],"message":"hi","user":{}}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	_, err := NewBuilder().
+		FieldLimit(-1).
+		Blacklist("a..b", ".x[").
+		SubtreeLimit("body", -5).
+		Rename("msg", "").
+		Build()
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Expected ErrInvalidConfig, got %v", err)
+	}
+	for _, want := range []string{"FieldLimit", `"a..b"`, "SubtreeLimits", "Rename"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error should mention %s: %v", want, err)
+		}
+	}
+
+	// Queries are checked once the rest of the config is valid
+	if _, err := NewBuilder().Blacklist(".x[").Build(); !errors.Is(err, ErrInvalidQuery) || !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig wrapping ErrInvalidQuery, got %v", err)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	cfg := Config{
+		FieldLimit:      4,
+		TotalLimit:      1,
+		TruncateStrings: true,
+		Blacklist:       []string{"user.*"},
+		Required:        []string{"user.id", "ts"},
+	}
+	if err := New(cfg).Err(); err != nil {
+		t.Errorf("Expected no error without Strict, got %v", err)
+	}
+
+	cfg.Strict = true
+	trimmer := New(cfg)
+	err := trimmer.Err()
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Expected ErrInvalidConfig, got %v", err)
+	}
+	for _, want := range []string{"FieldLimit 4", "TotalLimit 1", `"user.id" is removed by Blacklist path "user.*"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+	if _, err := trimmer.Trim([]byte(`{}`)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected trims to fail, got %v", err)
+	}
+	if _, err := NewBuilder().Apply(func(c *Config) { *c = cfg }).Build(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected Build to fail, got %v", err)
+	}
+
+	for _, blacklist := range []string{"users.admin", "users.*.name", "*.admin.id"} {
+		if err := New(Config{Strict: true, Blacklist: []string{blacklist}, Required: []string{"users.*.id"}}).Err(); err != nil {
+			t.Errorf("Blacklist %q only removes some users.*.id, got %v", blacklist, err)
+		}
+	}
+	if err := New(Config{Strict: true, Blacklist: []string{"*.*"}, Required: []string{"users.*.id"}}).Err(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected a Blacklist removing every users.*.id rejected, got %v", err)
+	}
+}
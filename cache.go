@@ -0,0 +1,80 @@
+package jsontrim
+
+import "unsafe"
+
+// nodeID identifies a map, slice or string of the tree being trimmed by its
+// backing storage and length. Trimming only ever removes entries from the
+// containers it keeps, so a container that changed no longer matches.
+type nodeID struct {
+	p unsafe.Pointer
+	n int
+}
+
+// sizeCache remembers the encoded size of nodes measured during one trim, so
+// a child measured precisely by trimFields isn't encoded again when
+// enforceTotal removes it.
+type sizeCache map[nodeID]int
+
+// idOf returns the nodeID of v, if v is a non-empty container or string.
+func idOf(v interface{}) (nodeID, bool) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if len(vv) > 0 {
+			return nodeID{*(*unsafe.Pointer)(unsafe.Pointer(&vv)), len(vv)}, true
+		}
+	case []interface{}:
+		if len(vv) > 0 {
+			return nodeID{unsafe.Pointer(&vv[0]), len(vv)}, true
+		}
+	case string:
+		if len(vv) > 0 {
+			return nodeID{unsafe.Pointer(unsafe.StringData(vv)), len(vv)}, true
+		}
+	}
+	return nodeID{}, false
+}
+
+// cachesSizes reports whether node sizes may be cached. Steps that rewrite
+// values deep inside kept containers, leaving their length unchanged
+// (SubtreeLimits, Weights, Strategies and custom Passes), turn it off.
+func (t *Trimmer) cachesSizes() bool {
+	return len(t.subtreeRules) == 0 && len(t.cfg.Weights) == 0 && len(t.strategyRules) == 0 && len(t.cfg.Passes) == 0
+}
+
+// sizeOf returns the size of v checked against the limits: SizeFunc if set,
+// otherwise its encoded size in Unit, or 0 if it can't be encoded.
+func (t *Trimmer) sizeOf(v interface{}) int {
+	id, ok := idOf(v)
+	if ok && t.sizes != nil {
+		if size, hit := t.sizes[id]; hit {
+			return size
+		}
+	}
+	var size int
+	if t.cfg.SizeFunc != nil {
+		size = t.cfg.SizeFunc(v)
+	} else {
+		encoded, err := t.encode(v)
+		if err != nil {
+			return 0
+		}
+		size = t.measure(encoded)
+	}
+	if ok && t.sizes != nil {
+		t.sizes[id] = size
+	}
+	return size
+}
+
+// totalSize returns the size of the document v checked against TotalLimit:
+// SizeFunc if set, otherwise its encoded size in Unit.
+func (t *Trimmer) totalSize(v interface{}) (int, error) {
+	if t.cfg.SizeFunc != nil {
+		return t.cfg.SizeFunc(v), nil
+	}
+	encoded, err := t.encode(v)
+	if err != nil {
+		return 0, err
+	}
+	return t.measure(encoded), nil
+}
@@ -0,0 +1,26 @@
+package jsontrim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSizeCache(t *testing.T) {
+	tr := New(Config{})
+	tr.sizes = sizeCache{}
+	m := map[string]interface{}{"a": strings.Repeat("x", 10), "b": 1.0}
+	if got := tr.sizeOf(m); got != 24 {
+		t.Fatalf("Expected 24, got %d", got)
+	}
+	if len(tr.sizes) != 1 {
+		t.Errorf("Expected the size cached, got %v", tr.sizes)
+	}
+	delete(m, "b")
+	if got := tr.sizeOf(m); got != 18 {
+		t.Errorf("Expected a shrunk map to be measured again, got %d", got)
+	}
+
+	if New(Config{Weights: map[string]int{"a": 1}}).cachesSizes() {
+		t.Error("Expected Weights to turn the cache off")
+	}
+}
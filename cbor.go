@@ -0,0 +1,377 @@
+package jsontrim
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// CBORCodec decodes and encodes CBOR (RFC 8949), e.g. IoT telemetry sent over
+// MQTT, so it can be trimmed to radio-friendly sizes with the same strategies
+// and blacklist rules. Limits are measured against the CBOR size.
+//
+// Integers decode as int64 (uint64 above math.MaxInt64), byte strings as
+// []byte, undefined as nil and tagged values as CBORTag. Indefinite-length
+// items are accepted and re-encoded with definite lengths. Non-string map keys
+// are converted with fmt.Sprint.
+type CBORCodec struct{}
+
+// CBORTag is a tagged CBOR value (e.g., tag 1 for epoch timestamps). The
+// trimmer treats it as a single opaque value.
+type CBORTag struct {
+	Number  uint64
+	Content interface{}
+}
+
+// ErrCBOR indicates malformed CBOR input.
+var ErrCBOR = errors.New("invalid cbor")
+
+// Decode implements Codec.
+func (CBORCodec) Decode(data []byte) (interface{}, error) {
+	d := &cborDecoder{buf: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.buf) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrCBOR, len(d.buf)-d.pos)
+	}
+	return v, nil
+}
+
+// Encode implements Codec.
+func (CBORCodec) Encode(v interface{}) ([]byte, error) {
+	return cborAppend(nil, v)
+}
+
+// errCBORBreak is returned by value when it reads the "break" stop code. It
+// only escapes as an error when the break is misplaced.
+var errCBORBreak = fmt.Errorf("%w: unexpected break", ErrCBOR)
+
+type cborDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *cborDecoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.buf)-d.pos) {
+		return nil, fmt.Errorf("%w: unexpected end of input", ErrCBOR)
+	}
+	b := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// arg reads the argument for additional info ai. indefinite is true for ai 31.
+func (d *cborDecoder) arg(ai byte) (n uint64, indefinite bool, err error) {
+	switch {
+	case ai < 24:
+		return uint64(ai), false, nil
+	case ai <= 27:
+		b, err := d.take(1 << (ai - 24))
+		if err != nil {
+			return 0, false, err
+		}
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, false, nil
+	case ai == 31:
+		return 0, true, nil
+	}
+	return 0, false, fmt.Errorf("%w: reserved additional info %d", ErrCBOR, ai)
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxNesting {
+		return nil, fmt.Errorf("%w: nesting too deep", ErrCBOR)
+	}
+	hb, err := d.take(1)
+	if err != nil {
+		return nil, err
+	}
+	major, ai := hb[0]>>5, hb[0]&0x1f
+
+	if major == 7 {
+		return d.simple(ai)
+	}
+	n, indefinite, err := d.arg(ai)
+	if err != nil {
+		return nil, err
+	}
+	if indefinite && (major == 0 || major == 1 || major == 6) {
+		return nil, fmt.Errorf("%w: indefinite length on major type %d", ErrCBOR, major)
+	}
+
+	switch major {
+	case 0:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 1:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: negative integer out of range", ErrCBOR)
+		}
+		return -1 - int64(n), nil
+	case 2, 3:
+		var b []byte
+		if indefinite {
+			b, err = d.chunks(major)
+		} else {
+			var raw []byte
+			raw, err = d.take(n)
+			b = append([]byte(nil), raw...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if major == 3 {
+			return string(b), nil
+		}
+		return b, nil
+	case 4:
+		var out []interface{}
+		if !indefinite {
+			if n > uint64(len(d.buf)-d.pos) {
+				return nil, fmt.Errorf("%w: unexpected end of input", ErrCBOR)
+			}
+			out = make([]interface{}, 0, n)
+		}
+		for i := uint64(0); indefinite || i < n; i++ {
+			v, err := d.value(depth + 1)
+			if err == errCBORBreak && indefinite {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		if out == nil {
+			out = []interface{}{}
+		}
+		return out, nil
+	case 5:
+		if !indefinite && n > uint64(len(d.buf)-d.pos)/2 {
+			return nil, fmt.Errorf("%w: unexpected end of input", ErrCBOR)
+		}
+		out := make(map[string]interface{})
+		for i := uint64(0); indefinite || i < n; i++ {
+			k, err := d.value(depth + 1)
+			if err == errCBORBreak && indefinite {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			out[key] = v
+		}
+		return out, nil
+	case 6:
+		content, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return CBORTag{Number: n, Content: content}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown major type %d", ErrCBOR, major)
+}
+
+// chunks concatenates the definite-length chunks of an indefinite string.
+func (d *cborDecoder) chunks(major byte) ([]byte, error) {
+	var out []byte
+	for {
+		hb, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		if hb[0] == 0xff {
+			return out, nil
+		}
+		if hb[0]>>5 != major {
+			return nil, fmt.Errorf("%w: bad chunk in indefinite string", ErrCBOR)
+		}
+		n, indefinite, err := d.arg(hb[0] & 0x1f)
+		if err != nil || indefinite {
+			return nil, fmt.Errorf("%w: bad chunk in indefinite string", ErrCBOR)
+		}
+		b, err := d.take(n)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+	}
+}
+
+func (d *cborDecoder) simple(ai byte) (interface{}, error) {
+	switch ai {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null, undefined
+		return nil, nil
+	case 25:
+		b, err := d.take(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfToFloat(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 31:
+		return nil, errCBORBreak
+	}
+	return nil, fmt.Errorf("%w: unsupported simple value %d", ErrCBOR, ai)
+}
+
+// halfToFloat converts an IEEE 754 half-precision float.
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
+
+// cborHead appends a major type header with argument n.
+func cborHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, m|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, m|27), n)
+	}
+}
+
+func cborAppendInt(b []byte, i int64) []byte {
+	if i >= 0 {
+		return cborHead(b, 0, uint64(i))
+	}
+	return cborHead(b, 1, uint64(-1-i))
+}
+
+// cborAppend appends the CBOR encoding of v to b.
+func cborAppend(b []byte, v interface{}) ([]byte, error) {
+	switch vv := v.(type) {
+	case nil:
+		return append(b, 0xf6), nil
+	case bool:
+		if vv {
+			return append(b, 0xf5), nil
+		}
+		return append(b, 0xf4), nil
+	case string:
+		return append(cborHead(b, 3, uint64(len(vv))), vv...), nil
+	case []byte:
+		return append(cborHead(b, 2, uint64(len(vv))), vv...), nil
+	case float64:
+		// Use single precision when it is lossless, as preferred serialization does
+		if f32 := float32(vv); float64(f32) == vv {
+			return binary.BigEndian.AppendUint32(append(b, 0xfa), math.Float32bits(f32)), nil
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(vv)), nil
+	case float32:
+		return binary.BigEndian.AppendUint32(append(b, 0xfa), math.Float32bits(vv)), nil
+	case int:
+		return cborAppendInt(b, int64(vv)), nil
+	case int8:
+		return cborAppendInt(b, int64(vv)), nil
+	case int16:
+		return cborAppendInt(b, int64(vv)), nil
+	case int32:
+		return cborAppendInt(b, int64(vv)), nil
+	case int64:
+		return cborAppendInt(b, vv), nil
+	case uint:
+		return cborHead(b, 0, uint64(vv)), nil
+	case uint8:
+		return cborHead(b, 0, uint64(vv)), nil
+	case uint16:
+		return cborHead(b, 0, uint64(vv)), nil
+	case uint32:
+		return cborHead(b, 0, uint64(vv)), nil
+	case uint64:
+		return cborHead(b, 0, vv), nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(vv), 10, 64); err == nil {
+			return cborAppendInt(b, i), nil
+		}
+		f, err := vv.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return cborAppend(b, f)
+	case CBORTag:
+		return cborAppend(cborHead(b, 6, vv.Number), vv.Content)
+	case []interface{}:
+		b = cborHead(b, 4, uint64(len(vv)))
+		var err error
+		for _, item := range vv {
+			if b, err = cborAppend(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		b = cborHead(b, 5, uint64(len(vv)))
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var err error
+		for _, k := range keys {
+			b = append(cborHead(b, 3, uint64(len(k))), k...)
+			if b, err = cborAppend(b, vv[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported type %T", v)
+}
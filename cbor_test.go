@@ -0,0 +1,92 @@
+package jsontrim
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"nil":    nil,
+		"bools":  []interface{}{true, false},
+		"ints":   []interface{}{int64(0), int64(23), int64(24), int64(-1), int64(-1000), int64(math.MinInt64), uint64(math.MaxUint64)},
+		"floats": []interface{}{1.5, 0.1},
+		"str":    strings.Repeat("s", 300),
+		"bytes":  []byte{1, 2, 3},
+		"tag":    CBORTag{Number: 1, Content: int64(1700000000)},
+		"empty":  []interface{}{},
+	}
+	encoded, err := CBORCodec{}.Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := CBORCodec{}.Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Round trip mismatch:\n in: %#v\nout: %#v", in, out)
+	}
+}
+
+func TestCBORDecodeRFCVectors(t *testing.T) {
+	cases := []struct {
+		raw  []byte
+		want interface{}
+	}{
+		{[]byte{0xf9, 0x3c, 0x00}, 1.0}, // half-precision 1.0
+		{[]byte{0x3b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, int64(math.MinInt64)},
+		{[]byte{0x7f, 0x62, 'a', 'b', 0x61, 'c', 0xff}, "abc"}, // indefinite text
+		{[]byte{0x9f, 0x01, 0x02, 0xff}, []interface{}{int64(1), int64(2)}},
+		{[]byte{0xbf, 0x61, 'a', 0x01, 0xff}, map[string]interface{}{"a": int64(1)}},
+		{[]byte{0xf7}, nil}, // undefined
+	}
+	for _, c := range cases {
+		got, err := CBORCodec{}.Decode(c.raw)
+		if err != nil {
+			t.Errorf("Decode(% x): %v", c.raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Decode(% x) = %#v, want %#v", c.raw, got, c.want)
+		}
+	}
+
+	for _, raw := range [][]byte{{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, {0x1c}, {0x61}} {
+		if _, err := (CBORCodec{}).Decode(raw); !errors.Is(err, ErrCBOR) {
+			t.Errorf("Decode(% x): expected ErrCBOR, got %v", raw, err)
+		}
+	}
+}
+
+func TestTrimCBOR(t *testing.T) {
+	doc := map[string]interface{}{
+		"device":   "sensor-1",
+		"readings": []interface{}{1.5, 2.5, 3.5},
+		"debug":    strings.Repeat("d", 200),
+		"secret":   "k",
+	}
+	raw, _ := CBORCodec{}.Encode(doc)
+
+	out, err := New(Config{TotalLimit: 64, Codec: CBORCodec{}, Blacklist: []string{"secret"}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 64 {
+		t.Errorf("CBOR output over limit: %d", len(out))
+	}
+	v, err := CBORCodec{}.Decode(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	if m["device"] != "sensor-1" {
+		t.Errorf("Lost small field: %#v", m)
+	}
+	if _, ok := m["secret"]; ok {
+		t.Error("Blacklist not applied to CBOR input")
+	}
+}
@@ -0,0 +1,210 @@
+package jsontrim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Codec converts between an encoded document and the generic tree the Trimmer
+// works on: map[string]interface{}, []interface{}, strings, numbers, bools and
+// nil. FieldLimit and TotalLimit are measured against Encode's output, so a
+// binary codec enforces limits on the binary size.
+type Codec interface {
+	Decode(data []byte) (interface{}, error)
+	Encode(v interface{}) ([]byte, error)
+}
+
+// JSONCodec is the default Codec, backed by encoding/json. By default numbers
+// decode to float64, which silently corrupts integers beyond ±2^53 (IDs,
+// counters, nanosecond timestamps).
+type JSONCodec struct {
+	UseNumber        bool                         // Decode numbers as json.Number, written out verbatim (default: false)
+	BigIntsAsStrings bool                         // Without UseNumber, decode integers beyond ±2^53 as strings instead of rounded floats (default: false)
+	DuplicateKeys    DuplicateKeyPolicy           // Which value a repeated object key keeps: LastWins, FirstWins, MergeDuplicates or ErrorOnDuplicate (default: LastWins)
+	OnDuplicate      func(key string, offset int) // Called for each repeated key, with the input offset just past it (optional)
+}
+
+// maxSafeInt is the largest integer float64 holds exactly.
+const maxSafeInt = 1 << 53
+
+// Decode implements Codec.
+func (c JSONCodec) Decode(data []byte) (interface{}, error) {
+	if c.checksDuplicates() {
+		return (&Trimmer{}).decodeTree(c, data, false)
+	}
+	var v interface{}
+	if !c.UseNumber && !c.BigIntsAsStrings {
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	if c.UseNumber {
+		return v, nil
+	}
+	return unsafeIntsToStrings(v), nil
+}
+
+// checksDuplicates reports whether decoding must look for repeated keys,
+// which encoding/json silently resolves as LastWins.
+func (c JSONCodec) checksDuplicates() bool {
+	return c.DuplicateKeys != LastWins || c.OnDuplicate != nil
+}
+
+// Encode implements Codec.
+func (JSONCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// unsafeIntsToStrings replaces the json.Numbers in v with float64, or with
+// their text for integers float64 can't hold exactly.
+func unsafeIntsToStrings(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case json.Number:
+		if isUnsafeInt(string(vv)) {
+			return string(vv)
+		}
+		f, _ := vv.Float64()
+		return f
+	case map[string]interface{}:
+		for k, val := range vv {
+			vv[k] = unsafeIntsToStrings(val)
+		}
+	case []interface{}:
+		for i, val := range vv {
+			vv[i] = unsafeIntsToStrings(val)
+		}
+	}
+	return v
+}
+
+// isUnsafeInt reports whether the JSON number n is an integer beyond ±2^53.
+func isUnsafeInt(n string) bool {
+	if strings.ContainsAny(n, ".eE") {
+		return false
+	}
+	i, err := strconv.ParseInt(n, 10, 64)
+	return err != nil || i > maxSafeInt || i < -maxSafeInt
+}
+
+// maxEscapeRatio is the most raw JSON bytes one encoded unit of a decoded
+// string can take: a \u0041 escape re-encodes as a single byte.
+const maxEscapeRatio = 6
+
+// capStrings returns raw with every string whose raw content is longer than
+// limit bytes cut down to about limit bytes, on a character and escape
+// boundary. With limit at maxEscapeRatio times FieldLimit, a cut string still
+// decodes to more than FieldLimit, so trimming treats it exactly as it would
+// the whole string. Object keys are never cut, and neither are strings at
+// paths keeps reports true for; a nil keeps cuts every other string. raw is
+// returned as is if nothing is cut.
+func capStrings(raw []byte, limit int, keeps func(path []string) bool) []byte {
+	var (
+		out  []byte
+		last int      // raw[:last] has been handled
+		open []capped // Containers around the current position
+	)
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '{':
+			open = append(open, capped{index: -1})
+			continue
+		case '[':
+			open = append(open, capped{})
+			continue
+		case '}', ']':
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			continue
+		case ',':
+			if n := len(open); n > 0 && open[n-1].index >= 0 {
+				open[n-1].index++
+			}
+			continue
+		}
+		if raw[i] != '"' {
+			continue
+		}
+		start, cut := i+1, -1
+		j := start
+		for j < len(raw) && raw[j] != '"' {
+			if cut < 0 && j-start >= limit && utf8.RuneStart(raw[j]) {
+				cut = j
+			}
+			switch {
+			case raw[j] != '\\':
+				j++
+			case j+1 < len(raw) && raw[j+1] == 'u':
+				j += 6
+			default:
+				j += 2
+			}
+		}
+		if n := len(open); n > 0 && open[n-1].index < 0 && isKey(raw, j+1) {
+			open[n-1].key = raw[start:min(j, len(raw))]
+			i = j
+			continue
+		}
+		if cut >= 0 && j < len(raw) && (keeps == nil || !keeps(cappedPath(open))) {
+			out = append(append(out, raw[last:cut]...), '"')
+			last = j + 1
+		}
+		i = j
+	}
+	if out == nil {
+		return raw
+	}
+	return append(out, raw[last:]...)
+}
+
+// capped is a container capStrings is inside: an array at index, or an
+// object (index -1) at the raw, still escaped, key.
+type capped struct {
+	index int
+	key   []byte
+}
+
+// isKey reports whether the string ending just before raw[i] is an object key.
+func isKey(raw []byte, i int) bool {
+	for ; i < len(raw); i++ {
+		switch raw[i] {
+		case ' ', '\t', '\n', '\r':
+		case ':':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// cappedPath returns the path of the value capStrings is at.
+func cappedPath(open []capped) []string {
+	path := make([]string, len(open))
+	for i, c := range open {
+		switch {
+		case c.index >= 0:
+			path[i] = strconv.Itoa(c.index)
+		case bytes.IndexByte(c.key, '\\') >= 0:
+			json.Unmarshal(append(append([]byte{'"'}, c.key...), '"'), &path[i])
+		default:
+			path[i] = string(c.key)
+		}
+	}
+	return path
+}
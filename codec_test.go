@@ -0,0 +1,156 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestJSONCodecNumbers(t *testing.T) {
+	raw := []byte(`{"id":9007199254740993,"neg":-9223372036854775809,"n":42,"f":1.5,"big":1e300}`)
+	tests := []struct {
+		codec JSONCodec
+		want  string
+	}{
+		{JSONCodec{}, `{"big":1e+300,"f":1.5,"id":9007199254740992,"n":42,"neg":-9223372036854776000}`},
+		{JSONCodec{BigIntsAsStrings: true}, `{"big":1e+300,"f":1.5,"id":"9007199254740993","n":42,"neg":"-9223372036854775809"}`},
+		{JSONCodec{UseNumber: true}, `{"big":1e300,"f":1.5,"id":9007199254740993,"n":42,"neg":-9223372036854775809}`},
+	}
+	for _, tt := range tests {
+		out, err := New(Config{Codec: tt.codec}).Trim(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.codec, out, tt.want)
+		}
+	}
+
+	if _, err := (JSONCodec{UseNumber: true}).Decode([]byte(`{} {}`)); err == nil {
+		t.Error("Expected an error for trailing data")
+	}
+}
+
+func TestTruncateOnDecode(t *testing.T) {
+	raw := []byte(`{"big":"` + strings.Repeat("\\u00e9x", 2000) + `","small":"ok","n":1}`)
+	capped := capStrings(raw, 60, nil)
+	if len(capped) >= 200 || !json.Valid(capped) {
+		t.Fatalf("Expected a short valid document, got %s", capped)
+	}
+
+	for _, truncate := range []bool{false, true} {
+		cfg := Config{FieldLimit: 10, TotalLimit: 4096, TruncateStrings: truncate}
+		want, err := New(cfg).Trim(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.TruncateOnDecode = true
+		got, err := New(cfg).Trim(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("Expected the same output as a full decode, got %s, want %s", got, want)
+		}
+	}
+
+	long := strings.Repeat("k", 80) // Longer than 6*FieldLimit
+	keyed := []byte(`{"` + long + `1":1,"` + long + `2":"` + strings.Repeat("v", 100) + `","` + long + `3":3}`)
+	if capped := capStrings(keyed, 60, nil); !strings.Contains(string(capped), long+"1") || !strings.Contains(string(capped), long+"3") {
+		t.Errorf("Expected object keys left whole, got %s", capped)
+	}
+	cfg := Config{FieldLimit: 10, TotalLimit: 4096, TruncateOnDecode: true}
+	if out, err := New(cfg).Trim(keyed); err != nil || string(out) != `{"`+long+`1":1,"`+long+`3":3}` {
+		t.Errorf("Expected long keys kept, got %s (%v)", out, err)
+	}
+
+	doc := []byte(`{"msg":"` + strings.Repeat("m", 700) + `","raw":{"k\u0065y":["` + strings.Repeat("r", 700) + `"]},"x":"` + strings.Repeat("x", 700) + `"}`)
+	cfg = Config{FieldLimit: 100, TotalLimit: 4096, Required: []string{"msg"}, Atomic: []string{"raw.key"}}
+	want, wantRes, err := New(cfg).TrimWithResult(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.TruncateOnDecode = true
+	got, res, err := New(cfg).TrimWithResult(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(res.PathsAffected)
+	sort.Strings(wantRes.PathsAffected)
+	if string(got) != string(want) || !reflect.DeepEqual(res.PathsAffected, wantRes.PathsAffected) || !strings.Contains(string(got), strings.Repeat("m", 700)) {
+		t.Errorf("Expected Required and Atomic strings kept whole, got %s (%v)", got, res.PathsAffected)
+	}
+}
+
+// yamlLike decodes to the map[interface{}]interface{} trees YAML decoders build.
+type yamlLike struct{ JSONCodec }
+
+func (yamlLike) Decode([]byte) (interface{}, error) {
+	return map[interface{}]interface{}{
+		"name": "svc",
+		"env":  []interface{}{map[interface{}]interface{}{1: "one", true: strings.Repeat("x", 100)}},
+	}, nil
+}
+
+func TestAnyKeyMaps(t *testing.T) {
+	out, err := New(Config{FieldLimit: 50, Codec: yamlLike{}}).Trim(nil)
+	if err != nil || string(out) != `{"env":[{"1":"one"}],"name":"svc"}` {
+		t.Errorf("Expected YAML-style maps trimmed, got %s (%v)", out, err)
+	}
+
+	hooked := New(Config{FieldLimit: 50, Hooks: Hooks{PreTrim: func(interface{}) interface{} {
+		return map[interface{}]interface{}{"a": strings.Repeat("x", 100), "b": 2}
+	}}})
+	if out, err := hooked.Trim([]byte(`{}`)); err != nil || string(out) != `{"b":2}` {
+		t.Errorf("Expected maps from hooks trimmed, got %s (%v)", out, err)
+	}
+}
+
+func TestJSONCodecDuplicateKeys(t *testing.T) {
+	raw := []byte(`{"id":1,"user":{"name":"a"},"id":2,"user":{"role":"admin"},"id":3}`)
+	tests := []struct {
+		policy DuplicateKeyPolicy
+		want   string
+	}{
+		{LastWins, `{"id":3,"user":{"role":"admin"}}`},
+		{FirstWins, `{"id":1,"user":{"name":"a"}}`},
+		{MergeDuplicates, `{"id":[1,2,3],"user":{"name":"a","role":"admin"}}`},
+	}
+	for _, tt := range tests {
+		var found []string
+		codec := JSONCodec{DuplicateKeys: tt.policy, OnDuplicate: func(key string, offset int) {
+			found = append(found, key+"@"+strconv.Itoa(offset))
+		}}
+		out, err := New(Config{Codec: codec}).Trim(raw)
+		if err != nil || string(out) != tt.want {
+			t.Errorf("Policy %d: got %s (%v), want %s", tt.policy, out, err, tt.want)
+		}
+		if want := []string{"id@32", "user@41", "id@63"}; !reflect.DeepEqual(found, want) {
+			t.Errorf("Policy %d: reported %v, want %v", tt.policy, found, want)
+		}
+	}
+
+	if _, err := New(Config{Codec: JSONCodec{DuplicateKeys: ErrorOnDuplicate}}).Trim(raw); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("Expected ErrDuplicateKey, got %v", err)
+	}
+	if _, err := (JSONCodec{DuplicateKeys: FirstWins}).Decode([]byte(`{"a":1} x`)); err == nil {
+		t.Error("Expected trailing garbage rejected")
+	}
+
+	nested := []byte(`{"a":{"x":1},"a":{"x":2},"a":{"x":3}}`)
+	for _, codec := range []Codec{JSONCodec{DuplicateKeys: MergeDuplicates}, LenientJSONCodec{DuplicateKeys: MergeDuplicates}} {
+		if out, err := New(Config{Codec: codec}).Trim(nested); err != nil || string(out) != `{"a":{"x":[1,2,3]}}` {
+			t.Errorf("%T: expected a third nested duplicate collected, got %s (%v)", codec, out, err)
+		}
+	}
+
+	var reports int
+	strict := JSONCodec{DuplicateKeys: ErrorOnDuplicate, OnDuplicate: func(string, int) { reports++ }}
+	if _, err := New(Config{Codec: strict, Blacklist: []string{"secret"}}).Trim([]byte(`{"secret":1,"secret":2}`)); !errors.Is(err, ErrDuplicateKey) || reports != 1 {
+		t.Errorf("Expected a repeated blacklisted key reported, got %d reports (%v)", reports, err)
+	}
+}
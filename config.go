@@ -0,0 +1,77 @@
+package jsontrim
+
+// ConfigV2 is Config with explicit limits. In Config a zero FieldLimit,
+// TotalLimit or MaxDepth means "use the default", so zero itself can't be
+// asked for. Here a nil limit takes the default and any other value, zero
+// included, is used as given:
+//
+//	t := jsontrim.NewV2(jsontrim.ConfigV2{
+//		MaxDepth: jsontrim.Int(1), // keep the root object, drop everything in it
+//		Config:   jsontrim.Config{Blacklist: []string{"token"}},
+//	})
+//
+// Existing Configs convert with Config.V2.
+type ConfigV2 struct {
+	FieldLimit *int // Max bytes per field/object/array (nil: 500)
+	TotalLimit *int // Max total output bytes (nil: 1024)
+	MaxDepth   *int // Recursion depth limit; the root is at depth 1 (nil: 10)
+	Config          // All other options. Its FieldLimit, TotalLimit and MaxDepth are ignored
+}
+
+// Int returns a pointer to n, for ConfigV2 limits.
+func Int(n int) *int {
+	return &n
+}
+
+// V2 converts c to a ConfigV2 with the same behavior: zero limits become nil
+// and take their defaults.
+func (c Config) V2() ConfigV2 {
+	v2 := ConfigV2{Config: c}
+	if c.FieldLimit != 0 {
+		v2.FieldLimit = Int(c.FieldLimit)
+	}
+	if c.TotalLimit != 0 {
+		v2.TotalLimit = Int(c.TotalLimit)
+	}
+	if c.MaxDepth != 0 {
+		v2.MaxDepth = Int(c.MaxDepth)
+	}
+	return v2
+}
+
+// NewV2 creates a Trimmer from a ConfigV2.
+func NewV2(cfg ConfigV2) *Trimmer {
+	c := cfg.Config
+	c.FieldLimit, c.TotalLimit, c.MaxDepth = 0, 0, 0
+	defaultLimits(&c, nil)
+	if cfg.FieldLimit != nil {
+		c.FieldLimit = *cfg.FieldLimit
+	}
+	if cfg.TotalLimit != nil {
+		c.TotalLimit = *cfg.TotalLimit
+	}
+	if cfg.MaxDepth != nil {
+		c.MaxDepth = *cfg.MaxDepth
+	}
+	t := build(c, nil)
+	t.defaults = limitDefaults{fieldLimit: cfg.FieldLimit == nil, totalLimit: cfg.TotalLimit == nil, maxDepth: cfg.MaxDepth == nil}
+	return t
+}
+
+// ConfigV2 returns the configuration t runs with, like Config, with the
+// limits that took their defaults left nil. Integrations whose natural limit
+// differs from the default can raise just those.
+func (t *Trimmer) ConfigV2() ConfigV2 {
+	t = t.current()
+	v2 := ConfigV2{Config: t.cfg}
+	if !t.defaults.fieldLimit {
+		v2.FieldLimit = Int(t.cfg.FieldLimit)
+	}
+	if !t.defaults.totalLimit {
+		v2.TotalLimit = Int(t.cfg.TotalLimit)
+	}
+	if !t.defaults.maxDepth {
+		v2.MaxDepth = Int(t.cfg.MaxDepth)
+	}
+	return v2
+}
@@ -0,0 +1,45 @@
+package jsontrim
+
+import "testing"
+
+func TestConfigV2(t *testing.T) {
+	raw := []byte(`{"id":1,"user":{"name":"a"}}`)
+
+	// MaxDepth 0 in v1 means the default of 10
+	if out, _ := New(Config{MaxDepth: 0}).Trim(raw); string(out) != string(raw) {
+		t.Errorf("v1: got %s", out)
+	}
+	if out, _ := NewV2(Config{}.V2()).Trim(raw); string(out) != string(raw) {
+		t.Errorf("V2 shim: got %s", out)
+	}
+
+	// In v2 an explicit zero is honored
+	shallow := NewV2(ConfigV2{MaxDepth: Int(1)})
+	if out, _ := shallow.Trim(raw); string(out) != `{}` {
+		t.Errorf("MaxDepth 1: got %s", out)
+	}
+	if out, _ := NewV2(ConfigV2{MaxDepth: Int(0)}).Trim(raw); string(out) != `null` {
+		t.Errorf("MaxDepth 0: got %s", out)
+	}
+	if out, _ := NewV2(ConfigV2{FieldLimit: Int(0), Config: Config{TotalLimit: 4096}}).Trim([]byte(`{"a":"x","b":1}`)); string(out) != `{}` {
+		t.Errorf("FieldLimit 0: got %s", out)
+	}
+
+	// Derived Trimmers keep the explicit zero
+	if out, _ := NewV2(ConfigV2{MaxDepth: Int(0)}).With(WithFieldLimit(10)).Trim(raw); string(out) != `null` {
+		t.Errorf("With after MaxDepth 0: got %s", out)
+	}
+}
+
+func TestConfigV2Defaults(t *testing.T) {
+	v2 := New(Config{FieldLimit: 500}).With(WithMaxDepth(3)).ConfigV2()
+	if v2.FieldLimit == nil || *v2.FieldLimit != 500 || v2.MaxDepth == nil || *v2.MaxDepth != 3 {
+		t.Errorf("Explicit limits should be set, got %+v", v2)
+	}
+	if v2.TotalLimit != nil || v2.Config.TotalLimit != 1024 {
+		t.Errorf("A defaulted TotalLimit should be nil, got %v", v2.TotalLimit)
+	}
+	if v2 := NewV2(ConfigV2{TotalLimit: Int(0)}).ConfigV2(); v2.TotalLimit == nil || v2.FieldLimit != nil {
+		t.Errorf("NewV2 limits should round-trip, got %+v", v2)
+	}
+}
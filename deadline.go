@@ -0,0 +1,113 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// reasonTimeout is reported for nodes dropped by the MaxDuration fallback.
+const reasonTimeout = "timeout"
+
+// trimClock is the MaxDuration deadline of one trim call, shared by the
+// sub-trimmers it spawns.
+type trimClock struct {
+	deadline time.Time
+	expired  bool
+}
+
+// expired reports whether the trim has run past MaxDuration. Once it has,
+// enforcement loops stop and the fallback finishes the job.
+func (t *Trimmer) expired() bool {
+	if t.clock == nil {
+		return false
+	}
+	if !t.clock.expired && time.Now().After(t.clock.deadline) {
+		t.clock.expired = true
+	}
+	return t.clock.expired
+}
+
+// hardFit is the MaxDuration fallback: a single pass that keeps what fits of v
+// in document order (object keys sorted, Required ones first), measuring each
+// child once. It always terminates, at the cost of choosing worse than the
+// strategy would. Required values are never dropped or cut; a Required
+// container that doesn't fit loses its unprotected descendants instead, and
+// enforceRequired fails the trim if that isn't enough.
+func (t *Trimmer) hardFit(v interface{}, limit int, path []string) interface{} {
+	if size, err := t.totalSize(v); err == nil && size <= limit {
+		return v
+	}
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			pi, pj := t.protects(append(path, keys[i])), t.protects(append(path, keys[j]))
+			if pi != pj {
+				return pi
+			}
+			return keys[i] < keys[j]
+		})
+		left := limit - 2 // The braces
+		for _, k := range keys {
+			quoted, _ := json.Marshal(k)
+			cost := t.measure(quoted) + 2 // Colon and comma
+			encoded, err := t.encode(vv[k])
+			if err == nil && cost+t.measure(encoded) <= left {
+				left -= cost + t.measure(encoded)
+				continue
+			}
+			if child := append(path, k); len(t.requiredParts) > 0 && t.protects(child) {
+				vv[k] = t.hardFit(vv[k], left-cost, child)
+				encoded, _ = t.encode(vv[k])
+				left -= cost + t.measure(encoded)
+				continue
+			}
+			t.record(append(path, k), reasonTimeout, vv[k])
+			delete(vv, k)
+		}
+		return vv
+	case []interface{}:
+		left := limit - 2 // The brackets
+		out := vv[:0]
+		for i, item := range vv {
+			encoded, err := t.encode(item)
+			if err == nil && t.measure(encoded)+1 <= left {
+				left -= t.measure(encoded) + 1
+				out = append(out, item)
+				continue
+			}
+			if child := append(path, strconv.Itoa(i)); len(t.requiredParts) > 0 && t.protects(child) {
+				item = t.hardFit(item, left-1, child)
+				encoded, _ = t.encode(item)
+				left -= t.measure(encoded) + 1
+				out = append(out, item)
+				continue
+			}
+			t.record(append(path, strconv.Itoa(i)), reasonTimeout, item)
+			left = -1 // Later items go too, so the kept ones stay in order
+		}
+		return out
+	case string:
+		if len(t.requiredParts) > 0 && t.protects(path) {
+			return v
+		}
+		if t.cfg.TruncateStrings && limit > 5 {
+			s := t.truncate(vv, limit-5) + "..."
+			if encoded, err := t.encode(s); err == nil && t.measure(encoded) <= limit {
+				t.record(path, reasonTimeout, v)
+				return s
+			}
+		}
+	default:
+		if len(t.requiredParts) > 0 && t.protects(path) {
+			return v
+		}
+	}
+	t.record(path, reasonTimeout, v)
+	return nil
+}
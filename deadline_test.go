@@ -0,0 +1,54 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxDuration(t *testing.T) {
+	doc := make(map[string]interface{})
+	for i := 0; i < 2000; i++ {
+		doc[fmt.Sprintf("k%04d", i)] = strings.Repeat("v", 50)
+	}
+	doc["id"] = "keep"
+	raw, _ := json.Marshal(doc)
+
+	cfg := Config{TotalLimit: 1000, FieldLimit: 1000, Required: []string{"id"}, MaxDuration: time.Nanosecond}
+	out, res, err := New(cfg).TrimWithResult(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.TimedOut || len(out) > 1000 {
+		t.Fatalf("Expected a timed-out trim under 1000 bytes, got %v and %d bytes", res.TimedOut, len(out))
+	}
+	var got map[string]interface{}
+	json.Unmarshal(out, &got)
+	if got["id"] != "keep" || got["k0000"] == nil {
+		t.Errorf("Expected Required fields first, then keys in order, got %s", out)
+	}
+
+	cfg.MaxDuration = time.Minute
+	if _, res, _ := New(cfg).TrimWithResult(raw); res.TimedOut {
+		t.Error("Expected no timeout with a generous MaxDuration")
+	}
+}
+
+func TestMaxDurationRequired(t *testing.T) {
+	raw := []byte(`{"a":"` + strings.Repeat("x", 60) + `","z":"small"}`)
+	for _, d := range []time.Duration{0, time.Nanosecond} {
+		cfg := Config{TotalLimit: 40, Required: []string{"a"}, TruncateStrings: true, MaxDuration: d}
+		if out, err := New(cfg).Trim(raw); !errors.Is(err, ErrRequiredTooLarge) {
+			t.Errorf("MaxDuration %v: expected ErrRequiredTooLarge, got %s (%v)", d, out, err)
+		}
+	}
+
+	nested := []byte(`{"user":{"id":1,"bio":"` + strings.Repeat("b", 100) + `"},"z":"small"}`)
+	out, err := New(Config{TotalLimit: 40, Required: []string{"user.id"}, MaxDuration: time.Nanosecond}).Trim(nested)
+	if err != nil || string(out) != `{"user":{"id":1},"z":"small"}` {
+		t.Errorf("Expected the Required container shrunk, got %s (%v)", out, err)
+	}
+}
@@ -0,0 +1,284 @@
+package jsontrim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// SkippedKey is the field StopAtLimit adds to the root of a document it
+// stopped parsing early, holding the number of input bytes left unread.
+const SkippedKey = "_skipped_bytes"
+
+// treeDecoder decodes JSONCodec input token by token, so that Blacklist and
+// StopAtLimit can act while the tree is built instead of after, and arrays
+// can be carved from an arena.
+type treeDecoder struct {
+	t       *Trimmer
+	codec   JSONCodec
+	dec     *json.Decoder
+	strip   bool // Apply Blacklist and Whitelist (see fusesStrip)
+	stop    bool // StopAtLimit
+	kept    int  // Estimated size of what trimming will keep, in Unit
+	stopped bool
+	merged  mergedArrays
+}
+
+// skipValue consumes a JSON value without materializing it. encoding/json
+// hands UnmarshalJSON a slice of its own buffer, so nothing is copied.
+type skipValue struct{ raw []byte }
+
+func (s *skipValue) UnmarshalJSON(b []byte) error {
+	s.raw = b
+	return nil
+}
+
+// fusesStrip reports whether Blacklist and Whitelist can be applied while
+// decoding: they're plain paths, nothing rewrites keys before them, and
+// nothing needs the stripped values (OnLimitExceeded is given the overage of
+// the whole document).
+func (t *Trimmer) fusesStrip() bool {
+	if _, ok := t.cfg.Codec.(JSONCodec); !ok {
+		return false
+	}
+	return (len(t.cfg.Blacklist) > 0 || len(t.cfg.Whitelist) > 0) &&
+		len(t.blacklistQuery) == 0 && len(t.whitelistQuery) == 0 && len(t.dropIfRules) == 0 &&
+		!t.cfg.ParseEmbedded && !t.cfg.Keys.enabled() && t.cfg.Hooks.OnLimitExceeded == nil
+}
+
+// decodeTree decodes raw like codec. With strip set, blacklisted values (and
+// those outside the Whitelist) are skipped as they're read, never
+// materialized, with the same results stripBlacklisted would give. With
+// StopAtLimit, it stops materializing nodes once the estimated size of what
+// trimming keeps passes TotalLimit: the rest of the input is never read, and
+// the root records how much was skipped under SkippedKey (as a trailing
+// element for an array root).
+func (t *Trimmer) decodeTree(codec JSONCodec, raw []byte, strip bool) (interface{}, error) {
+	d := &treeDecoder{t: t, codec: codec, dec: json.NewDecoder(bytes.NewReader(raw)), strip: strip, stop: t.cfg.StopAtLimit}
+	d.dec.UseNumber()
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	v, err := d.value(tok, []string{})
+	if err != nil {
+		return nil, err
+	}
+	if v == removed {
+		v = nil
+	}
+	if !d.stopped {
+		if _, err := d.dec.Token(); !errors.Is(err, io.EOF) {
+			return nil, errors.New("invalid character after top-level value")
+		}
+		return v, nil
+	}
+
+	skipped := len(raw) - int(d.dec.InputOffset())
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		vv[SkippedKey] = skipped
+	case []interface{}:
+		v = append(vv, map[string]interface{}{SkippedKey: skipped})
+	}
+	return v, nil
+}
+
+// value materializes the value starting with tok, at path.
+func (d *treeDecoder) value(tok json.Token, path []string) (interface{}, error) {
+	switch tt := tok.(type) {
+	case json.Delim:
+		d.kept += 2
+		if tt == '{' {
+			return d.object(path)
+		}
+		return d.array(path)
+	case string:
+		d.kept += d.scalarCost(d.t.strLen(tt) + 2)
+		return tt, nil
+	case json.Number:
+		d.kept += d.scalarCost(len(tt))
+		switch {
+		case d.codec.UseNumber:
+			return tt, nil
+		case d.codec.BigIntsAsStrings && isUnsafeInt(string(tt)):
+			return string(tt), nil
+		}
+		return tt.Float64()
+	case bool:
+		d.kept += 5
+	case nil:
+		d.kept += 4
+	}
+	return tok, nil
+}
+
+// scalarCost is what a scalar of size n is expected to keep: FieldLimit drops
+// it, or truncates strings to FieldLimit with TruncateStrings.
+func (d *treeDecoder) scalarCost(n int) int {
+	switch limit := d.t.cfg.FieldLimit; {
+	case n <= limit:
+		return n
+	case d.t.cfg.TruncateStrings:
+		return limit
+	}
+	return 0
+}
+
+// stops reports whether StopAtLimit ends decoding before the next entry.
+func (d *treeDecoder) stops() bool {
+	if d.stop && d.kept > d.t.cfg.TotalLimit {
+		d.stopped = true
+	}
+	return d.stopped
+}
+
+// child decodes the value at path, or skips it if it's stripped.
+func (d *treeDecoder) child(path []string) (interface{}, error) {
+	if d.strip {
+		rule, blacklisted := d.t.blacklistRule(path)
+		if blacklisted || !d.t.whitelisted(path) {
+			var s skipValue
+			if err := d.dec.Decode(&s); err != nil {
+				return nil, err
+			}
+			reason := reasonWhitelist
+			if blacklisted {
+				reason = reasonBlacklist
+			}
+			d.t.recordRule(path, reason, rule, json.RawMessage(s.raw))
+			if d.t.cfg.ReplaceWithMarker {
+				return marked, nil
+			}
+			return removed, nil
+		}
+	}
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	return d.value(tok, path)
+}
+
+func (d *treeDecoder) object(path []string) (interface{}, error) {
+	m := make(map[string]interface{})
+	n := 0                   // Entries read, stripped or not
+	var seen map[string]bool // Keys read, stripped or not, if duplicates are checked
+	if d.codec.checksDuplicates() {
+		seen = make(map[string]bool)
+	}
+	for d.dec.More() {
+		if d.stops() {
+			return m, nil
+		}
+		keyTok, err := d.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := keyTok.(string)
+		offset := int(d.dec.InputOffset())
+		n++
+		v, err := d.child(append(path, key))
+		if err != nil {
+			return nil, err
+		}
+		dup := seen[key]
+		if seen != nil {
+			seen[key] = true
+		}
+		if dup {
+			if err := d.duplicate(key, offset); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case v == removed:
+			if d.codec.DuplicateKeys == LastWins {
+				delete(m, key) // A stripped value replaces the earlier one, as it would stripped after decoding
+			}
+		case dup && d.codec.DuplicateKeys == FirstWins:
+		default:
+			if _, ok := m[key]; !ok {
+				d.kept += d.t.strLen(key) + 4 // Quotes, colon and comma
+			}
+			d.codec.DuplicateKeys.add(m, &d.merged, key, v)
+		}
+		if d.stopped {
+			return m, nil
+		}
+	}
+	if _, err := d.dec.Token(); err != nil { // '}'
+		return nil, err
+	}
+	if d.strip && d.t.prunes(path, n, len(m)) {
+		d.t.record(path, reasonEmpty, m)
+		return removed, nil
+	}
+	return m, nil
+}
+
+// duplicate reports a repeated key, just before offset, to OnDuplicate, and
+// fails under ErrorOnDuplicate.
+func (d *treeDecoder) duplicate(key string, offset int) error {
+	if d.codec.OnDuplicate != nil {
+		d.codec.OnDuplicate(key, offset)
+	}
+	if d.codec.DuplicateKeys == ErrorOnDuplicate {
+		return fmt.Errorf("%w %q at offset %d", ErrDuplicateKey, key, offset)
+	}
+	return nil
+}
+
+func (d *treeDecoder) array(path []string) (interface{}, error) {
+	arr := []interface{}{}
+	start := 0
+	if d.t.arena != nil {
+		start = len(d.t.arena.stack)
+	}
+	add := func(v interface{}) {
+		if d.t.arena != nil {
+			d.t.arena.stack = append(d.t.arena.stack, v)
+		} else {
+			arr = append(arr, v)
+		}
+	}
+	done := func() []interface{} {
+		if d.t.arena != nil {
+			return d.t.arena.take(start)
+		}
+		return arr
+	}
+
+	n := 0
+	for d.dec.More() {
+		if d.stops() {
+			return done(), nil
+		}
+		v, err := d.child(append(path, strconv.Itoa(n)))
+		if err != nil {
+			return nil, err
+		}
+		n++
+		if v != removed {
+			d.kept++ // The comma
+			add(v)
+		}
+		if d.stopped {
+			return done(), nil
+		}
+	}
+	if _, err := d.dec.Token(); err != nil { // ']'
+		return nil, err
+	}
+	arr = done()
+	if d.strip && len(arr) == 0 && !d.t.cfg.KeepEmpty {
+		if n > 0 {
+			d.t.record(path, reasonEmpty, arr)
+		}
+		return removed, nil
+	}
+	return arr, nil
+}
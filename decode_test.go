@@ -0,0 +1,89 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStopAtLimit(t *testing.T) {
+	items := make([]interface{}, 5000)
+	for i := range items {
+		items[i] = fmt.Sprintf("item-%d", i)
+	}
+	raw, _ := json.Marshal(map[string]interface{}{"id": 1, "items": items})
+
+	out, err := New(Config{TotalLimit: 200, StopAtLimit: true}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	skipped, _ := got[SkippedKey].(float64)
+	if got["id"] != 1.0 || skipped < float64(len(raw)/2) {
+		t.Errorf("Expected the tail skipped and summarized, got %s", out)
+	}
+
+	// Documents under the limit decode exactly as usual
+	small := []byte(`{"a":[1,2,{"b":"` + strings.Repeat("x", 10) + `"}],"c":null}`)
+	want, _ := New(Config{}).Trim(small)
+	if got, _ := New(Config{StopAtLimit: true}).Trim(small); string(got) != string(want) {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestFusedBlacklist(t *testing.T) {
+	raw := []byte(`{"users":[{"name":"a","password":"s1"},{"password":"s2"}],"token":"t","meta":{"x":1},"tags":[]}`)
+	for _, cfg := range []Config{
+		{Blacklist: []string{"users.*.password", "token"}},
+		{Blacklist: []string{"users.*.password"}, PruneEmpty: true},
+		{Blacklist: []string{"token"}, ReplaceWithMarker: true},
+		{Whitelist: []string{"users.0", "meta"}},
+	} {
+		tr := New(cfg)
+		if !tr.fusesStrip() {
+			t.Fatalf("Expected %+v to strip while decoding", cfg)
+		}
+		got, res, err := tr.TrimWithResult(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Hooks.OnLimitExceeded = func([]byte, int) {} // Disables fusion
+		want, wantRes, err := New(cfg).TrimWithResult(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) || len(res.PathsAffected) != len(wantRes.PathsAffected) {
+			t.Errorf("Expected %s (%v), got %s (%v)", want, wantRes.PathsAffected, got, res.PathsAffected)
+		}
+	}
+}
+
+func TestFusedBlacklistDuplicates(t *testing.T) {
+	for _, tt := range []struct {
+		codec JSONCodec
+		raw   string
+	}{
+		{JSONCodec{}, `{"a":0.47,"a":[],"token":"t"}`},
+		{JSONCodec{}, `{"token":"t","b":{"token":1},"b":{"token":2}}`},
+		{JSONCodec{DuplicateKeys: FirstWins}, `{"a":[],"a":0.47,"token":"t"}`},
+		{JSONCodec{DuplicateKeys: FirstWins}, `{"token":"t","token":"u","a":1}`},
+	} {
+		cfg := Config{Codec: tt.codec, Blacklist: []string{"token", "b.token"}}
+		got, err := New(cfg).Trim([]byte(tt.raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Hooks.OnLimitExceeded = func([]byte, int) {} // Disables fusion
+		want, err := New(cfg).Trim([]byte(tt.raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s: expected %s, got %s", tt.raw, want, got)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package jsontrim
+
+import "math"
+
+// NonFinitePolicy selects what happens to NaN and ±Inf numbers, which JSON
+// can't represent.
+type NonFinitePolicy int
+
+const (
+	// NonFiniteKeep leaves them as they are (default); with JSONCodec the
+	// trim then fails with json.UnsupportedValueError.
+	NonFiniteKeep NonFinitePolicy = iota
+	// NonFiniteNull replaces them with null.
+	NonFiniteNull
+	// NonFiniteMarker replaces them with Marker.
+	NonFiniteMarker
+	// NonFiniteString replaces them with "NaN", "+Inf" or "-Inf".
+	NonFiniteString
+)
+
+// finite applies NonFinite to v if it's a NaN or infinite float.
+func (t *Trimmer) finite(v interface{}) interface{} {
+	if t.cfg.NonFinite == NonFiniteKeep {
+		return v
+	}
+	var f float64
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case float32:
+		f = float64(n)
+	default:
+		return v
+	}
+	if !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return v
+	}
+	switch t.cfg.NonFinite {
+	case NonFiniteNull:
+		return nil
+	case NonFiniteMarker:
+		return Marker
+	}
+	return formatNonFinite(f)
+}
+
+// formatNonFinite returns the text of a NaN or infinite f.
+func formatNonFinite(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case f > 0:
+		return "+Inf"
+	}
+	return "-Inf"
+}
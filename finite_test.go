@@ -0,0 +1,34 @@
+package jsontrim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNonFinite(t *testing.T) {
+	v := map[string]float64{"nan": math.NaN(), "inf": math.Inf(1), "neg": math.Inf(-1), "ok": 1.5}
+	if _, err := New(Config{}).TrimValue(v); err == nil {
+		t.Error("Expected NonFiniteKeep to fail with JSONCodec")
+	}
+	tests := []struct {
+		policy NonFinitePolicy
+		want   string
+	}{
+		{NonFiniteNull, `{"inf":null,"nan":null,"neg":null,"ok":1.5}`},
+		{NonFiniteMarker, `{"inf":"[TRIMMED]","nan":"[TRIMMED]","neg":"[TRIMMED]","ok":1.5}`},
+		{NonFiniteString, `{"inf":"+Inf","nan":"NaN","neg":"-Inf","ok":1.5}`},
+	}
+	for _, tt := range tests {
+		out, err := New(Config{NonFinite: tt.policy}).TrimValue(v)
+		if err != nil || string(out) != tt.want {
+			t.Errorf("Policy %d: got %s (%v), want %s", tt.policy, out, err, tt.want)
+		}
+	}
+
+	hooked := New(Config{NonFinite: NonFiniteNull, Hooks: Hooks{PreTrim: func(interface{}) interface{} {
+		return map[string]interface{}{"x": []interface{}{math.NaN()}}
+	}}})
+	if out, err := hooked.Trim([]byte(`{}`)); err != nil || string(out) != `{"x":[null]}` {
+		t.Errorf("Expected NaN from a hook replaced, got %s (%v)", out, err)
+	}
+}
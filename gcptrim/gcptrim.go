@@ -0,0 +1,58 @@
+// Package gcptrim trims Google Cloud Logging entry payloads in place, for use
+// with jsontrim.Profiles.CloudLogging:
+//
+//	trimmer := jsontrim.New(jsontrim.Profiles.CloudLogging(entry.Labels))
+//	gcptrim.TrimPayload(trimmer, &entry.Payload)
+//	logger.Log(entry)
+//
+// It takes a pointer to logging.Entry's Payload field rather than the Entry,
+// so it doesn't depend on cloud.google.com/go/logging.
+package gcptrim
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// TrimPayload trims *payload to fit t's TotalLimit, replacing it with the
+// result. Strings are sent as textPayload by the logging client, so they are
+// cut on a UTF-8 boundary and keep their type. Anything else (a
+// json.RawMessage, []byte, map or struct) is encoded and trimmed, and becomes
+// a json.RawMessage, which the client sends as jsonPayload. On error
+// *payload is left unchanged.
+func TrimPayload(t *jsontrim.Trimmer, payload *interface{}) error {
+	switch p := (*payload).(type) {
+	case nil:
+		return nil
+	case string:
+		if limit := t.Config().TotalLimit; len(p) > limit {
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(p[cut]) {
+				cut--
+			}
+			*payload = p[:cut]
+		}
+		return nil
+	case json.RawMessage:
+		return trim(t, payload, p)
+	case []byte:
+		return trim(t, payload, p)
+	default:
+		raw, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		return trim(t, payload, raw)
+	}
+}
+
+func trim(t *jsontrim.Trimmer, payload *interface{}, raw []byte) error {
+	out, err := t.Trim(raw)
+	if err != nil {
+		return err
+	}
+	*payload = json.RawMessage(out)
+	return nil
+}
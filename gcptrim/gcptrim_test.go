@@ -0,0 +1,34 @@
+package gcptrim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+func TestTrimPayload(t *testing.T) {
+	trimmer := jsontrim.New(jsontrim.Config{TotalLimit: 64, Blacklist: []string{"token"}})
+
+	var payload interface{} = map[string]interface{}{"msg": "hi", "token": "secret"}
+	if err := TrimPayload(trimmer, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if raw, ok := payload.(json.RawMessage); !ok || string(raw) != `{"msg":"hi"}` {
+		t.Errorf("Got %T %s", payload, payload)
+	}
+
+	payload = strings.Repeat("é", 40)
+	if err := TrimPayload(trimmer, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if s := payload.(string); s != strings.Repeat("é", 32) {
+		t.Errorf("Expected the text cut to 64 bytes on a rune boundary, got %q", s)
+	}
+
+	payload = json.RawMessage(`{`)
+	if err := TrimPayload(trimmer, &payload); err == nil || string(payload.(json.RawMessage)) != `{` {
+		t.Errorf("Expected an error and the payload unchanged, got %v, %s", err, payload)
+	}
+}
@@ -0,0 +1,17 @@
+package httptrim
+
+import "net/http"
+
+// Chi returns Middleware(opts) for chi's Router.Use and Router.With, which
+// take net/http middleware as is:
+//
+//	r := chi.NewRouter()
+//	r.Use(httptrim.Chi(httptrim.Options{Trimmer: trimmer}))
+//
+// Middleware calls Route after the handler has written its response, when
+// chi has matched the route even for middleware added with Use, so Route can
+// use chi.RouteContext(r.Context()).RoutePattern() to pick a Trimmer. For
+// Fiber, see the fibertrim module.
+func Chi(opts Options) func(http.Handler) http.Handler {
+	return Middleware(opts)
+}
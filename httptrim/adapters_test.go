@@ -0,0 +1,68 @@
+package httptrim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+func TestTrimBody(t *testing.T) {
+	trimmer := jsontrim.New(jsontrim.Config{TotalLimit: 100, FieldLimit: 400})
+	big := []byte(`{"id":1,"items":["` + strings.Repeat("x", 300) + `"]}`)
+
+	tests := []struct {
+		name                  string
+		status                int
+		contentType, encoding string
+		body                  []byte
+		wantResult            string
+		wantSame              bool
+	}{
+		{name: "trimmed", status: 200, contentType: "application/json", body: big, wantResult: "applied"},
+		{name: "small", status: 200, contentType: "application/json", body: []byte(`{"id":1}`), wantSame: true},
+		{name: "text", status: 200, contentType: "text/plain", body: big, wantSame: true},
+		{name: "gzip", status: 200, contentType: "application/json", encoding: "gzip", body: big, wantSame: true},
+		{name: "no content", status: http.StatusNoContent, contentType: "application/json", body: big, wantSame: true},
+		{name: "invalid", status: 200, contentType: "application/json", body: []byte(`{"a":` + strings.Repeat("1", 200)), wantResult: "failed", wantSame: true},
+	}
+	for _, tt := range tests {
+		out, result := TrimBody(trimmer, 0, tt.status, tt.contentType, tt.encoding, tt.body)
+		if result != tt.wantResult {
+			t.Errorf("%s: result %q, want %q", tt.name, result, tt.wantResult)
+		}
+		if same := string(out) == string(tt.body); same != tt.wantSame {
+			t.Errorf("%s: body unchanged = %v, want %v", tt.name, same, tt.wantSame)
+		}
+		if tt.wantResult == "applied" && len(out) > 100 {
+			t.Errorf("%s: trimmed body is %d bytes: %s", tt.name, len(out), out)
+		}
+	}
+}
+
+func TestChiRoutePattern(t *testing.T) {
+	type routeKey struct{}
+	var seen string
+	h := Chi(Options{Route: func(r *http.Request) *jsontrim.Trimmer {
+		seen = *r.Context().Value(routeKey{}).(*string)
+		return jsontrim.New(jsontrim.Config{TotalLimit: 50})
+	}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*r.Context().Value(routeKey{}).(*string) = "/users/{id}" // As chi's router does, after middleware added with Use
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1,"bio":"` + strings.Repeat("x", 300) + `"}`))
+	}))
+
+	pattern := ""
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), routeKey{}, &pattern)))
+	if seen != "/users/{id}" {
+		t.Errorf("Route saw pattern %q, want the routed one", seen)
+	}
+	if rec.Header().Get(Header) != "applied" || rec.Body.Len() > 50 {
+		t.Errorf("Expected the route's Trimmer to apply, got %s", rec.Body)
+	}
+}
@@ -0,0 +1,97 @@
+package httptrim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// ErrIncompleteBody is passed to audit callbacks when the body was closed
+// before it had been read to the end.
+var ErrIncompleteBody = errors.New("request body not fully read")
+
+// teeBody passes a body through unchanged while feeding a copy to
+// Trimmer.TrimStream running in its own goroutine.
+type teeBody struct {
+	body io.ReadCloser
+	pw   *io.PipeWriter
+	done chan struct{}
+	out  bytes.Buffer
+	err  error
+	once sync.Once
+	fn   func(trimmed []byte, err error)
+}
+
+// TeeBody wraps body so that everything read from it is also streamed through
+// t.TrimStream concurrently. Once body hits EOF or is closed, fn is called
+// with the trimmed copy, or with an error (ErrIncompleteBody if the reader
+// stopped early). Only the trimmed copy is kept in memory, never the whole
+// body; TrimStream's path-based rules (Blacklist, Rename, limits) apply.
+func TeeBody(body io.ReadCloser, t *jsontrim.Trimmer, fn func(trimmed []byte, err error)) io.ReadCloser {
+	pr, pw := io.Pipe()
+	tb := &teeBody{body: body, pw: pw, done: make(chan struct{}), fn: fn}
+	go func() {
+		defer close(tb.done)
+		tb.err = t.TrimStream(pr, &tb.out)
+		// Keep the tee flowing if TrimStream gave up before the end
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	return tb
+}
+
+func (tb *teeBody) Read(p []byte) (int, error) {
+	n, err := tb.body.Read(p)
+	if n > 0 {
+		if _, werr := tb.pw.Write(p[:n]); werr != nil {
+			tb.finish(werr) // Report the copy as failed rather than trimmed short
+		}
+	}
+	if err == io.EOF {
+		tb.finish(nil)
+	} else if err != nil {
+		tb.finish(err)
+	}
+	return n, err
+}
+
+func (tb *teeBody) Close() error {
+	err := tb.body.Close()
+	tb.finish(ErrIncompleteBody) // No-op if the body was read to the end
+	return err
+}
+
+// finish ends the copy with cause (nil for a clean EOF), waits for the
+// trimmer and reports the result.
+func (tb *teeBody) finish(cause error) {
+	tb.once.Do(func() {
+		tb.pw.CloseWithError(cause)
+		<-tb.done
+		if tb.err != nil {
+			tb.fn(nil, tb.err)
+			return
+		}
+		tb.fn(tb.out.Bytes(), nil)
+	})
+}
+
+// Audit returns middleware that tees every request body through t (see
+// TeeBody) and calls fn with the trimmed copy, for audit logging. The handler
+// sees the original body. fn runs at the latest when the handler returns.
+func Audit(t *jsontrim.Trimmer, fn func(r *http.Request, trimmed []byte, err error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body := TeeBody(r.Body, t, func(trimmed []byte, err error) { fn(r, trimmed, err) })
+			r.Body = body
+			defer body.Close()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
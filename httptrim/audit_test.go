@@ -0,0 +1,50 @@
+package httptrim
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+func TestAudit(t *testing.T) {
+	body := `{"user":"a","password":"secret","note":"` + strings.Repeat("n", 200) + `"}`
+	trimmer := jsontrim.New(jsontrim.Config{TotalLimit: 4096, FieldLimit: 50, TruncateStrings: true, Blacklist: []string{"password"}})
+
+	var audited string
+	var auditErr error
+	handler := Audit(trimmer, func(r *http.Request, trimmed []byte, err error) {
+		audited, auditErr = string(trimmed), err
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		if string(got) != body {
+			t.Errorf("Handler should see the original body, got %s", got)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+	if auditErr != nil {
+		t.Fatal(auditErr)
+	}
+	if strings.Contains(audited, "secret") || !strings.Contains(audited, `"user":"a"`) || len(audited) > 100 {
+		t.Errorf("Expected a trimmed, redacted copy, got %s", audited)
+	}
+}
+
+func TestAuditIncompleteBody(t *testing.T) {
+	var auditErr error
+	handler := Audit(jsontrim.New(jsontrim.Config{}), func(r *http.Request, trimmed []byte, err error) {
+		auditErr = err
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body.Read(make([]byte, 4)) // Stop early
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(`{"a":"`+strings.Repeat("x", 100)+`"}`)))
+
+	if !errors.Is(auditErr, ErrIncompleteBody) {
+		t.Errorf("Expected ErrIncompleteBody, got %v", auditErr)
+	}
+}
@@ -0,0 +1,54 @@
+// Package fibertrim is httptrim's middleware for Fiber, which is built on
+// fasthttp rather than net/http. It is a module of its own, so jsontrim
+// itself doesn't depend on Fiber.
+package fibertrim
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/arun0009/jsontrim"
+	"github.com/arun0009/jsontrim/httptrim"
+)
+
+// Options configures New.
+type Options struct {
+	Trimmer   *jsontrim.Trimmer                   // Used for every route Route doesn't handle (default: jsontrim.New(jsontrim.Config{}))
+	Route     func(c fiber.Ctx) *jsontrim.Trimmer // Optional per-route Trimmer, called once the handler has returned, e.g. by c.Route().Path; nil falls back to Trimmer
+	Threshold int                                 // Bodies up to this many bytes are sent as is (default: the Trimmer's TotalLimit)
+}
+
+// New returns Fiber middleware that trims JSON responses above the
+// threshold, with the same rules as httptrim.Middleware: uncompressed JSON
+// only, sent with the httptrim.Header header when trimmed or when trimming
+// failed. Streamed bodies are passed through untouched.
+//
+//	app.Use(fibertrim.New(fibertrim.Options{Trimmer: trimmer}))
+func New(opts Options) fiber.Handler {
+	if opts.Trimmer == nil {
+		opts.Trimmer = jsontrim.New(jsontrim.Config{})
+	}
+	return func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		res := c.Response()
+		if res.IsBodyStream() {
+			return nil
+		}
+		t := opts.Trimmer
+		if opts.Route != nil {
+			if rt := opts.Route(c); rt != nil {
+				t = rt
+			}
+		}
+		body, result := httptrim.TrimBody(t, opts.Threshold, res.StatusCode(),
+			string(res.Header.ContentType()), string(res.Header.ContentEncoding()), res.Body())
+		if result != "" {
+			c.Set(httptrim.Header, result)
+		}
+		if result == "applied" {
+			res.SetBodyRaw(body)
+		}
+		return nil
+	}
+}
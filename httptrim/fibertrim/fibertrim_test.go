@@ -0,0 +1,47 @@
+package fibertrim
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/arun0009/jsontrim"
+	"github.com/arun0009/jsontrim/httptrim"
+)
+
+func TestNew(t *testing.T) {
+	var routed string
+	app := fiber.New()
+	app.Use(New(Options{Route: func(c fiber.Ctx) *jsontrim.Trimmer {
+		routed = c.Route().Path
+		return jsontrim.New(jsontrim.Config{TotalLimit: 50})
+	}}))
+	app.Get("/users/:id", func(c fiber.Ctx) error {
+		return c.JSON(map[string]interface{}{"id": 1, "bio": strings.Repeat("x", 300)})
+	})
+	app.Get("/text", func(c fiber.Ctx) error {
+		return c.SendString(strings.Repeat("x", 300))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.Header.Get(httptrim.Header) != "applied" || len(body) > 50 || routed != "/users/:id" {
+		t.Errorf("Expected the route's Trimmer to apply, got %s %s (route %q)", resp.Header.Get(httptrim.Header), body, routed)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/text", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	if resp.Header.Get(httptrim.Header) != "" || len(body) != 300 {
+		t.Errorf("Non-JSON responses should pass through, got %d bytes", len(body))
+	}
+}
@@ -0,0 +1,185 @@
+// Package httptrim trims oversized JSON HTTP responses on the server side, for
+// APIs (internal debug endpoints, admin tools) whose clients can't cope with
+// multi-megabyte bodies.
+//
+// JSON responses larger than the route's threshold are buffered, trimmed, and
+// sent with an "X-JSONTrim: applied" header. Other responses, responses with
+// a Content-Encoding, and handlers that flush are passed through untouched.
+package httptrim
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// Header is set on responses the middleware changed: "applied" when trimmed,
+// "failed" when trimming failed and the original body was sent instead.
+const Header = "X-JSONTrim"
+
+// Options configures Middleware.
+type Options struct {
+	Trimmer   *jsontrim.Trimmer                       // Used for every route Route doesn't handle (default: jsontrim.New(jsontrim.Config{}))
+	Route     func(r *http.Request) *jsontrim.Trimmer // Optional per-route Trimmer, called once the handler has written the response; nil falls back to Trimmer
+	Threshold int                                     // Bodies up to this many bytes are sent as is (default: the Trimmer's TotalLimit)
+}
+
+// Middleware returns middleware that trims JSON responses above the threshold.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	if opts.Trimmer == nil {
+		opts.Trimmer = jsontrim.New(jsontrim.Config{})
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w, threshold: opts.Threshold}
+			next.ServeHTTP(rw, r)
+			// Routers that match inside the chain (chi) have done so by now
+			t := opts.Trimmer
+			if opts.Route != nil {
+				if rt := opts.Route(r); rt != nil {
+					t = rt
+				}
+			}
+			rw.finish(t)
+		})
+	}
+}
+
+// Handler wraps h with Middleware(opts).
+func Handler(h http.Handler, opts Options) http.Handler {
+	return Middleware(opts)(h)
+}
+
+// responseWriter buffers a JSON response until the handler returns. It
+// switches to passing writes straight through as soon as it sees a response
+// it won't trim.
+type responseWriter struct {
+	http.ResponseWriter
+	threshold   int
+	status      int
+	wroteHeader bool
+	passthrough bool
+	buf         bytes.Buffer
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 {
+		rw.ResponseWriter.WriteHeader(status) // Informational, the real response follows
+		return
+	}
+	rw.wroteHeader = true
+	rw.status = status
+	if !trimmable(status, rw.Header().Get("Content-Type"), rw.Header().Get("Content-Encoding")) {
+		rw.passthrough = true
+		rw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		if rw.Header().Get("Content-Type") == "" {
+			rw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.passthrough {
+		return rw.ResponseWriter.Write(p)
+	}
+	return rw.buf.Write(p)
+}
+
+// Flush sends what's buffered and turns trimming off for the rest of the
+// response, since flushed bytes can't be trimmed later.
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if !rw.passthrough {
+		rw.passthrough = true
+		rw.ResponseWriter.WriteHeader(rw.status)
+		_, _ = rw.ResponseWriter.Write(rw.buf.Bytes()) // Flush has no error to return; the client is gone
+		rw.buf = bytes.Buffer{}
+	}
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// finish trims a buffered response with t and sends it.
+func (rw *responseWriter) finish(t *jsontrim.Trimmer) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.passthrough {
+		return
+	}
+
+	body, result := trimBody(t, rw.threshold, rw.buf.Bytes())
+	if result != "" {
+		rw.Header().Set(Header, result)
+	}
+	rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	rw.ResponseWriter.WriteHeader(rw.status)
+	_, _ = rw.ResponseWriter.Write(body) // Headers are sent; a client that went away can't be told
+}
+
+// TrimBody trims a fully buffered response body the way Middleware does, for
+// frameworks that don't use net/http (the fibertrim module uses it for Fiber). It returns the body to
+// send and the value for the Header header: "applied", "failed", or "" when
+// the response was left alone. A threshold <= 0 means the Trimmer's
+// TotalLimit.
+func TrimBody(t *jsontrim.Trimmer, threshold, status int, contentType, contentEncoding string, body []byte) ([]byte, string) {
+	if !trimmable(status, contentType, contentEncoding) {
+		return body, ""
+	}
+	return trimBody(t, threshold, body)
+}
+
+// trimBody trims a trimmable body above the threshold.
+func trimBody(t *jsontrim.Trimmer, threshold int, body []byte) ([]byte, string) {
+	if threshold <= 0 {
+		threshold = t.Config().TotalLimit
+	}
+	if len(body) <= threshold {
+		return body, ""
+	}
+	out, err := t.Trim(body)
+	if err != nil {
+		return body, "failed"
+	}
+	return out, "applied"
+}
+
+// trimmable reports whether a response is buffered for trimming: a JSON body
+// that isn't compressed, with a status that has a body.
+func trimmable(status int, contentType, contentEncoding string) bool {
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if contentEncoding != "" {
+		return false
+	}
+	return IsJSON(contentType)
+}
+
+// IsJSON reports whether a Content-Type names JSON: application/json or any
+// +json type (e.g., application/problem+json).
+func IsJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
@@ -0,0 +1,72 @@
+package httptrim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+func TestMiddleware(t *testing.T) {
+	big := `{"id":1,"items":["` + strings.Repeat("x", 300) + `","` + strings.Repeat("y", 300) + `"]}`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(big))
+	})
+	mux.HandleFunc("/small", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1}`))
+	})
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(big))
+	})
+	mux.HandleFunc("/admin/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte(big))
+	})
+
+	handler := Handler(mux, Options{
+		Trimmer: jsontrim.New(jsontrim.Config{TotalLimit: 400, FieldLimit: 400}),
+		Route: func(r *http.Request) *jsontrim.Trimmer {
+			if strings.HasPrefix(r.URL.Path, "/admin/") {
+				return jsontrim.New(jsontrim.Config{TotalLimit: 100, FieldLimit: 400})
+			}
+			return nil
+		},
+	})
+
+	tests := []struct {
+		path     string
+		maxLen   int
+		applied  bool
+		wantBody string
+		wantCode int
+	}{
+		{path: "/big", maxLen: 400, applied: true, wantCode: 200},
+		{path: "/small", wantBody: `{"id": 1}`, wantCode: 200},
+		{path: "/text", wantBody: big, wantCode: 200},
+		{path: "/admin/big", maxLen: 100, applied: true, wantCode: http.StatusTeapot},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.path, rec.Code, tt.wantCode)
+		}
+		if got := rec.Header().Get(Header) == "applied"; got != tt.applied {
+			t.Errorf("%s: %s header = %q", tt.path, Header, rec.Header().Get(Header))
+		}
+		if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+			t.Errorf("%s: body %s, want %s", tt.path, rec.Body, tt.wantBody)
+		}
+		if tt.maxLen > 0 && (rec.Body.Len() > tt.maxLen || !strings.Contains(rec.Body.String(), `"id":1`)) {
+			t.Errorf("%s: expected a trimmed body under %d bytes, got %d: %s", tt.path, tt.maxLen, rec.Body.Len(), rec.Body)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package jsontrim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer caps the buffers kept in bufferPool, so one huge document
+// doesn't pin its memory for the life of the process.
+const maxPooledBuffer = 1 << 20
+
+var (
+	// ErrClosed is returned by writes to a trimming writer after Close.
+	ErrClosed = errors.New("write to closed writer")
+	// ErrBufferFull is returned by a trimming reader or writer whose document
+	// exceeded Config.MaxBuffer. What was buffered has been salvaged, trimmed
+	// and passed on as far as possible.
+	ErrBufferFull = errors.New("buffer limit exceeded")
+)
+
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// TrimTo trims raw and writes the result to w, returning the number of bytes
+// written. The output is encoded into a pooled buffer instead of a fresh
+// slice, which saves an allocation per call for high-volume sinks. Nothing is
+// written if trimming fails.
+func (t *Trimmer) TrimTo(w io.Writer, raw []byte) (int, error) {
+	t, v, err := t.process(raw, false)
+	defer t.releaseArena()
+	if t.streamsOver(err) {
+		out, _, err := t.trimStreamed(raw)
+		if err != nil {
+			return 0, t.failed(err)
+		}
+		return w.Write(out)
+	}
+	if err != nil {
+		return 0, t.failed(err)
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+	start := t.startPhase()
+	if err := t.encodeTo(buf, v); err != nil {
+		return 0, t.failed(err)
+	}
+	t.endPhase(phaseEncode, start)
+	if _, err := t.finish(raw, buf.Bytes(), v); err != nil {
+		return 0, t.failed(err)
+	}
+	return w.Write(buf.Bytes())
+}
+
+// encodeTo is encode into buf. JSON is streamed into it directly.
+func (t *Trimmer) encodeTo(buf *bytes.Buffer, v interface{}) error {
+	switch t.cfg.Codec.(type) {
+	case JSONCodec, LenientJSONCodec:
+		if err := json.NewEncoder(buf).Encode(v); err != nil {
+			return err
+		}
+		buf.Truncate(buf.Len() - 1) // Encode adds a newline Marshal doesn't
+		return nil
+	}
+	encoded, err := t.encode(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(encoded)
+	return nil
+}
+
+// trimmingReader serves the trimmed form of the document read from r.
+type trimmingReader struct {
+	r   io.Reader
+	t   *Trimmer
+	out *bytes.Reader
+	err error
+}
+
+// NewTrimmingReader returns a Reader that reads the whole JSON document from r
+// on first use, trims it with t, and serves the trimmed bytes, e.g. to
+// replace an http.Request.Body. Read and trim errors are returned from Read.
+//
+// With Config.MaxBuffer set, at most that many bytes are read from r. A
+// longer document is cut there, salvaged, and served trimmed, followed by
+// ErrBufferFull instead of io.EOF.
+func NewTrimmingReader(r io.Reader, t *Trimmer) io.Reader {
+	return &trimmingReader{r: r, t: t}
+}
+
+func (tr *trimmingReader) Read(p []byte) (int, error) {
+	if tr.out == nil && tr.err == nil {
+		tr.fill()
+	}
+	if tr.out != nil && tr.out.Len() > 0 {
+		return tr.out.Read(p)
+	}
+	if tr.err != nil {
+		return 0, tr.err
+	}
+	return 0, io.EOF
+}
+
+// fill reads and trims the document, setting out and err.
+func (tr *trimmingReader) fill() {
+	src := tr.r
+	if max := tr.t.cfg.MaxBuffer; max > 0 {
+		src = io.LimitReader(src, int64(max)+1)
+	}
+	raw, err := io.ReadAll(src)
+	if err != nil {
+		tr.err = err
+		return
+	}
+	var out []byte
+	if max := tr.t.cfg.MaxBuffer; max > 0 && len(raw) > max {
+		out, tr.err = tr.t.salvage(raw[:max])
+	} else {
+		out, tr.err = tr.t.Trim(raw)
+	}
+	tr.out = bytes.NewReader(out)
+}
+
+// trimmingWriter buffers a document and writes its trimmed form on Close.
+type trimmingWriter struct {
+	w      io.Writer
+	t      *Trimmer
+	buf    bytes.Buffer
+	closed bool
+	err    error // Set once the buffer overflowed
+}
+
+// NewTrimmingWriter returns a WriteCloser that buffers everything written to
+// it and, on Close, trims the buffered document with t and writes the result
+// to w. It slots into encoder pipelines such as
+// json.NewEncoder(tw).Encode(v). Close does not close w.
+//
+// With Config.MaxBuffer set, a write that would grow the buffer past it fails
+// with ErrBufferFull: what fits is salvaged, trimmed and written to w right
+// away, and every later Write and Close returns the same error.
+func NewTrimmingWriter(w io.Writer, t *Trimmer) io.WriteCloser {
+	return &trimmingWriter{w: w, t: t}
+}
+
+func (tw *trimmingWriter) Write(p []byte) (int, error) {
+	if tw.err != nil {
+		return 0, tw.err
+	}
+	if tw.closed {
+		return 0, ErrClosed
+	}
+	max := tw.t.cfg.MaxBuffer
+	if max <= 0 || tw.buf.Len()+len(p) <= max {
+		return tw.buf.Write(p)
+	}
+
+	n := max - tw.buf.Len()
+	tw.buf.Write(p[:n])
+	out, err := tw.t.salvage(tw.buf.Bytes())
+	if len(out) > 0 {
+		if _, werr := tw.w.Write(out); werr != nil {
+			err = werr
+		}
+	}
+	tw.err = err
+	tw.buf = bytes.Buffer{}
+	return n, tw.err
+}
+
+// Close trims the buffered document and writes it out. Later calls are no-ops.
+func (tw *trimmingWriter) Close() error {
+	if tw.closed || tw.err != nil {
+		tw.closed = true
+		return tw.err
+	}
+	tw.closed = true
+	_, err := tw.t.TrimTo(tw.w, tw.buf.Bytes())
+	tw.buf = bytes.Buffer{}
+	return err
+}
+
+// salvage trims what could be kept of a document cut short at MaxBuffer. The
+// JSON prefix is closed off after its last complete value (see closePrefix)
+// and trimmed as usual. It always returns an error wrapping ErrBufferFull,
+// along with the output if there was any.
+func (t *Trimmer) salvage(prefix []byte) ([]byte, error) {
+	if _, ok := t.cfg.Codec.(JSONCodec); !ok {
+		return nil, ErrBufferFull // Only JSON can be cut and closed safely
+	}
+	doc := closePrefix(prefix)
+	if doc == nil {
+		return nil, ErrBufferFull
+	}
+	out, err := t.Trim(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBufferFull, err)
+	}
+	return out, ErrBufferFull
+}
+
+// closePrefix turns the prefix of a JSON document into a complete document:
+// it cuts after the last complete value (or the last opened container) and
+// closes every container still open. It returns nil if no container was
+// opened, since a cut scalar can't be repaired.
+func closePrefix(prefix []byte) []byte {
+	const (
+		wantKey   = iota // Object: key or '}'
+		wantColon        // Object: ':'
+		wantValue        // Object value, or array element or ']'
+		wantComma        // ',' or close
+	)
+	type frame struct {
+		obj   bool
+		state int
+	}
+	var (
+		stack   []frame
+		cut     = -1
+		closers []byte
+	)
+	mark := func(pos int) {
+		cut, closers = pos, closers[:0]
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].obj {
+				closers = append(closers, '}')
+			} else {
+				closers = append(closers, ']')
+			}
+		}
+	}
+	// valueDone moves the enclosing container past a complete value ending at pos
+	valueDone := func(pos int) bool {
+		if len(stack) == 0 {
+			return false // The root is complete
+		}
+		stack[len(stack)-1].state = wantComma
+		mark(pos)
+		return true
+	}
+
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		case c == '{' || c == '[':
+			stack = append(stack, frame{obj: c == '{', state: wantValue})
+			if c == '{' {
+				stack[len(stack)-1].state = wantKey
+			}
+			mark(i + 1)
+		case c == '}' || c == ']':
+			if len(stack) == 0 {
+				return nil
+			}
+			stack = stack[:len(stack)-1]
+			if !valueDone(i + 1) {
+				return prefix[:i+1]
+			}
+		case c == ':':
+			if len(stack) > 0 {
+				stack[len(stack)-1].state = wantValue
+			}
+		case c == ',':
+			if len(stack) > 0 {
+				if top := &stack[len(stack)-1]; top.obj {
+					top.state = wantKey
+				} else {
+					top.state = wantValue
+				}
+			}
+		case c == '"':
+			end := i + 1
+			for ; end < len(prefix) && prefix[end] != '"'; end++ {
+				if prefix[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(prefix) {
+				i = len(prefix) // Unterminated string
+				break
+			}
+			i = end
+			if len(stack) > 0 && stack[len(stack)-1].obj && stack[len(stack)-1].state == wantKey {
+				stack[len(stack)-1].state = wantColon
+			} else if !valueDone(i + 1) {
+				return prefix[:i+1]
+			}
+		default:
+			// Number or literal: complete only if something follows it
+			end := i
+			for end < len(prefix) && !bytes.ContainsRune([]byte(" \t\n\r,]}"), rune(prefix[end])) {
+				end++
+			}
+			if end >= len(prefix) {
+				i = len(prefix)
+				break
+			}
+			i = end - 1
+			if !valueDone(end) {
+				return prefix[:end]
+			}
+		}
+	}
+	if cut < 0 {
+		return nil
+	}
+	return append(prefix[:cut:cut], closers...)
+}
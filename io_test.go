@@ -0,0 +1,118 @@
+package jsontrim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTrimTo(t *testing.T) {
+	raw := []byte(`{"id":1,"html":"<b>` + strings.Repeat("x", 600) + `</b>","token":"x"}`)
+	for _, cfg := range []Config{
+		{TotalLimit: 4096, Blacklist: []string{"token"}},
+		{TotalLimit: 4096, Blacklist: []string{"token"}, Codec: MsgPackCodec{}},
+	} {
+		trimmer := New(cfg)
+		in := raw
+		if cfg.Codec != nil {
+			v, _ := JSONCodec{}.Decode(raw)
+			in, _ = cfg.Codec.Encode(v)
+		}
+		want, err := trimmer.Trim(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		n, err := trimmer.TrimTo(&buf, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(want) || !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("TrimTo wrote %d bytes %q, want %q", n, buf.Bytes(), want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := New(Config{}).TrimTo(&buf, []byte(`{`)); err == nil || buf.Len() != 0 {
+		t.Errorf("Expected an error and no output, got %v, %q", err, buf.Bytes())
+	}
+}
+
+func TestTrimmingReader(t *testing.T) {
+	trimmer := New(Config{TotalLimit: 4096, Blacklist: []string{"password"}})
+	out, err := io.ReadAll(NewTrimmingReader(strings.NewReader(`{"user":"a","password":"x"}`), trimmer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"user":"a"}` {
+		t.Errorf("Got %s", out)
+	}
+
+	if _, err := io.ReadAll(NewTrimmingReader(strings.NewReader(`{`), trimmer)); err == nil {
+		t.Error("Expected the trim error from Read")
+	}
+}
+
+func TestTrimmingWriter(t *testing.T) {
+	var out bytes.Buffer
+	tw := NewTrimmingWriter(&out, New(Config{TotalLimit: 4096, Blacklist: []string{"password"}}))
+	if err := json.NewEncoder(tw).Encode(map[string]string{"user": "a", "password": "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Nothing should be written before Close, got %s", out.Bytes())
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != `{"user":"a"}` {
+		t.Errorf("Got %s", out.Bytes())
+	}
+	if _, err := tw.Write([]byte(`{}`)); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed writing after Close, got %v", err)
+	}
+}
+
+func TestClosePrefix(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{`{"a":1,"b":"xy`, `{"a":1}`},
+		{`{"a":[1,2,{"c":true},`, `{"a":[1,2,{"c":true}]}`},
+		{`{"a":12`, `{}`},
+		{`{"a":"b\"c","d`, `{"a":"b\"c"}`},
+		{`[{"x":1}, {"y":`, `[{"x":1}, {}]`},
+		{`{"a":1}  `, `{"a":1}`},
+		{`"abc`, ``},
+	} {
+		if got := closePrefix([]byte(tt.in)); string(got) != tt.want {
+			t.Errorf("closePrefix(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaxBuffer(t *testing.T) {
+	trimmer := New(Config{TotalLimit: 4096, MaxBuffer: 40})
+	doc := `{"id":7,"items":["one","two","three","four","five","six","seven"]}`
+
+	out, err := io.ReadAll(NewTrimmingReader(strings.NewReader(doc), trimmer))
+	if !errors.Is(err, ErrBufferFull) {
+		t.Errorf("Reader: expected ErrBufferFull, got %v", err)
+	}
+	if want := `{"id":7,"items":["one","two","three"]}`; string(out) != want {
+		t.Errorf("Reader: got %s, want %s", out, want)
+	}
+
+	var buf bytes.Buffer
+	tw := NewTrimmingWriter(&buf, trimmer)
+	if _, err := io.WriteString(tw, doc); !errors.Is(err, ErrBufferFull) {
+		t.Errorf("Writer: expected ErrBufferFull, got %v", err)
+	}
+	if err := tw.Close(); !errors.Is(err, ErrBufferFull) {
+		t.Errorf("Close: expected ErrBufferFull, got %v", err)
+	}
+	if buf.String() != string(out) {
+		t.Errorf("Writer: got %s, want %s", buf.Bytes(), out)
+	}
+}
@@ -1,31 +1,120 @@
 package jsontrim
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net/url"
 	"reflect"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+	"unsafe"
 )
 
 // Config holds customization options for the Trimmer.
 type Config struct {
-	FieldLimit        int           // Max bytes per field/object/array (default: 500)
-	TotalLimit        int           // Max total output bytes (default: 1024)
-	Blacklist         []string      // Paths to exclude. Supports wildcards (e.g., "users.*.email")
-	Strategy          TruncStrategy // Removal order during total enforcement (default: RemoveLargest)
-	MaxDepth          int           // Recursion depth limit (default: 10)
-	TruncateStrings   bool          // Truncate long strings with "..." instead of dropping (default: false)
-	ReplaceWithMarker bool          // If true, replaced fields become "[TRIMMED]" instead of being deleted
-	Hooks             Hooks         // Optional pre/post callbacks
+	FieldLimit        int                      // Max bytes per field/object/array (default: 500)
+	TotalLimit        int                      // Max total output bytes (default: 1024)
+	Blacklist         []string                 // Paths to exclude. Supports wildcards (e.g., "users.*.email") and jq-style queries (e.g., `.events[] | select(.level == "debug")`)
+	Whitelist         []string                 // If set, only these paths (and everything under them) are kept. Supports wildcards and jq-style queries
+	Strategy          TruncStrategy            // Removal order during total enforcement (default: RemoveLargest)
+	MaxDepth          int                      // Recursion depth limit (default: 10)
+	TruncateStrings   bool                     // Truncate long strings with "..." instead of dropping (default: false)
+	ReplaceWithMarker bool                     // If true, replaced fields become "[TRIMMED]" instead of being deleted
+	Hooks             Hooks                    // Optional pre/post callbacks
+	Unit              SizeUnit                 // How FieldLimit/TotalLimit are measured (default: Bytes)
+	ParseEmbedded     bool                     // Parse stringified JSON inside string values and trim it recursively (default: false)
+	Transformers      []Transformer            // Value rewrites applied before field limits, in order (default: none)
+	StripControlChars bool                     // Remove ANSI escape sequences and control characters other than newline and tab from strings, before Transformers (default: false)
+	PreserveURLs      bool                     // When truncating URLs, drop the query string before cutting scheme/host/path (default: false)
+	Rename            map[string]string        // Path -> new key, applied during traversal (e.g., "msg": "message"); it replaces an existing key of that name, recorded as a removal. Supports wildcards
+	Required          []string                 // Paths that are never removed by FieldLimit or TotalLimit enforcement. Supports wildcards
+	Atomic            []string                 // Paths kept whole or removed whole: never trimmed inside, truncated or transformed. Supports wildcards
+	Codec             Codec                    // Input/output encoding; limits are measured against it (default: JSONCodec)
+	SubtreeLimits     map[string]int           // Path -> max size of that subtree, enforced before TotalLimit (e.g., "request.headers": 1024). Supports wildcards
+	DepthDecay        float64                  // Per-level factor applied to FieldLimit below the top level, e.g. 0.5 halves it per level (default: 0, disabled)
+	DropNulls         bool                     // Remove explicit null values, as versions before null preservation did (default: false)
+	PruneEmpty        bool                     // Remove objects/arrays left empty by trimming; containers empty in the input are kept (default: false)
+	KeepEmpty         bool                     // Keep arrays/objects whose contents were all stripped as []/{} instead of dropping the key; overrides PruneEmpty (default: false)
+	FieldHooks        map[string]FieldHook     // Path -> callback run when a matching node is visited, before Atomic and Transformers. Supports wildcards
+	HeavyTrimRatio    float64                  // Share of the input a trim must remove to call Hooks.OnHeavyTrim (default: 0.9)
+	Stats             *Stats                   // Aggregates removed paths, bytes saved and failures across calls; see NewStats (default: none)
+	OnTimings         func(PhaseTimings)       // Called after each successful trim with how long each phase took (default: none)
+	AuditWriter       io.Writer                // Receives one JSON line per removed, replaced or truncated path: time, path, reason, original size (default: none)
+	DropIf            map[string]string        // Path -> condition on the document (its own small expression language, not CEL); the path is stripped when it holds (e.g., "response.body": "response.status < 400"). Supports wildcards
+	MaxBuffer         int                      // Max bytes NewTrimmingReader/NewTrimmingWriter buffer; past it, what's buffered is salvaged and trimmed with ErrBufferFull (default: 0, unlimited)
+	Strategies        map[string]TruncStrategy // Path -> strategy for removals inside that subtree, e.g. FIFO{} for "events"; Strategy picks which top-level field to shrink (default: none). Supports wildcards
+	Passes            []Pass                   // Ordered pipeline run before TotalLimit enforcement, each pass until it's done or the document fits, e.g. NullsPass{}, PrefixPass{...}, SamplePass{} (default: none)
+	Weights           map[string]int           // Top-level key -> priority weight; when over TotalLimit, each field is trimmed to its weighted share (default: none, unlisted keys weigh 1)
+	Keys              KeyPolicy                // Normalizes object keys before any other rule, e.g. MongoKeys or ElasticsearchKeys (default: keys kept as is)
+	ProtectTimestamps bool                     // Never truncate RFC3339 strings or epoch numbers under timestamp-like keys ("ts", "*_at", ...), and remove them last (default: false)
+	TimestampPaths    []string                 // Paths always treated as timestamps, as with ProtectTimestamps. Supports wildcards
+	MaxDuration       time.Duration            // Time budget for one trim; past it, enforcement stops and a single-pass fallback keeps what fits in document order (default: 0, unlimited)
+	TruncateOnDecode  bool                     // Cut very long JSON strings before decoding, so they're never materialized in full; a cut string is still over FieldLimit, so trims the same. Required and Atomic strings are left whole, and nothing is cut with ParseEmbedded (default: false)
+	StopAtLimit       bool                     // Stop decoding once what trimming is expected to keep passes TotalLimit; the rest of the input is skipped and its size put under SkippedKey (default: false)
+	Arena             bool                     // When JSONCodec decodes token by token (a plain-path Blacklist or Whitelist, or StopAtLimit), put arrays in chunks pooled across trims instead of one allocation each; hooks, FieldHooks and Transformers must not keep values past the call (default: false)
+	MaxMemory         int                      // Best-effort cap on the bytes decoding the input materializes, estimated before decoding; over it, trims fail with ErrMemoryLimit (default: 0, unlimited)
+	StreamOverMemory  bool                     // Trim inputs over MaxMemory with TrimStream instead of failing; JSON codecs only (default: false)
+	OversizePolicy    OversizePolicy           // What to do with documents over TotalLimit: OversizeTrim, OversizeDrop, OversizeStub or OversizePassThrough (default: OversizeTrim)
+	MaxFields         int                      // Max top-level fields of an object; extra fields are removed by Strategy before TotalLimit is enforced (default: 0, unlimited)
+	NonFinite         NonFinitePolicy          // What NaN and ±Inf numbers become: NonFiniteKeep, NonFiniteNull, NonFiniteMarker or NonFiniteString (default: NonFiniteKeep)
+	Stringers         bool                     // Convert fmt.Stringer values that don't marshal themselves to their String() in TrimValue inputs and hook or Transformer output (default: false)
+	KeepLast          map[string]int           // Path -> keep only the newest N elements (the tail) of the array there, before any other limit, e.g. "events": 50 for ring-buffer style lists (default: none). Supports wildcards
+	Strict            bool                     // Reject invalid or contradictory settings (TruncateStrings with a FieldLimit too small for the suffix, Required paths under Blacklist, TotalLimit below {}): every call fails with ErrInvalidConfig (default: false)
+	SummarizeBytes    bool                     // Replace []byte values in TrimValue inputs and hook or Transformer output with "[N bytes]" instead of their base64 (default: false)
+	SizeFunc          func(v interface{}) int  // Defines size for FieldLimit, TotalLimit, SubtreeLimits and Weights, and ranks values when picking what to remove (default: encoded size in Unit, ranked by a byte estimate)
 }
 
+// SizeUnit selects the unit FieldLimit and TotalLimit are expressed in.
+type SizeUnit int
+
+const (
+	// Bytes measures the UTF-8 encoded output (default).
+	Bytes SizeUnit = iota
+	// UTF16 measures UTF-16 code units, matching JavaScript/Java string length.
+	// Characters outside the Basic Multilingual Plane count as two units.
+	UTF16
+)
+
 // Hooks for extensibility.
 type Hooks struct {
 	PreTrim  func(v interface{}) interface{}
 	PostTrim func(v interface{}, err error) interface{}
+
+	// BeforeTrim and AfterTrim run right after PreTrim and PostTrim and may
+	// abort the trim: a non-nil error is returned from Trim wrapped in
+	// ErrHookAborted, and no output is produced. AfterTrim also receives a
+	// summary of what was trimmed.
+	BeforeTrim func(v interface{}) (interface{}, error)
+	AfterTrim  func(v interface{}, res TrimResult) (interface{}, error)
+
+	// OnLimitExceeded is called before any trimming when the decoded input
+	// exceeds TotalLimit, with the original input and the overage in Unit,
+	// e.g. to count oversized payloads or archive them elsewhere in full.
+	// raw must not be modified.
+	OnLimitExceeded func(raw []byte, over int)
+
+	// OnHeavyTrim is called after a trim that removed more than
+	// Config.HeavyTrimRatio of the input, e.g. to alert when trimming is
+	// destroying payloads rather than bounding them. ratio is
+	// res.BytesRemoved / res.InputSize. raw must not be modified.
+	OnHeavyTrim func(raw []byte, res TrimResult, ratio float64)
 }
 
+// FieldHook is called for a node matching a FieldHooks path, with the path in
+// dot notation. It returns the value to keep (v itself, or a rewrite), or
+// false to drop the node (or mark it, with ReplaceWithMarker).
+type FieldHook func(path string, v interface{}) (interface{}, bool)
+
 // TruncStrategy defines removal policies for EnforceTotalLimit.
 type TruncStrategy interface {
 	// SelectNextToRemove identifies the next field/item to drop.
@@ -33,10 +122,60 @@ type TruncStrategy interface {
 	SelectNextToRemove(v interface{}) string
 }
 
+// BudgetStrategy is an optional extension of TruncStrategy that is told how
+// far over its limit the document is, so it can pick a removal that just gets
+// under it instead of always the largest. BestFit implements it.
+type BudgetStrategy interface {
+	TruncStrategy
+	// SelectWithBudget is SelectNextToRemove given the overage, in the
+	// Trimmer's Unit.
+	SelectWithBudget(v interface{}, over int) string
+}
+
+// BulkStrategy is an optional extension of TruncStrategy for arrays. It ranks
+// every element for removal at once, so the Trimmer can work out how many
+// elements must go and drop them in one step instead of asking the strategy
+// and re-measuring after each one. RemoveLargest and FIFO implement it.
+type BulkStrategy interface {
+	TruncStrategy
+	// RemovalOrder returns the indexes of arr in the order they should be removed.
+	RemovalOrder(arr []interface{}) []int
+}
+
+// Transformer rewrites values during field trimming, before limits are checked.
+// It is called for every node (containers included) with the node's path.
+type Transformer interface {
+	// Transform returns the replacement for v, or v itself to leave it alone.
+	// path must not be retained after the call returns.
+	Transform(path []string, v interface{}) interface{}
+}
+
+// markerString is the type of marked.
+type markerString string
+
+// marked stands in for Marker while trimming, so input strings that happen to
+// equal Marker are never mistaken for trimmer output. It is rendered as a
+// plain Marker string before hooks run and in the output.
+var marked interface{} = markerString(Marker)
+
+// removal is the type of removed.
+type removal struct{}
+
+// removed is returned by the internal passes for a node that must be dropped.
+// It keeps deletions distinguishable from legitimate JSON nulls and never
+// reaches the output.
+var removed interface{} = removal{}
+
 // Built-in strategies.
 type (
-	RemoveLargest  struct{}
-	FIFO           struct{}
+	RemoveLargest struct {
+		Size func(v interface{}) int // Ranks entries; set from Config.SizeFunc when nil (default: a byte estimate)
+	}
+	FIFO struct{}
+	// Sample thins arrays evenly, keeping first and last; objects lose their largest fields
+	Sample struct {
+		KeepNumbers bool // Objects lose their largest non-numeric fields only (default: false)
+	}
 	PrioritizeKeys struct {
 		KeepKeys []string
 		Fallback TruncStrategy
@@ -46,6 +185,10 @@ type (
 var (
 	// ErrCannotTrim indicates the JSON couldn't be reduced below limits.
 	ErrCannotTrim = errors.New("cannot trim JSON below limits")
+	// ErrHookAborted wraps an error returned by Hooks.BeforeTrim or Hooks.AfterTrim.
+	ErrHookAborted = errors.New("trim aborted by hook")
+	// ErrRequiredTooLarge indicates the Required fields alone exceed TotalLimit.
+	ErrRequiredTooLarge = errors.New("required fields exceed total limit")
 	// Marker is the value used when ReplaceWithMarker is true.
 	Marker = "[TRIMMED]"
 )
@@ -58,7 +201,7 @@ func (s RemoveLargest) SelectNextToRemove(v interface{}) string {
 		maxSize := 0
 		for k, val := range vv {
 			// Optimization: Use size estimation to avoid heavy allocations
-			sz := estimateSize(val)
+			sz := sizeWith(s.Size, val)
 			if sz > maxSize {
 				maxSize = sz
 				maxKey = k
@@ -69,7 +212,7 @@ func (s RemoveLargest) SelectNextToRemove(v interface{}) string {
 		maxIdx := -1
 		maxSize := 0
 		for i, item := range vv {
-			sz := estimateSize(item)
+			sz := sizeWith(s.Size, item)
 			if sz > maxSize {
 				maxSize = sz
 				maxIdx = i
@@ -82,13 +225,29 @@ func (s RemoveLargest) SelectNextToRemove(v interface{}) string {
 	return ""
 }
 
-// SelectNextToRemove for FIFO: First key or index 0.
+// RemovalOrder for RemoveLargest: Largest first, earlier index on ties.
+func (s RemoveLargest) RemovalOrder(arr []interface{}) []int {
+	sizes := make([]int, len(arr))
+	order := make([]int, len(arr))
+	for i, item := range arr {
+		sizes[i] = sizeWith(s.Size, item)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sizes[order[a]] > sizes[order[b]] })
+	return order
+}
+
+// SelectNextToRemove for FIFO: First key in sorted (output) order, or index 0.
 func (s FIFO) SelectNextToRemove(v interface{}) string {
 	switch vv := v.(type) {
 	case map[string]interface{}:
+		first, found := "", false
 		for k := range vv {
-			return k
+			if !found || k < first {
+				first, found = k, true
+			}
 		}
+		return first
 	case []interface{}:
 		if len(vv) > 0 {
 			return fmt.Sprintf("idx:%d", 0)
@@ -97,6 +256,63 @@ func (s FIFO) SelectNextToRemove(v interface{}) string {
 	return ""
 }
 
+// RemovalOrder for FIFO: Front to back.
+func (s FIFO) RemovalOrder(arr []interface{}) []int {
+	order := make([]int, len(arr))
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+// SelectNextToRemove for Sample: The first index in RemovalOrder; largest key
+// for objects, skipping numbers with KeepNumbers.
+func (s Sample) SelectNextToRemove(v interface{}) string {
+	switch vv := v.(type) {
+	case []interface{}:
+		if len(vv) > 0 {
+			return fmt.Sprintf("idx:%d", s.RemovalOrder(vv)[0])
+		}
+		return ""
+	case map[string]interface{}:
+		if s.KeepNumbers {
+			candidates := make(map[string]interface{}, len(vv))
+			for k, val := range vv {
+				if _, ok := toFloat(val); !ok {
+					candidates[k] = val
+				}
+			}
+			v = candidates
+		}
+	}
+	return RemoveLargest{}.SelectNextToRemove(v)
+}
+
+// RemovalOrder for Sample: The reverse of a keep order that takes both ends
+// and then repeatedly the midpoints of the gaps, so whatever survives is
+// spread evenly across the array.
+func (s Sample) RemovalOrder(arr []interface{}) []int {
+	n := len(arr)
+	keep := make([]int, 0, n)
+	if n > 0 {
+		keep = append(keep, 0)
+	}
+	if n > 1 {
+		keep = append(keep, n-1)
+	}
+	for gaps := [][2]int{{0, n - 1}}; len(gaps) > 0; gaps = gaps[1:] {
+		lo, hi := gaps[0][0], gaps[0][1]
+		if hi-lo < 2 {
+			continue
+		}
+		mid := (lo + hi) / 2
+		keep = append(keep, mid)
+		gaps = append(gaps, [2]int{lo, mid}, [2]int{mid, hi})
+	}
+	slices.Reverse(keep)
+	return keep
+}
+
 // SelectNextToRemove for PrioritizeKeys: Skips keep keys, falls back.
 func (s PrioritizeKeys) SelectNextToRemove(v interface{}) string {
 	fallback := s.Fallback
@@ -133,26 +349,110 @@ func (s PrioritizeKeys) SelectNextToRemove(v interface{}) string {
 	return ""
 }
 
-// Trimmer is the main struct.
+// Trimmer is the main struct. It is safe for concurrent use: its
+// configuration is never modified after it's built, every trim works on its
+// own decoded tree, and Update swaps in a whole new configuration that each
+// call picks up as a snapshot when it starts.
 type Trimmer struct {
+	live           *atomic.Pointer[Trimmer] // Latest version set by Update; nil on per-call and derived copies
 	cfg            Config
-	blacklistParts [][]string // Pre-split paths for faster wildcard matching
+	defaults       limitDefaults // Limits cfg left zero, filled in with their defaults
+	blacklistParts [][]string    // Pre-split paths for faster wildcard matching
+	blacklistRules []string      // The Blacklist entry each of blacklistParts comes from ("" for DropIf)
+	whitelistParts [][]string
+	blacklistQuery []query  // jq-style Blacklist entries, resolved to paths per document
+	queryRules     []string // The Blacklist entry of each of blacklistQuery
+	whitelistQuery []query
+	selectQuery    []query      // Query of each Select in Transformers, by index (nil for other Transformers)
+	selectPaths    [][][]string // Set on per-call copies: the paths each selectQuery resolved to
+	dropIfRules    []dropIfRule
+	renameRules    []renameRule
+	requiredParts  [][]string
+	atomicParts    [][]string
+	subtreeRules   []subtreeRule
+	keepLastRules  []subtreeRule
+	strategyRules  []strategyRule
+	fieldHooks     []fieldHookRule
+	scansFields    bool          // Oversized top-level strings may be dropped before decoding, see dropLongFields
+	longFields     [][2][]byte   // Set on per-call copies: key and raw value of each field dropLongFields dropped
+	err            error         // Configuration error returned by every trim
+	stats          *trimStats    // Set on per-call copies that record what they trim
+	clock          *trimClock    // Set on per-call copies when MaxDuration is set
+	sizes          sizeCache     // Set on per-call copies, see cachesSizes
+	arena          *nodeArena    // Set on per-call copies with Arena, until the output is encoded
+	timings        *PhaseTimings // Set on per-call copies that time their phases
+}
+
+// renameRule is a pre-split Rename entry.
+type renameRule struct {
+	parts []string
+	to    string
+}
+
+// fieldHookRule is a pre-split FieldHooks entry.
+type fieldHookRule struct {
+	parts []string
+	fn    FieldHook
+}
+
+// dropIfRule is a pre-split DropIf entry with its compiled condition.
+type dropIfRule struct {
+	parts []string
+	cond  queryExpr
+}
+
+// subtreeRule is a pre-split SubtreeLimits or KeepLast entry.
+type subtreeRule struct {
+	parts []string
+	limit int
+}
+
+// strategyRule is a pre-split Strategies entry.
+type strategyRule struct {
+	parts    []string
+	strategy TruncStrategy
 }
 
 // New creates a Trimmer with defaults filled.
 func New(cfg Config) *Trimmer {
-	if cfg.FieldLimit == 0 {
-		cfg.FieldLimit = 500
+	defaults := defaultLimits(&cfg, nil)
+	t := build(cfg, nil)
+	t.defaults = defaults
+	return t
+}
+
+// limitDefaults records which limits took their defaults.
+type limitDefaults struct {
+	fieldLimit, totalLimit, maxDepth bool
+}
+
+// defaultLimits fills in zero FieldLimit, TotalLimit and MaxDepth and reports
+// which it filled. A zero that parent has too was set explicitly through
+// NewV2 and is kept.
+func defaultLimits(cfg *Config, parent *Config) limitDefaults {
+	var d limitDefaults
+	if cfg.FieldLimit == 0 && (parent == nil || parent.FieldLimit != 0) {
+		cfg.FieldLimit, d.fieldLimit = 500, true
 	}
-	if cfg.TotalLimit == 0 {
-		cfg.TotalLimit = 1024
+	if cfg.TotalLimit == 0 && (parent == nil || parent.TotalLimit != 0) {
+		cfg.TotalLimit, d.totalLimit = 1024, true
 	}
-	if cfg.MaxDepth == 0 {
-		cfg.MaxDepth = 10
+	if cfg.MaxDepth == 0 && (parent == nil || parent.MaxDepth != 0) {
+		cfg.MaxDepth, d.maxDepth = 10, true
 	}
+	return d
+}
+
+// build fills in the remaining defaults and compiles cfg. Compiled
+// Blacklist/Whitelist rules are taken from base when they are unchanged.
+func build(cfg Config, base *Trimmer) *Trimmer {
+	scans := scansFields(&cfg)
 	if cfg.Strategy == nil {
 		cfg.Strategy = RemoveLargest{}
 	}
+	if cfg.Codec == nil {
+		cfg.Codec = JSONCodec{}
+	}
 	if cfg.Hooks.PreTrim == nil {
 		cfg.Hooks.PreTrim = func(v interface{}) interface{} { return v }
 	}
@@ -160,64 +460,441 @@ func New(cfg Config) *Trimmer {
 		cfg.Hooks.PostTrim = func(v interface{}, err error) interface{} { return v }
 	}
 
-	t := &Trimmer{cfg: cfg}
+	t := &Trimmer{cfg: cfg, live: new(atomic.Pointer[Trimmer]), scansFields: scans}
+	if base != nil && base.err == nil && slices.Equal(cfg.Blacklist, base.cfg.Blacklist) && slices.Equal(cfg.Whitelist, base.cfg.Whitelist) {
+		// Compiled rules are never modified after build, so they can be shared
+		t.blacklistParts, t.blacklistQuery = base.blacklistParts, base.blacklistQuery
+		t.blacklistRules, t.queryRules = base.blacklistRules, base.queryRules
+		t.whitelistParts, t.whitelistQuery = base.whitelistParts, base.whitelistQuery
+	} else {
+		t.compileLists()
+	}
+	t.compileSelects()
+	for _, p := range cfg.Required {
+		t.requiredParts = append(t.requiredParts, strings.Split(p, "."))
+	}
+	for _, p := range cfg.Atomic {
+		t.atomicParts = append(t.atomicParts, strings.Split(p, "."))
+	}
+	for p, expr := range cfg.DropIf {
+		cond, err := parseCondition(expr)
+		if err != nil {
+			if t.err == nil {
+				t.err = err
+			}
+			continue
+		}
+		t.dropIfRules = append(t.dropIfRules, dropIfRule{parts: strings.Split(p, "."), cond: cond})
+	}
+	for p, limit := range cfg.SubtreeLimits {
+		t.subtreeRules = append(t.subtreeRules, subtreeRule{parts: strings.Split(p, "."), limit: limit})
+	}
+	for p, n := range cfg.KeepLast {
+		t.keepLastRules = append(t.keepLastRules, subtreeRule{parts: strings.Split(p, "."), limit: n})
+	}
+	for p, s := range cfg.Strategies {
+		t.strategyRules = append(t.strategyRules, strategyRule{parts: strings.Split(p, "."), strategy: s})
+	}
+	for p, fn := range cfg.FieldHooks {
+		t.fieldHooks = append(t.fieldHooks, fieldHookRule{parts: strings.Split(p, "."), fn: fn})
+	}
+	// Run overlapping hooks in a stable order
+	sort.Slice(t.fieldHooks, func(i, j int) bool {
+		return strings.Join(t.fieldHooks[i].parts, ".") < strings.Join(t.fieldHooks[j].parts, ".")
+	})
+	for from, to := range cfg.Rename {
+		t.renameRules = append(t.renameRules, renameRule{parts: strings.Split(from, "."), to: to})
+	}
+	if cfg.Strict && t.err == nil {
+		t.err = validateConfig(cfg)
+	}
+	return t
+}
+
+// compileLists pre-splits Blacklist and Whitelist paths and compiles their queries.
+func (t *Trimmer) compileLists() {
 	// Pre-process blacklist for wildcard support (Feature re-added)
-	for _, p := range cfg.Blacklist {
+	for _, p := range t.cfg.Blacklist {
+		if isQuery(p) {
+			if qs := t.addQuery(t.blacklistQuery, p); len(qs) > len(t.blacklistQuery) {
+				t.blacklistQuery = qs
+				t.queryRules = append(t.queryRules, p)
+			}
+			continue
+		}
 		t.blacklistParts = append(t.blacklistParts, strings.Split(p, "."))
+		t.blacklistRules = append(t.blacklistRules, p)
 	}
-	return t
+	for _, p := range t.cfg.Whitelist {
+		if isQuery(p) {
+			t.whitelistQuery = t.addQuery(t.whitelistQuery, p)
+			continue
+		}
+		t.whitelistParts = append(t.whitelistParts, strings.Split(p, "."))
+	}
+}
+
+// addQuery compiles expr and appends it to qs, keeping the first error for trim to report.
+func (t *Trimmer) addQuery(qs []query, expr string) []query {
+	q, err := parseQuery(expr)
+	if err != nil {
+		if t.err == nil {
+			t.err = err
+		}
+		return qs
+	}
+	return append(qs, q)
+}
+
+// Config returns the configuration t runs with, defaults filled in.
+func (t *Trimmer) Config() Config {
+	return t.current().cfg
 }
 
 // Trim takes raw JSON bytes, strips blacklist, applies limits, and returns trimmed bytes.
 func (t *Trimmer) Trim(raw []byte) ([]byte, error) {
-	var v interface{}
-	if err := json.Unmarshal(raw, &v); err != nil {
-		return nil, err
+	out, _, err := t.trim(raw, false)
+	return out, err
+}
+
+// Err returns the configuration error every trim fails with, if any: a
+// malformed query or condition, or with Strict, anything Builder.Build
+// would reject. Check it after New to fail at startup instead.
+func (t *Trimmer) Err() error {
+	return t.current().err
+}
+
+// TrimString is Trim for strings. Neither the input nor the output is
+// copied: codecs only read their input, and the output buffer isn't kept.
+func (t *Trimmer) TrimString(s string) (string, error) {
+	out, err := t.Trim(unsafe.Slice(unsafe.StringData(s), len(s)))
+	if err != nil || len(out) == 0 {
+		return "", err
+	}
+	return unsafe.String(&out[0], len(out)), nil
+}
+
+// MustTrim is like Trim but panics on error. It is meant for fixtures and
+// init-time data, not for untrusted input.
+func (t *Trimmer) MustTrim(raw []byte) []byte {
+	out, err := t.Trim(raw)
+	if err != nil {
+		panic("jsontrim: " + err.Error())
 	}
+	return out
+}
 
-	// Step 0: Strip blacklisted paths (Wildcard aware)
-	v = t.stripBlacklisted(v)
+// TrimOrOriginal is like Trim but returns raw unchanged on any error, for
+// call sites (like logging) that prefer an untrimmed payload to none.
+func (t *Trimmer) TrimOrOriginal(raw []byte) []byte {
+	out, err := t.Trim(raw)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// defaultTrimmer backs the package-level Trim. It is built on first use.
+var defaultTrimmer = sync.OnceValue(func() *Trimmer {
+	return New(Config{TruncateStrings: true})
+})
+
+// Trim trims raw to at most limit bytes with default settings, for callers
+// that just need a payload under a size. Fields are only cut as far as the
+// total requires. A limit <= 0 uses the default TotalLimit.
+func Trim(raw []byte, limit int) ([]byte, error) {
+	t := defaultTrimmer()
+	if limit > 0 {
+		t = t.withTotalLimit(limit)
+		t.cfg.FieldLimit = limit
+	}
+	return t.Trim(raw)
+}
+
+// trim runs the pipeline and encodes the result. With collect set (or an
+// AfterTrim hook) it works on a copy of t that records what it trims, and
+// fills in the TrimResult.
+func (t *Trimmer) trim(raw []byte, collect bool) ([]byte, TrimResult, error) {
+	out, res, err := t.trimOnce(raw, collect)
+	if err != nil {
+		return nil, res, t.failed(err)
+	}
+	return out, res, nil
+}
+
+// trimOnce is trim without failure accounting.
+func (t *Trimmer) trimOnce(raw []byte, collect bool) ([]byte, TrimResult, error) {
+	t, v, err := t.process(raw, collect)
+	defer t.releaseArena()
+	if t.streamsOver(err) {
+		return t.trimStreamed(raw)
+	}
+	if err != nil {
+		return nil, TrimResult{}, err
+	}
+	start := t.startPhase()
+	out, err := t.encode(v)
+	if err != nil {
+		return nil, TrimResult{}, err
+	}
+	t.endPhase(phaseEncode, start)
+	res, err := t.finish(raw, out, v)
+	if err != nil {
+		return nil, res, err
+	}
+	return out, res, nil
+}
+
+// process decodes raw and runs every trimming step and hook on it. It returns
+// the Trimmer the call ran on (a recording copy of t, when collecting) with
+// the trimmed tree.
+func (t *Trimmer) process(raw []byte, collect bool) (*Trimmer, interface{}, error) {
+	t = t.current()
+	if t.err != nil {
+		return t, nil, t.err
+	}
+	run := *t
+	run.live = nil
+	if collect || t.cfg.Hooks.AfterTrim != nil || t.cfg.Hooks.OnHeavyTrim != nil || t.cfg.AuditWriter != nil || t.cfg.Stats != nil {
+		run.stats = &trimStats{sized: t.cfg.AuditWriter != nil}
+	}
+	if t.cfg.MaxDuration > 0 {
+		run.clock = &trimClock{deadline: time.Now().Add(t.cfg.MaxDuration)}
+	}
+	if t.cachesSizes() {
+		run.sizes = sizeCache{}
+	}
+	if t.timesPhases(collect) {
+		run.timings = &PhaseTimings{}
+	}
+	t = &run
+	start := t.startPhase()
+
+	src := raw
+	if t.scansFields {
+		src = t.dropLongFields(src)
+	}
+	if _, ok := t.cfg.Codec.(JSONCodec); ok && t.cfg.TruncateOnDecode && t.cfg.FieldLimit > 0 && !t.cfg.ParseEmbedded && t.cfg.SizeFunc == nil {
+		var keeps func([]string) bool
+		if len(t.requiredParts) > 0 || len(t.atomicParts) > 0 {
+			keeps = t.keepsWhole
+		}
+		src = capStrings(src, maxEscapeRatio*t.cfg.FieldLimit, keeps)
+	}
+	if err := t.checkMemory(src); err != nil {
+		return t, nil, err
+	}
+	var (
+		v   interface{}
+		err error
+	)
+	fused := t.fusesStrip()
+	if c, ok := t.cfg.Codec.(JSONCodec); ok && (fused || t.cfg.StopAtLimit) {
+		if t.cfg.Arena { // Only here: encoding/json's own decoder allocates less than decodeTree with an arena
+			t.arena = arenaPool.Get().(*nodeArena)
+		}
+		v, err = t.decodeTree(c, src, fused)
+	} else {
+		v, err = t.cfg.Codec.Decode(src)
+	}
+	if err != nil {
+		return t, nil, err
+	}
+	if _, ok := t.cfg.Codec.(JSONCodec); !ok {
+		v = stringKeys(v) // Custom codecs may decode YAML-style maps
+	}
+	start = t.endPhase(phaseDecode, start)
+	if t.cfg.Hooks.OnLimitExceeded != nil {
+		if size, err := t.totalSize(v); err == nil {
+			if over := size - t.cfg.TotalLimit; over > 0 {
+				t.cfg.Hooks.OnLimitExceeded(raw, over)
+			}
+		}
+	}
+
+	if t.cfg.Keys.enabled() {
+		v = t.normalizeKeys(v, 0, nil)
+	}
+
+	// Step 0: Strip blacklisted paths (Wildcard aware), unless done while decoding
+	if !fused {
+		v = t.stripBlacklisted(v)
+	}
+	t.endPhase(phaseBlacklist, start)
 
 	// Hooks: Pre
+	if t.cfg.ReplaceWithMarker {
+		v = renderMarkers(v, true)
+	}
 	v = t.cfg.Hooks.PreTrim(v)
+	if t.cfg.Hooks.BeforeTrim != nil {
+		if v, err = t.cfg.Hooks.BeforeTrim(v); err != nil {
+			return t, nil, fmt.Errorf("%w: %w", ErrHookAborted, err)
+		}
+	}
 
-	// Step 1: Trim oversized fields (recursive)
-	v = t.trimFields(v, 1)
-
-	// Step 2: Enforce total limit
-	v = t.enforceTotal(v)
+	handled, err := t.applyOversize(&v)
+	if err != nil {
+		return t, nil, err
+	}
+	if !handled {
+		if v, err = t.enforceLimits(v); err != nil {
+			return t, nil, err
+		}
+	}
 
 	// Hooks: Post
+	if t.cfg.ReplaceWithMarker {
+		v = renderMarkers(v, true)
+	}
 	v = t.cfg.Hooks.PostTrim(v, nil)
+	if t.cfg.Hooks.AfterTrim != nil {
+		encoded, err := t.encode(v)
+		if err != nil {
+			return t, nil, err
+		}
+		if v, err = t.cfg.Hooks.AfterTrim(v, t.stats.result(t.measure(raw), t.measure(encoded))); err != nil {
+			return t, nil, fmt.Errorf("%w: %w", ErrHookAborted, err)
+		}
+	}
+	return t, v, nil
+}
 
-	out, err := json.Marshal(v)
-	if err != nil {
-		return nil, err
+// enforceLimits trims fields and then enforces subtree and total limits.
+func (t *Trimmer) enforceLimits(v interface{}) (interface{}, error) {
+	// Step 1: Trim oversized fields (recursive)
+	start := t.startPhase()
+	if t.selectQuery != nil {
+		t.selectPaths = make([][][]string, len(t.selectQuery))
+		for i, q := range t.selectQuery {
+			if q != nil {
+				t.selectPaths[i] = q.paths(v)
+			}
+		}
+	}
+	for _, f := range t.longFields {
+		t.record([]string{string(f[0])}, reasonFieldLimit, json.RawMessage(f[1]))
+	}
+	if v = t.trimFields(v, 1, nil); v == removed {
+		v = nil
 	}
+	start = t.endPhase(phaseFieldTrim, start)
 
+	// Step 2: Enforce subtree and total limits
+	if len(t.subtreeRules) > 0 {
+		v = t.enforceSubtreeLimits(v, nil)
+	}
+	if t.cfg.MaxFields > 0 {
+		v = t.enforceMaxFields(v)
+	}
+	if len(t.cfg.Passes) > 0 {
+		v = t.runPasses(v)
+	}
+	if len(t.cfg.Weights) > 0 {
+		v = t.enforceWeighted(v)
+	}
+	v = t.enforceTotal(v)
+	if t.expired() {
+		v = t.hardFit(v, t.cfg.TotalLimit, nil) // Keeps Required fields first
+	}
+	if len(t.requiredParts) > 0 {
+		var err error
+		if v, err = t.enforceRequired(v); err != nil {
+			return nil, err
+		}
+	}
+	t.endPhase(phaseEnforce, start)
+	return v, nil
+}
+
+// finish checks the output v, encoded as out, against TotalLimit, builds
+// the TrimResult and writes the audit log.
+func (t *Trimmer) finish(raw, out []byte, v interface{}) (TrimResult, error) {
+	var res TrimResult
 	// Defensive check
-	if len(out) > t.cfg.TotalLimit {
-		return nil, ErrCannotTrim
+	size := t.measure(out)
+	if t.cfg.SizeFunc != nil {
+		size = t.cfg.SizeFunc(v)
+	}
+	if size > t.cfg.TotalLimit && t.cfg.OversizePolicy != OversizePassThrough {
+		return res, ErrCannotTrim
 	}
 
-	return out, nil
+	if t.stats != nil {
+		res = t.stats.result(t.measure(raw), t.measure(out))
+	}
+	res.TimedOut = t.expired()
+	if t.timings != nil {
+		res.Timings = *t.timings
+		if t.cfg.OnTimings != nil {
+			t.cfg.OnTimings(res.Timings)
+		}
+	}
+	if t.cfg.AuditWriter != nil {
+		if err := t.writeAudit(); err != nil {
+			return res, fmt.Errorf("audit: %w", err)
+		}
+	}
+	if t.cfg.Stats != nil {
+		t.cfg.Stats.observe(res)
+	}
+	if t.cfg.Hooks.OnHeavyTrim != nil && res.InputSize > 0 {
+		threshold := t.cfg.HeavyTrimRatio
+		if threshold <= 0 {
+			threshold = 0.9
+		}
+		if ratio := float64(res.BytesRemoved) / float64(res.InputSize); ratio > threshold {
+			t.cfg.Hooks.OnHeavyTrim(raw, res, ratio)
+		}
+	}
+	return res, nil
 }
 
 // stripBlacklisted removes fields matching the config paths (Wildcard Feature re-added).
 func (t *Trimmer) stripBlacklisted(v interface{}) interface{} {
-	if len(t.blacklistParts) == 0 {
+	if len(t.cfg.Blacklist) == 0 && len(t.cfg.Whitelist) == 0 && len(t.dropIfRules) == 0 {
 		return v
 	}
-	return t.stripRecursive(v, []string{})
+	if len(t.blacklistQuery) > 0 || len(t.whitelistQuery) > 0 || len(t.dropIfRules) > 0 {
+		// Queries and conditions are resolved against this document; the
+		// paths they yield are then matched like any other rule
+		run := *t
+		run.blacklistParts = t.blacklistParts[:len(t.blacklistParts):len(t.blacklistParts)]
+		run.blacklistRules = t.blacklistRules[:len(t.blacklistRules):len(t.blacklistRules)]
+		for i, q := range t.blacklistQuery {
+			for _, p := range q.paths(v) {
+				run.blacklistParts = append(run.blacklistParts, p)
+				run.blacklistRules = append(run.blacklistRules, t.queryRules[i])
+			}
+		}
+		for _, r := range t.dropIfRules {
+			if truthy(r.cond.eval(v)) {
+				run.blacklistParts = append(run.blacklistParts, r.parts)
+				run.blacklistRules = append(run.blacklistRules, "")
+			}
+		}
+		run.whitelistParts = append(t.whitelistParts[:len(t.whitelistParts):len(t.whitelistParts)], resolveQueries(t.whitelistQuery, v)...)
+		t = &run
+	}
+	if v = t.stripRecursive(v, []string{}); v == removed {
+		return nil
+	}
+	return v
 }
 
 func (t *Trimmer) stripRecursive(v interface{}, currentPath []string) interface{} {
-	// Check if current path matches any blacklist rule
-	if t.matchesBlacklist(currentPath) {
+	// Check if current path matches any blacklist rule, or falls outside the whitelist
+	rule, blacklisted := t.blacklistRule(currentPath)
+	if blacklisted || !t.whitelisted(currentPath) {
+		if blacklisted {
+			t.recordRule(currentPath, reasonBlacklist, rule, v)
+		} else {
+			t.record(currentPath, reasonWhitelist, v)
+		}
 		if t.cfg.ReplaceWithMarker {
-			return Marker
+			return marked
 		}
-		return nil
+		return removed
 	}
 
 	switch vv := v.(type) {
@@ -226,10 +903,14 @@ func (t *Trimmer) stripRecursive(v interface{}, currentPath []string) interface{
 		for k, val := range vv {
 			newPath := append(currentPath, k)
 			stripped := t.stripRecursive(val, newPath)
-			if stripped != nil {
+			if stripped != removed {
 				out[k] = stripped
 			}
 		}
+		if t.prunes(currentPath, len(vv), len(out)) {
+			t.record(currentPath, reasonEmpty, vv)
+			return removed
+		}
 		return out
 	case []interface{}:
 		out := make([]interface{}, 0, len(vv))
@@ -237,36 +918,186 @@ func (t *Trimmer) stripRecursive(v interface{}, currentPath []string) interface{
 			// Arrays use index in path for matching, e.g., "data.0"
 			newPath := append(currentPath, fmt.Sprintf("%d", i))
 			stripped := t.stripRecursive(item, newPath)
-			if stripped != nil {
+			if stripped != removed {
 				out = append(out, stripped)
 			}
 		}
-		if len(out) == 0 {
-			return nil
+		if len(out) == 0 && !t.cfg.KeepEmpty {
+			if len(vv) > 0 {
+				t.record(currentPath, reasonEmpty, vv)
+			}
+			return removed
 		}
 		return out
+	case string:
+		// Embedded JSON: blacklist paths continue into the parsed document
+		if t.cfg.ParseEmbedded {
+			if parsed, ok := parseEmbedded(vv); ok {
+				stripped := t.stripRecursive(parsed, currentPath)
+				if stripped == removed {
+					return removed
+				}
+				b, err := json.Marshal(stripped)
+				if err == nil {
+					return string(b)
+				}
+			}
+		}
 	}
 	return v
 }
 
+// prunes reports whether a container at path that went from before to after
+// entries should be removed under PruneEmpty. The root and Required paths stay.
+func (t *Trimmer) prunes(path []string, before, after int) bool {
+	return t.cfg.PruneEmpty && !t.cfg.KeepEmpty && before > 0 && after == 0 && len(path) > 0 && !t.isRequired(path)
+}
+
+// whitelisted reports whether path may be kept under Whitelist: it lies on
+// the way to, at, or under a whitelisted path. Everything passes when
+// Whitelist is empty.
+func (t *Trimmer) whitelisted(path []string) bool {
+	if len(t.cfg.Whitelist) == 0 || len(path) == 0 {
+		return true
+	}
+	for _, rule := range t.whitelistParts {
+		n := min(len(rule), len(path))
+		if matchParts(rule[:n], path[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveQueries returns the paths every query selects in v.
+func resolveQueries(qs []query, v interface{}) [][]string {
+	var paths [][]string
+	for _, q := range qs {
+		paths = append(paths, q.paths(v)...)
+	}
+	return paths
+}
+
 // matchesBlacklist checks if the current path slice matches any blacklist pattern (Wildcard Feature re-added).
 func (t *Trimmer) matchesBlacklist(path []string) bool {
+	_, ok := t.blacklistRule(path)
+	return ok
+}
+
+// blacklistRule returns the first Blacklist entry matching path.
+func (t *Trimmer) blacklistRule(path []string) (string, bool) {
 	if len(path) == 0 {
-		return false
+		return "", false
 	}
-	for _, rule := range t.blacklistParts {
-		if len(rule) != len(path) {
+	for i, rule := range t.blacklistParts {
+		if matchParts(rule, path) {
+			return t.blacklistRules[i], true
+		}
+	}
+	return "", false
+}
+
+// renamedField is a field trimFields renamed, with the key it had.
+type renamedField struct {
+	from        string
+	v, original interface{}
+}
+
+// renameKey returns the new key for the field at path, if a Rename rule matches.
+func (t *Trimmer) renameKey(path []string) (string, bool) {
+	for _, r := range t.renameRules {
+		if matchParts(r.parts, path) {
+			return r.to, true
+		}
+	}
+	return "", false
+}
+
+// runFieldHooks passes v through every FieldHooks entry matching path. It
+// stops and returns false as soon as one of them vetoes the node.
+func (t *Trimmer) runFieldHooks(path []string, v interface{}) (interface{}, bool) {
+	var joined string
+	for _, h := range t.fieldHooks {
+		if !matchParts(h.parts, path) {
 			continue
 		}
-		match := true
-		for i, part := range rule {
-			// Wildcard match or exact match
-			if part != "*" && part != path[i] {
-				match = false
-				break
+		if joined == "" {
+			joined = strings.Join(path, ".")
+		}
+		var keep bool
+		if v, keep = h.fn(joined, v); !keep {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// keepsWhole reports whether the string at path must never be cut: it's
+// Required, or at or below an Atomic path.
+func (t *Trimmer) keepsWhole(path []string) bool {
+	if t.protects(path) {
+		return true
+	}
+	for i := range path {
+		if t.isAtomic(path[:i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAtomic reports whether path matches an Atomic rule.
+func (t *Trimmer) isAtomic(path []string) bool {
+	for _, rule := range t.atomicParts {
+		if matchParts(rule, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchParts reports whether path matches a pre-split pattern. "*" matches any single segment.
+func matchParts(rule, path []string) bool {
+	if len(rule) != len(path) {
+		return false
+	}
+	for i, part := range rule {
+		// Wildcard match or exact match
+		switch part {
+		case "*":
+		case literalStar:
+			if path[i] != "*" {
+				return false
+			}
+		default:
+			if part != path[i] {
+				return false
 			}
 		}
-		if match {
+	}
+	return true
+}
+
+// matchPattern is matchParts for a dotted pattern that hasn't been split,
+// for callers (like transformers) that hold patterns as plain strings.
+func matchPattern(pattern string, path []string) bool {
+	for i := 0; i < len(path); i++ {
+		seg, rest, more := strings.Cut(pattern, ".")
+		if seg != "*" && seg != path[i] {
+			return false
+		}
+		if !more {
+			return i == len(path)-1
+		}
+		pattern = rest
+	}
+	return false
+}
+
+// matchesAnyPattern reports whether path matches any of the dotted patterns.
+func matchesAnyPattern(patterns []string, path []string) bool {
+	for _, p := range patterns {
+		if matchPattern(p, path) {
 			return true
 		}
 	}
@@ -274,100 +1105,276 @@ func (t *Trimmer) matchesBlacklist(path []string) bool {
 }
 
 // trimFields recursively trims nested content (Marker Feature re-added).
-func (t *Trimmer) trimFields(v interface{}, depth int) interface{} {
+func (t *Trimmer) trimFields(v interface{}, depth int, path []string) interface{} {
 	if depth > t.cfg.MaxDepth {
+		t.record(path, reasonMaxDepth, v)
 		if t.cfg.ReplaceWithMarker {
-			return Marker
+			return marked
 		}
-		return nil
+		return removed
+	}
+
+	if len(t.fieldHooks) > 0 {
+		hooked, keep := t.runFieldHooks(path, v)
+		if !keep {
+			t.record(path, reasonFieldHook, v)
+			if t.cfg.ReplaceWithMarker {
+				return marked
+			}
+			return removed
+		}
+		v = hooked
 	}
 
+	// Atomic nodes are kept verbatim; the parent's size check may still drop them whole
+	if t.isAtomic(path) {
+		return v
+	}
+
+	if t.cfg.StripControlChars {
+		if str, ok := v.(string); ok {
+			v = stripControl(str)
+		}
+	}
+	for i, tf := range t.cfg.Transformers {
+		if sel, ok := tf.(Select); ok {
+			if sel.Transformer == nil || !t.selected(i, path) {
+				continue
+			}
+			tf = sel.Transformer
+		}
+		v = tf.Transform(path, v)
+	}
+	if m, ok := v.(map[interface{}]interface{}); ok {
+		v = stringKeys(m) // From a hook or Transformer
+	}
+	v = t.finite(t.plainValue(v))
+
 	switch vv := v.(type) {
 	case map[string]interface{}:
 		out := make(map[string]interface{})
+		var renamed map[string]renamedField // Merged last so renamed keys win collisions
+		childLimit := t.fieldLimit(depth + 1)
 		for k, val := range vv {
-			trimmed := t.trimFields(val, depth+1)
-			if trimmed == nil {
+			childPath := append(path, k)
+			trimmed := t.trimFields(val, depth+1, childPath)
+			if trimmed == removed {
 				continue
 			}
-			// Check individual field size
-			if estimateSize(trimmed) > t.cfg.FieldLimit { // Use estimateSize
+			// Check individual field size (Required fields and their parents are exempt)
+			if !t.protects(childPath) && t.mayExceed(trimmed, childLimit) && !t.isTimestamp(childPath, trimmed) && !t.shrinksLater(trimmed, childPath, childLimit) {
 				// Verify with precise marshal
-				encoded, _ := json.Marshal(trimmed)
-				if len(encoded) > t.cfg.FieldLimit {
-					if t.cfg.ReplaceWithMarker {
-						out[k] = Marker
+				if t.sizeOf(trimmed) > childLimit {
+					t.record(childPath, reasonFieldLimit, val)
+					if !t.cfg.ReplaceWithMarker {
+						continue
 					}
-					continue
+					trimmed = marked
 				}
 			}
-			out[k] = trimmed
+			newKey, ok := t.renameKey(childPath)
+			if !ok {
+				out[k] = trimmed
+				continue
+			}
+			if renamed == nil {
+				renamed = make(map[string]renamedField)
+			}
+			// Of two keys renamed onto one, the first in key order wins
+			if prev, dup := renamed[newKey]; dup && prev.from < k {
+				t.record(childPath, reasonRename, val)
+				continue
+			} else if dup {
+				t.record(append(path[:len(path):len(path)], prev.from), reasonRename, prev.original)
+			}
+			renamed[newKey] = renamedField{from: k, v: trimmed, original: val}
+		}
+		for k, f := range renamed {
+			if old, dup := out[k]; dup {
+				t.record(append(path[:len(path):len(path)], k), reasonRename, old)
+			}
+			out[k] = f.v
+		}
+		if t.prunes(path, len(vv), len(out)) {
+			t.record(path, reasonEmpty, vv)
+			return removed
 		}
 		return out
 
 	case []interface{}:
+		if len(t.keepLastRules) > 0 {
+			vv = t.keepLast(vv, path)
+		}
 		out := make([]interface{}, 0, len(vv))
-		for _, item := range vv {
-			trimmed := t.trimFields(item, depth+1)
-			if trimmed == nil {
+		childLimit := t.fieldLimit(depth + 1)
+		for i, item := range vv {
+			childPath := append(path, strconv.Itoa(i))
+			trimmed := t.trimFields(item, depth+1, childPath)
+			if trimmed == removed {
 				continue
 			}
-			if estimateSize(trimmed) > t.cfg.FieldLimit { // Use estimateSize
-				encoded, _ := json.Marshal(trimmed)
-				if len(encoded) > t.cfg.FieldLimit {
+			if !t.protects(childPath) && t.mayExceed(trimmed, childLimit) && !t.isTimestamp(childPath, trimmed) && !t.shrinksLater(trimmed, childPath, childLimit) {
+				if t.sizeOf(trimmed) > childLimit {
+					t.record(childPath, reasonFieldLimit, item)
 					if t.cfg.ReplaceWithMarker {
-						out = append(out, Marker)
+						out = append(out, marked)
 					}
 					continue
 				}
 			}
 			out = append(out, trimmed)
 		}
+		if t.prunes(path, len(vv), len(out)) {
+			t.record(path, reasonEmpty, vv)
+			return removed
+		}
 		return out
 	}
 
 	// Primitives
 	if str, ok := v.(string); ok {
-		if len(str) > t.cfg.FieldLimit {
+		limit := t.fieldLimit(depth)
+		if t.fieldLen(str) > limit && !t.protects(path) && !t.isTimestamp(path, str) {
+			t.record(path, reasonFieldLimit, str)
+			if t.cfg.ParseEmbedded {
+				if parsed, ok := parseEmbedded(str); ok {
+					if s, ok := t.trimEmbedded(parsed, depth, path); ok {
+						return s
+					}
+				}
+			}
 			if t.cfg.TruncateStrings {
-				newLen := t.cfg.FieldLimit - 6
-				if newLen > 0 && len(str) > newLen {
-					return str[:newLen] + "..."
+				newLen := limit - 6
+				if t.cfg.PreserveURLs {
+					if u, ok := t.truncateURL(str, newLen); ok {
+						return u
+					}
+				}
+				if newLen > 0 && t.strLen(str) > newLen {
+					return t.truncate(str, newLen) + "..."
 				}
 			}
 			if t.cfg.ReplaceWithMarker {
-				return Marker
+				return marked
 			}
-			return nil
+			return removed
 		}
 	}
 
+	if v == nil && t.cfg.DropNulls {
+		t.record(path, reasonNull, nil)
+		return removed
+	}
 	return v
 }
 
+// mayExceed reports whether v could be over limit, from a byte estimate
+// that's cheaper than sizeOf. Under SizeFunc the estimate says nothing, so
+// every value is checked.
+func (t *Trimmer) mayExceed(v interface{}, limit int) bool {
+	return t.cfg.SizeFunc != nil || estimateSize(v) > limit
+}
+
+// fieldLen returns the size of the string s checked against FieldLimit:
+// SizeFunc if set, otherwise its length in Unit.
+func (t *Trimmer) fieldLen(s string) int {
+	if t.cfg.SizeFunc != nil {
+		return t.cfg.SizeFunc(s)
+	}
+	return t.strLen(s)
+}
+
+// fieldLimit returns FieldLimit for a node at depth (top-level fields are at
+// depth 2), shrunk by DepthDecay for every level below the top.
+func (t *Trimmer) fieldLimit(depth int) int {
+	if t.cfg.DepthDecay <= 0 || t.cfg.DepthDecay >= 1 || depth <= 2 {
+		return t.cfg.FieldLimit
+	}
+	return max(1, int(float64(t.cfg.FieldLimit)*math.Pow(t.cfg.DepthDecay, float64(depth-2))))
+}
+
+// parseEmbedded decodes s if it holds a stringified JSON object or array.
+func parseEmbedded(s string) (interface{}, bool) {
+	trimmed := strings.TrimSpace(s)
+	if len(trimmed) < 2 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return nil, false
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(trimmed), &v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// trimEmbedded trims a parsed embedded document and re-stringifies it so the
+// string (including escaping) fits the field limit at depth.
+func (t *Trimmer) trimEmbedded(v interface{}, depth int, path []string) (string, bool) {
+	if v = t.trimFields(v, depth, path); v == removed {
+		return "", false
+	}
+	fieldLimit := t.fieldLimit(depth)
+	limit := fieldLimit
+	for limit > 2 {
+		sub := t.withTotalLimit(limit)
+		sub.cfg.Codec = JSONCodec{} // Embedded documents are always JSON text
+		sub.stats = nil             // The whole string is already recorded
+		b, err := json.Marshal(sub.enforceTotal(v))
+		if err != nil {
+			return "", false
+		}
+		// Escaping the quotes inside grows the string, so shrink and retry
+		quoted, _ := json.Marshal(string(b))
+		over := t.measure(quoted) - 2 - fieldLimit
+		if over <= 0 {
+			return string(b), true
+		}
+		limit -= over
+	}
+	return "", false
+}
+
 // enforceTotal iteratively applies strategy until under limit.
 // Optimization: Marshals once at start, then subtracts size of removed items.
 func (t *Trimmer) enforceTotal(v interface{}) interface{} {
 	// Initial precise measurement
-	encoded, err := json.Marshal(v)
+	currentSize, err := t.totalSize(v)
 	if err != nil {
 		return v
 	}
-	currentSize := len(encoded)
 
 	if currentSize <= t.cfg.TotalLimit {
 		return v
 	}
 
+	// Arrays can be cut down in one step when the strategy ranks them up front
+	if arr, ok := v.([]interface{}); ok && len(t.requiredParts) == 0 {
+		if bs, ok := t.sized(t.cfg.Strategy).(BulkStrategy); ok {
+			v = t.evictBulk(arr, bs.RemovalOrder(arr), currentSize-t.cfg.TotalLimit)
+			if currentSize, err = t.totalSize(v); err != nil {
+				return v
+			}
+		}
+	}
+
 	// Track if we ran out of options to prevent infinite recursion
 	hitDeadEnd := false
 
 	for currentSize > t.cfg.TotalLimit {
-		toRemove := t.cfg.Strategy.SelectNextToRemove(v)
+		if t.expired() {
+			hitDeadEnd = true
+			break
+		}
+		toRemove := t.selectNext(v, currentSize-t.cfg.TotalLimit)
 		if toRemove == "" {
 			hitDeadEnd = true
 			break
 		}
+		if len(t.strategyRules) > 0 && t.removeInside(v, toRemove, nil, currentSize-t.cfg.TotalLimit) {
+			if currentSize, err = t.totalSize(v); err != nil {
+				return v
+			}
+			continue
+		}
 
 		switch vv := v.(type) {
 		case map[string]interface{}:
@@ -375,21 +1382,19 @@ func (t *Trimmer) enforceTotal(v interface{}) interface{} {
 				if val, ok := vv[toRemove]; ok {
 					// Calculate reduction
 					removedSize := 0
-					if t.cfg.ReplaceWithMarker && val != Marker {
+					if t.cfg.ReplaceWithMarker && val != marked {
 						// Replacing value with Marker
 						// Cost was: "key":VALUE
 						// New Cost: "key":"[TRIMMED]"
-						valBytes, _ := json.Marshal(val)
-						// delta = len(valBytes) - len(Marker) - 2 (quotes if marker is string)
+						// delta = size(val) - len(Marker) - 2 (quotes if marker is string)
 						// Actually simpler: we just track the delta of the value part.
 						// "key": val -> "key": "Marker"
 						// Delta is len(val) - len(Marker_with_quotes)
-						removedSize = len(valBytes) - (len(Marker) + 2)
-						vv[toRemove] = Marker
+						removedSize = t.sizeOf(val) - (t.strLen(Marker) + 2)
+						vv[toRemove] = marked
 					} else {
 						// Removing entirely
 						// Cost was: "key":VALUE,
-						valBytes, _ := json.Marshal(val)
 						// Size = len(key) + 2(quotes) + 1(colon) + len(val) + 1(comma)
 						// Note: The comma logic is imperfect (last item has no comma), but we are conservative.
 						// We assume worst case (middle item) to ensure we don't under-trim,
@@ -398,10 +1403,11 @@ func (t *Trimmer) enforceTotal(v interface{}) interface{} {
 						// Let's count: len(key) + 2("") + 1(:) + len(val)
 						// We intentionally ignore the comma to be conservative (under-counting reduction),
 						// forcing us to maybe remove one extra item rather than stop too early.
-						removedSize = len(toRemove) + 3 + len(valBytes)
+						removedSize = t.strLen(toRemove) + 3 + t.sizeOf(val)
 						delete(vv, toRemove)
 					}
 					currentSize -= removedSize
+					t.record([]string{toRemove}, reasonTotalLimit, val)
 				}
 			}
 		case []interface{}:
@@ -411,23 +1417,22 @@ func (t *Trimmer) enforceTotal(v interface{}) interface{} {
 					removedSize := 0
 					val := vv[idx]
 
-					if t.cfg.ReplaceWithMarker && val != Marker {
-						valBytes, _ := json.Marshal(val)
+					if t.cfg.ReplaceWithMarker && val != marked {
 						// Replacing: value -> "Marker"
-						removedSize = len(valBytes) - (len(Marker) + 2)
-						vv[idx] = Marker
+						removedSize = t.sizeOf(val) - (t.strLen(Marker) + 2)
+						vv[idx] = marked
 					} else {
 						// Removing entirely: value,
-						valBytes, _ := json.Marshal(val)
 						// We estimate reduction as just the value.
 						// Ignoring comma/bracket overhead is conservative.
-						removedSize = len(valBytes)
+						removedSize = t.sizeOf(val)
 						// Slice remove
 						copy(vv[idx:], vv[idx+1:])
 						vv = vv[:len(vv)-1]
 						v = vv
 					}
 					currentSize -= removedSize
+					t.record([]string{strconv.Itoa(idx)}, reasonTotalLimit, val)
 				}
 			}
 		}
@@ -436,8 +1441,7 @@ func (t *Trimmer) enforceTotal(v interface{}) interface{} {
 	// Final verification check (Recursion)
 	// Only recurse if we didn't hit a dead end (to avoid infinite loop)
 	if !hitDeadEnd {
-		encodedCheck, _ := json.Marshal(v)
-		if len(encodedCheck) > t.cfg.TotalLimit {
+		if size, _ := t.totalSize(v); size > t.cfg.TotalLimit {
 			return t.enforceTotal(v)
 		}
 	}
@@ -445,6 +1449,187 @@ func (t *Trimmer) enforceTotal(v interface{}) interface{} {
 	return v
 }
 
+// evictBulk removes (or marks) as few elements of arr as needed to save over
+// units, taking them in order. Element sizes are measured once and the count
+// is found by binary search over their running total.
+func (t *Trimmer) evictBulk(arr []interface{}, order []int, over int) []interface{} {
+	markerCost := t.strLen(Marker) + 2
+	var candidates []int
+	savings := []int{0} // savings[k]: units saved by evicting the first k candidates
+	for _, i := range order {
+		if i < 0 || i >= len(arr) || (t.cfg.ReplaceWithMarker && arr[i] == marked) {
+			continue
+		}
+		size := t.sizeOf(arr[i])
+		save := size + 1 // The element and its comma
+		if t.cfg.ReplaceWithMarker {
+			save = size - markerCost
+		}
+		if save <= 0 {
+			continue // Marking it wouldn't help
+		}
+		candidates = append(candidates, i)
+		savings = append(savings, savings[len(savings)-1]+save)
+	}
+
+	k := sort.Search(len(savings), func(k int) bool { return savings[k] >= over })
+	k = min(k, len(candidates))
+	if k == 0 {
+		return arr
+	}
+
+	evict := make(map[int]bool, k)
+	for _, i := range candidates[:k] {
+		evict[i] = true
+		t.record([]string{strconv.Itoa(i)}, reasonTotalLimit, arr[i])
+	}
+	out := make([]interface{}, 0, len(arr))
+	for i, item := range arr {
+		switch {
+		case !evict[i]:
+			out = append(out, item)
+		case t.cfg.ReplaceWithMarker:
+			out = append(out, marked)
+		}
+	}
+	return out
+}
+
+// encode serializes v with the configured codec.
+func (t *Trimmer) encode(v interface{}) ([]byte, error) {
+	if t.cfg.ReplaceWithMarker {
+		switch t.cfg.Codec.(type) {
+		case JSONCodec, LenientJSONCodec: // encoding/json renders markerString as a string
+		default:
+			v = renderMarkers(v, false)
+		}
+	}
+	return t.cfg.Codec.Encode(v)
+}
+
+// renderMarkers replaces marked with the plain Marker string, either in place
+// or in a copy of the containers (leaving v untouched).
+func renderMarkers(v interface{}, inPlace bool) interface{} {
+	switch vv := v.(type) {
+	case markerString:
+		return string(vv)
+	case map[string]interface{}:
+		out := vv
+		if !inPlace {
+			out = make(map[string]interface{}, len(vv))
+		}
+		for k, val := range vv {
+			out[k] = renderMarkers(val, inPlace)
+		}
+		return out
+	case []interface{}:
+		out := vv
+		if !inPlace {
+			out = make([]interface{}, len(vv))
+		}
+		for i, item := range vv {
+			out[i] = renderMarkers(item, inPlace)
+		}
+		return out
+	}
+	return v
+}
+
+// measure returns the size of an encoded document in the configured unit.
+func (t *Trimmer) measure(encoded []byte) int {
+	if t.cfg.Unit == UTF16 {
+		return utf16Len(encoded)
+	}
+	return len(encoded)
+}
+
+// strLen returns the length of a raw string value in the configured unit.
+func (t *Trimmer) strLen(s string) int {
+	if t.cfg.Unit == UTF16 {
+		n := 0
+		for _, r := range s {
+			n++
+			if r >= 0x10000 {
+				n++
+			}
+		}
+		return n
+	}
+	return len(s)
+}
+
+// truncate cuts s to at most n units without splitting a character.
+func (t *Trimmer) truncate(s string, n int) string {
+	units := 0
+	for i, r := range s {
+		w := utf8.RuneLen(r)
+		if t.cfg.Unit == UTF16 {
+			w = 1
+			if r >= 0x10000 {
+				w = 2 // Surrogate pair
+			}
+		}
+		if units+w > n {
+			return s[:i]
+		}
+		units += w
+	}
+	return s
+}
+
+// truncateURL shortens an absolute URL to about n units, giving up the
+// fragment and then the query string before touching scheme, host or path, so
+// the endpoint stays identifiable. ok is false if s is not a URL or its host
+// alone doesn't fit.
+func (t *Trimmer) truncateURL(s string, n int) (string, bool) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", false
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if full := u.String(); t.strLen(full) <= n {
+		return full, true
+	}
+
+	hadQuery := u.RawQuery != "" || u.ForceQuery
+	u.RawQuery = ""
+	u.ForceQuery = false
+	base := u.String()
+	if hadQuery && t.strLen(base)+4 <= n {
+		return base + "?...", true
+	}
+	if t.strLen(base) <= n {
+		return base, true
+	}
+
+	origin := u.Scheme + "://" + u.Host
+	if t.strLen(origin)+3 > n {
+		return "", false
+	}
+	return t.truncate(base, n-3) + "...", true
+}
+
+// utf16Len counts UTF-16 code units in UTF-8 encoded b.
+func utf16Len(b []byte) int {
+	n := 0
+	for len(b) > 0 {
+		if b[0] < utf8.RuneSelf {
+			n++
+			b = b[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(b)
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+		b = b[size:]
+	}
+	return n
+}
+
 // estimateSize provides a rough byte count to avoid expensive Marshaling (Performance Feature re-added).
 func estimateSize(v interface{}) int {
 	if v == nil {
@@ -452,7 +1637,9 @@ func estimateSize(v interface{}) int {
 	}
 	switch val := v.(type) {
 	case string:
-		return len(val) + 2 // + quotes
+		return escapedLen(val) + 2 // + quotes
+	case markerString:
+		return escapedLen(string(val)) + 2
 	case bool:
 		if val {
 			return 4
@@ -460,10 +1647,17 @@ func estimateSize(v interface{}) int {
 		return 5
 	case float64:
 		return 8 // Very rough
+	case json.Number:
+		return len(val)
+	case []byte:
+		return base64.StdEncoding.EncodedLen(len(val)) + 2
+	case time.Time:
+		var buf [64]byte
+		return len(val.AppendFormat(buf[:0], time.RFC3339Nano)) + 2
 	case map[string]interface{}:
 		s := 2 // {}
 		for k, sub := range val {
-			s += len(k) + 2 + 1 // "key":
+			s += escapedLen(k) + 2 + 1 // "key":
 			s += estimateSize(sub)
 			s += 1 // comma
 		}
@@ -480,3 +1674,33 @@ func estimateSize(v interface{}) int {
 		return int(reflect.TypeOf(val).Size())
 	}
 }
+
+// escapedLen returns the length of s once JSON-escaped by encoding/json,
+// without the quotes: quotes, backslashes and \n-style controls take two
+// bytes; other controls, <, >, &, U+2028 and U+2029 take six, and each
+// invalid UTF-8 byte becomes a three-byte U+FFFD.
+func escapedLen(s string) int {
+	n := len(s)
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\' || c == '\n' || c == '\r' || c == '\t' || c == '\b' || c == '\f':
+				n++
+			case c < 0x20 || c == '<' || c == '>' || c == '&':
+				n += 5
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			n += 2 // Replaced by the three-byte U+FFFD
+		case r == '\u2028' || r == '\u2029':
+			n += 3 // Three bytes become six
+		}
+		i += size
+	}
+	return n
+}
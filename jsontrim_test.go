@@ -2,7 +2,9 @@ package jsontrim
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -224,3 +226,474 @@ func countNests(m map[string]interface{}) int {
 	}
 	return depth
 }
+
+func TestUTF16Unit(t *testing.T) {
+	// Each emoji is 4 UTF-8 bytes but 2 UTF-16 code units.
+	raw := []byte(`{"msg":"` + strings.Repeat("😀", 30) + `"}`)
+
+	// 30 emoji = 120 bytes, 60 UTF-16 units. A 100 unit limit only fits in UTF16 mode.
+	out, err := New(Config{FieldLimit: 100, TotalLimit: 100, Unit: UTF16}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "😀") {
+		t.Errorf("UTF16 mode dropped a field that fits: %s", out)
+	}
+
+	out, err = New(Config{FieldLimit: 100, TotalLimit: 100}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "😀") {
+		t.Errorf("Bytes mode kept a field over the limit: %s", out)
+	}
+}
+
+func TestTruncateKeepsRunesIntact(t *testing.T) {
+	raw := []byte(`{"msg":"` + strings.Repeat("é", 100) + `"}`)
+	out, err := New(Config{FieldLimit: 21, TruncateStrings: true}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]string
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsRune(m["msg"], '�') {
+		t.Errorf("Truncation split a multi-byte character: %q", m["msg"])
+	}
+}
+
+func TestParseEmbedded(t *testing.T) {
+	inner := `{"user":"bob","token":"s3cret","blob":"` + strings.Repeat("x", 300) + `"}`
+	doc, _ := json.Marshal(map[string]string{"payload": inner})
+
+	trimmer := New(Config{
+		FieldLimit:    200,
+		TotalLimit:    1024,
+		Blacklist:     []string{"payload.token"},
+		ParseEmbedded: true,
+	})
+	out, err := trimmer.Trim(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(m["payload"], "s3cret") {
+		t.Error("Blacklist missed secret inside embedded JSON")
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(m["payload"]), &payload); err != nil {
+		t.Fatalf("Embedded JSON no longer valid: %v (%q)", err, m["payload"])
+	}
+	if payload["user"] != "bob" {
+		t.Errorf("Lost small embedded field: %v", payload)
+	}
+	if len(m["payload"]) > 200 {
+		t.Errorf("Embedded JSON over FieldLimit: %d", len(m["payload"]))
+	}
+}
+
+func TestPreserveURLs(t *testing.T) {
+	endpoint := "https://api.example.com/v1/orders/42"
+	raw := []byte(`{"url":"` + endpoint + `?token=` + strings.Repeat("a", 200) + `#frag"}`)
+	out, err := New(Config{FieldLimit: 60, TruncateStrings: true, PreserveURLs: true}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]string
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["url"] != endpoint+"?..." {
+		t.Errorf("Expected query dropped with path kept, got %q", m["url"])
+	}
+
+	long := "https://api.example.com/" + strings.Repeat("p", 100)
+	trimmer := New(Config{FieldLimit: 40, TruncateStrings: true, PreserveURLs: true})
+	if got, ok := trimmer.truncateURL(long, 34); !ok || !strings.HasPrefix(got, "https://api.example.com/ppp") || len(got) > 34 {
+		t.Errorf("Long path not cut after host: %q", got)
+	}
+	if _, ok := trimmer.truncateURL("not a url "+strings.Repeat("x", 50), 34); ok {
+		t.Error("Plain text treated as URL")
+	}
+}
+
+func TestRename(t *testing.T) {
+	raw := []byte(`{"msg":"hello","lvl":"info","events":[{"ts":1},{"ts":2}],"message":"old"}`)
+	trimmer := New(Config{Rename: map[string]string{
+		"msg":         "message",
+		"lvl":         "level",
+		"events.*.ts": "timestamp",
+	}})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["message"] != "hello" || m["level"] != "info" {
+		t.Errorf("Top-level rename failed: %s", out)
+	}
+	if _, ok := m["msg"]; ok {
+		t.Errorf("Original key kept: %s", out)
+	}
+	if !strings.Contains(string(out), `{"timestamp":1}`) {
+		t.Errorf("Wildcard rename failed: %s", out)
+	}
+	out, res, err := New(Config{Rename: map[string]string{"msg": "message"}}).TrimWithResult([]byte(`{"msg":"a","message":"b"}`))
+	if err != nil || string(out) != `{"message":"a"}` || !reflect.DeepEqual(res.PathsAffected, []string{"message"}) {
+		t.Errorf("Expected the overwritten key recorded, got %s (%v, %v)", out, res.PathsAffected, err)
+	}
+	merged := New(Config{Rename: map[string]string{"a": "x", "b": "x"}})
+	for i := 0; i < 20; i++ {
+		out, res, err := merged.TrimWithResult([]byte(`{"b":2,"a":1}`))
+		if err != nil || string(out) != `{"x":1}` || !reflect.DeepEqual(res.PathsAffected, []string{"b"}) {
+			t.Fatalf("Expected the first key in order to win, got %s (%v, %v)", out, res.PathsAffected, err)
+		}
+	}
+}
+
+func TestAtomic(t *testing.T) {
+	raw := []byte(`{"ts":"2024-01-02T03:04:05.123456789Z","note":"2024-01-02T03:04:05.123456789Z","sel":{"a":"` + strings.Repeat("x", 30) + `","b":"` + strings.Repeat("y", 30) + `"}}`)
+	trimmer := New(Config{FieldLimit: 25, TotalLimit: 500, TruncateStrings: true, Atomic: []string{"ts", "sel"}})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["ts"]; ok {
+		t.Errorf("Atomic string should be dropped whole, not truncated: %s", out)
+	}
+	if note, _ := m["note"].(string); !strings.HasSuffix(note, "...") {
+		t.Errorf("Non-atomic string should be truncated: %s", out)
+	}
+	if _, ok := m["sel"]; ok {
+		t.Errorf("Atomic object should be dropped whole, not trimmed inside: %s", out)
+	}
+}
+
+func TestDepthDecay(t *testing.T) {
+	s := strings.Repeat("x", 60)
+	raw := []byte(`{"top":"` + s + `","a":{"mid":"` + s + `","b":{"deep":"` + s + `"}}}`)
+	trimmer := New(Config{FieldLimit: 100, TotalLimit: 1000, TruncateStrings: true, DepthDecay: 0.5})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m struct {
+		Top string
+		A   struct {
+			Mid string
+			B   struct{ Deep string }
+		}
+	}
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	// Limits: top 100, mid 50, deep 25 (minus the "..." suffix allowance)
+	if m.Top != s {
+		t.Errorf("Top-level field should keep the full limit: %q", m.Top)
+	}
+	if len(m.A.Mid) != 47 || len(m.A.B.Deep) != 22 {
+		t.Errorf("Expected decayed truncation (47, 22), got (%d, %d)", len(m.A.Mid), len(m.A.B.Deep))
+	}
+}
+
+func TestBulkArrayEviction(t *testing.T) {
+	items := make([]interface{}, 200)
+	for i := range items {
+		items[i] = fmt.Sprintf("item-%03d", i)
+	}
+	raw, _ := json.Marshal(items)
+
+	for _, strategy := range []TruncStrategy{FIFO{}, RemoveLargest{}} {
+		out, err := New(Config{TotalLimit: 200, Strategy: strategy}).Trim(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatal(err)
+		}
+		// Each element costs 11 bytes with its comma: [ + 18*11 - 1 + ] = 199
+		if len(out) > 200 || len(got) != 18 {
+			t.Errorf("%T: expected 18 items in %d bytes, got %d items in %d bytes", strategy, 18*11+1, len(got), len(out))
+		}
+		if got[len(got)-1] != "item-199" {
+			t.Errorf("%T: expected the tail to survive, got %v", strategy, got)
+		}
+	}
+}
+
+func TestPruneEmpty(t *testing.T) {
+	raw := []byte(`{"user":{"password":"x","token":"y"},"tags":["` + strings.Repeat("t", 50) + `"],"meta":{},"id":1}`)
+	cfg := Config{FieldLimit: 20, TotalLimit: 500, Blacklist: []string{"user.password", "user.token"}}
+
+	out, _ := New(cfg).Trim(raw)
+	if !strings.Contains(string(out), `"user":{}`) {
+		t.Errorf("Without PruneEmpty the emptied object should stay: %s", out)
+	}
+
+	cfg.PruneEmpty = true
+	out, err := New(cfg).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":1,"meta":{}}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
+
+func TestKeepEmpty(t *testing.T) {
+	raw := []byte(`{"emails":["a@x.io"],"user":{"token":"y"},"list":[],"id":1}`)
+	cfg := Config{Blacklist: []string{"emails.*", "user.token"}, PruneEmpty: true}
+
+	out, _ := New(cfg).Trim(raw)
+	if want := `{"id":1}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+
+	cfg.KeepEmpty = true
+	out, err := New(cfg).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"emails":[],"id":1,"list":[],"user":{}}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
+
+func TestPreserveNulls(t *testing.T) {
+	raw := []byte(`{"a":null,"b":[1,null],"c":{"d":null},"secret":"x"}`)
+	cfg := Config{Blacklist: []string{"secret"}}
+
+	out, err := New(cfg).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":null,"b":[1,null],"c":{"d":null}}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+
+	cfg.DropNulls = true
+	out, _ = New(cfg).Trim(raw)
+	if want := `{"b":[1],"c":{}}`; string(out) != want {
+		t.Errorf("DropNulls: got %s, want %s", out, want)
+	}
+}
+
+func TestMarkerSentinel(t *testing.T) {
+	raw, _ := MsgPackCodec{}.Encode(map[string]interface{}{
+		"note":   Marker, // User data that happens to equal the marker
+		"secret": "x",
+		"blob":   strings.Repeat("b", 100),
+	})
+	var seen interface{}
+	trimmer := New(Config{
+		FieldLimit:        50,
+		ReplaceWithMarker: true,
+		Codec:             MsgPackCodec{},
+		Blacklist:         []string{"secret"},
+		Hooks: Hooks{PostTrim: func(v interface{}, err error) interface{} {
+			seen = v.(map[string]interface{})["blob"]
+			return v
+		}},
+	})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != Marker {
+		t.Errorf("PostTrim should see the plain Marker string, got %#v", seen)
+	}
+	v, _ := MsgPackCodec{}.Decode(out)
+	want := map[string]interface{}{"note": Marker, "secret": Marker, "blob": Marker}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Got %#v, want %#v", v, want)
+	}
+}
+
+func TestHookAbort(t *testing.T) {
+	policy := errors.New("missing tenant")
+	trimmer := New(Config{Hooks: Hooks{
+		BeforeTrim: func(v interface{}) (interface{}, error) {
+			if _, ok := v.(map[string]interface{})["tenant"]; !ok {
+				return nil, policy
+			}
+			return v, nil
+		},
+		AfterTrim: func(v interface{}, res TrimResult) (interface{}, error) {
+			v.(map[string]interface{})["checked"] = true
+			return v, nil
+		},
+	}})
+
+	if _, err := trimmer.Trim([]byte(`{"a":1}`)); !errors.Is(err, ErrHookAborted) || !errors.Is(err, policy) {
+		t.Errorf("Expected ErrHookAborted wrapping the hook error, got %v", err)
+	}
+	out, err := trimmer.Trim([]byte(`{"tenant":"t1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"checked":true,"tenant":"t1"}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
+
+func TestFieldHooks(t *testing.T) {
+	raw := []byte(`{"users":[{"name":"ann","card":"4111111111111111"},{"name":"bob","debug":true}]}`)
+	var visited []string
+	trimmer := New(Config{FieldHooks: map[string]FieldHook{
+		"users.*.card": func(path string, v interface{}) (interface{}, bool) {
+			visited = append(visited, path)
+			s := v.(string)
+			return "****" + s[len(s)-4:], true
+		},
+		"users.*.debug": func(path string, v interface{}) (interface{}, bool) {
+			return nil, false
+		},
+	}})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"users":[{"card":"****1111","name":"ann"},{"name":"bob"}]}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+	if !reflect.DeepEqual(visited, []string{"users.0.card"}) {
+		t.Errorf("Unexpected hook paths: %v", visited)
+	}
+}
+
+func TestOnLimitExceeded(t *testing.T) {
+	var calls, overage int
+	trimmer := New(Config{TotalLimit: 20, Hooks: Hooks{OnLimitExceeded: func(raw []byte, over int) {
+		calls++
+		overage = over
+	}}})
+
+	if _, err := trimmer.Trim([]byte(`{"a": 1}`)); err != nil || calls != 0 {
+		t.Fatalf("Hook should not fire under the limit (calls=%d, err=%v)", calls, err)
+	}
+	raw := []byte(`{"a":"` + strings.Repeat("x", 30) + `"}`)
+	if _, err := trimmer.Trim(raw); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || overage != len(raw)-20 {
+		t.Errorf("Expected one call with overage %d, got %d calls, overage %d", len(raw)-20, calls, overage)
+	}
+}
+
+func TestOnHeavyTrim(t *testing.T) {
+	var ratios []float64
+	hooks := Hooks{OnHeavyTrim: func(raw []byte, res TrimResult, ratio float64) { ratios = append(ratios, ratio) }}
+	trimmer := New(Config{TotalLimit: 1024, FieldLimit: 50, Hooks: hooks})
+
+	trimmer.Trim([]byte(`{"a":"` + strings.Repeat("x", 40) + `","b":"` + strings.Repeat("y", 60) + `"}`))
+	if len(ratios) != 0 {
+		t.Fatalf("Hook should not fire for a light trim, got %v", ratios)
+	}
+	trimmer.Trim([]byte(`{"id":1,"blob":"` + strings.Repeat("z", 1000) + `"}`))
+	if len(ratios) != 1 || ratios[0] < 0.98 {
+		t.Errorf("Expected one alert near 99%%, got %v", ratios)
+	}
+
+	trimmer = trimmer.With(func(c *Config) { c.HeavyTrimRatio = 0.3 })
+	trimmer.Trim([]byte(`{"a":"` + strings.Repeat("x", 40) + `","b":"` + strings.Repeat("y", 60) + `"}`))
+	if len(ratios) != 2 {
+		t.Errorf("Expected a lower HeavyTrimRatio to fire, got %v", ratios)
+	}
+}
+
+func TestWhitelist(t *testing.T) {
+	raw := []byte(`{"id":1,"user":{"name":"ann","ssn":"123"},"items":[{"sku":"a","price":2},{"sku":"b"}],"debug":"x"}`)
+	out, err := New(Config{Whitelist: []string{"id", "user.name", "items.*.sku"}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":1,"items":[{"sku":"a"},{"sku":"b"}],"user":{"name":"ann"}}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
+
+func TestPackageTrim(t *testing.T) {
+	raw := []byte(`{"body":"` + strings.Repeat("b", 900) + `","id":1,"tags":["a","b"]}`)
+
+	// Fits: nothing is cut, even though body exceeds the default FieldLimit
+	out, err := Trim(raw, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(raw) {
+		t.Errorf("Expected input unchanged, got %s", out)
+	}
+
+	out, err = Trim(raw, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 200 || !strings.Contains(string(out), `"id":1`) {
+		t.Errorf("Expected output under 200 bytes keeping id, got %d: %s", len(out), out)
+	}
+}
+
+func TestMustTrimAndTrimOrOriginal(t *testing.T) {
+	trimmer := New(Config{TotalLimit: 4096})
+	if out := trimmer.MustTrim([]byte(`{"a":1}`)); string(out) != `{"a":1}` {
+		t.Errorf("MustTrim: got %s", out)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("MustTrim should panic on invalid input")
+			}
+		}()
+		trimmer.MustTrim([]byte(`{`))
+	}()
+
+	bad := []byte(`not json`)
+	if out := trimmer.TrimOrOriginal(bad); string(out) != string(bad) {
+		t.Errorf("TrimOrOriginal should return the input on error, got %s", out)
+	}
+	if out := trimmer.TrimOrOriginal([]byte(`{"a": 1}`)); string(out) != `{"a":1}` {
+		t.Errorf("TrimOrOriginal: got %s", out)
+	}
+}
+
+func TestTrimString(t *testing.T) {
+	trimmer := New(Config{TotalLimit: 4096, Blacklist: []string{"token"}})
+	out, err := trimmer.TrimString(`{"id": 1, "token": "x"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != `{"id":1}` {
+		t.Errorf("Got %s", out)
+	}
+	if _, err := trimmer.TrimString(`{`); err == nil {
+		t.Error("Expected an error for invalid input")
+	}
+}
+
+func TestEstimateSizeEscapes(t *testing.T) {
+	for _, s := range []string{"plain", `say "hi"\n`, "line\nbreak\ttab\x01", "<a&b>", "café  ", "bad\xff"} {
+		encoded, _ := json.Marshal(s)
+		if got := estimateSize(s); got != len(encoded) {
+			t.Errorf("estimateSize(%q) = %d, want %d", s, got, len(encoded))
+		}
+	}
+	v := map[string]interface{}{`k"ey`: strings.Repeat("\n", 10)}
+	encoded, _ := json.Marshal(v)
+	if got := estimateSize(v); got < len(encoded) {
+		t.Errorf("Expected escapes counted in objects, got %d < %d", got, len(encoded))
+	}
+}
@@ -0,0 +1,101 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// KeyPolicy normalizes object keys for sinks that reject or misread some of
+// them. Keys are normalized right after decoding, so every path rule
+// (Blacklist, Required, ...) matches the normalized keys, which also makes
+// keys that contained dots addressable.
+type KeyPolicy struct {
+	Dots              bool   // Replace "." (MongoDB rejects it, Elasticsearch reads it as an object path)
+	Dollar            bool   // Strip leading "$" (MongoDB operators)
+	LeadingUnderscore bool   // Strip leading "_" (Elasticsearch metadata fields)
+	ControlChars      bool   // Replace control characters
+	Replacement       string // Replaces dots and control characters (default: "_")
+	MaxDepth          int    // Objects nested deeper than this many keys are stored as JSON strings (default: 0, unlimited)
+}
+
+// Key policies for common sinks.
+var (
+	MongoKeys         = KeyPolicy{Dots: true, Dollar: true, ControlChars: true}
+	ElasticsearchKeys = KeyPolicy{Dots: true, LeadingUnderscore: true, ControlChars: true, MaxDepth: 20}
+)
+
+// enabled reports whether p changes anything.
+func (p KeyPolicy) enabled() bool {
+	return p.Dots || p.Dollar || p.LeadingUnderscore || p.ControlChars || p.MaxDepth > 0
+}
+
+// key returns the normalized form of k.
+func (p KeyPolicy) key(k string) string {
+	if p.Dollar || p.LeadingUnderscore {
+		k = strings.TrimLeftFunc(k, func(r rune) bool {
+			return (p.Dollar && r == '$') || (p.LeadingUnderscore && r == '_')
+		})
+	}
+	if p.Dots || p.ControlChars {
+		repl := p.Replacement
+		if repl == "" {
+			repl = "_"
+		}
+		var b strings.Builder
+		for _, r := range k {
+			if (p.Dots && r == '.') || (p.ControlChars && unicode.IsControl(r)) {
+				b.WriteString(repl)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		k = b.String()
+	}
+	if k == "" {
+		return "key"
+	}
+	return k
+}
+
+// normalizeKeys applies the KeyPolicy to v. depth counts the objects
+// enclosing v. Keys that collide after normalization get a numeric suffix
+// ("a_b", "a_b_2") in sorted order of the original keys, so nothing is lost.
+func (t *Trimmer) normalizeKeys(v interface{}, depth int, path []string) interface{} {
+	p := t.cfg.Keys
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if p.MaxDepth > 0 && depth >= p.MaxDepth && len(vv) > 0 {
+			encoded, err := json.Marshal(vv)
+			if err != nil {
+				return v
+			}
+			t.record(path, reasonKeyDepth, vv)
+			return string(encoded)
+		}
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make(map[string]interface{}, len(vv))
+		for _, k := range keys {
+			nk := p.key(k)
+			for i := 2; ; i++ {
+				if _, taken := out[nk]; !taken {
+					break
+				}
+				nk = p.key(k) + "_" + strconv.Itoa(i)
+			}
+			out[nk] = t.normalizeKeys(vv[k], depth+1, append(path, nk))
+		}
+		return out
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = t.normalizeKeys(item, depth, append(path, strconv.Itoa(i)))
+		}
+	}
+	return v
+}
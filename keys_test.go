@@ -0,0 +1,23 @@
+package jsontrim
+
+import "testing"
+
+func TestKeyPolicy(t *testing.T) {
+	raw := []byte(`{"$where":"x","a.b":1,"a_b":2,"ok\u0007":true,"user":{"e.mail":"e","pw":"p"}}`)
+	out, err := New(Config{TotalLimit: 4096, Keys: MongoKeys, Blacklist: []string{"user.pw"}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a_b":1,"a_b_2":2,"ok_":true,"user":{"e_mail":"e"},"where":"x"}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+
+	raw = []byte(`{"_id":1,"a":{"b":{"c":{"d":1}}},"l":[{"m":{"n":1}}]}`)
+	out, err = New(Config{TotalLimit: 4096, Keys: KeyPolicy{LeadingUnderscore: true, MaxDepth: 2}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":{"b":"{\"c\":{\"d\":1}}"},"id":1,"l":[{"m":"{\"n\":1}"}]}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
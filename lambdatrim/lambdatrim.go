@@ -0,0 +1,96 @@
+// Package lambdatrim keeps AWS Lambda responses and log lines inside Lambda's
+// payload limits. A wrapped handler whose JSON response would exceed the 6MB
+// synchronous invocation limit returns a trimmed response, with the Trimmer's
+// strategy choosing what survives, instead of failing the invocation.
+//
+// The package doesn't import aws-lambda-go: wrapped handlers have a signature
+// lambda.Start accepts, and json.RawMessage responses are passed through as is.
+package lambdatrim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/arun0009/jsontrim"
+)
+
+const (
+	// ResponseLimit is the maximum synchronous invocation response payload.
+	ResponseLimit = 6 << 20
+	// LogEventLimit is the largest CloudWatch Logs event message: 256KB less
+	// the 26 bytes CloudWatch counts per event.
+	LogEventLimit = 256<<10 - 26
+)
+
+// ErrResponseTooLarge is returned when a response exceeds the limit and
+// can't be trimmed below it.
+var ErrResponseTooLarge = errors.New("response exceeds lambda payload limit")
+
+// Options configures Wrap.
+type Options struct {
+	Trimmer *jsontrim.Trimmer // Trims oversized responses; its TotalLimit is capped at Limit, or raised to it if left at its default, and a default FieldLimit is raised to that TotalLimit (default: TotalLimit and FieldLimit of Limit)
+	Limit   int               // Maximum response size in bytes (default: ResponseLimit)
+}
+
+// Wrap returns a handler that encodes h's response and trims it if it's
+// larger than the limit. Responses within the limit are returned unchanged.
+func Wrap[Req, Resp any](h func(context.Context, Req) (Resp, error), opts Options) func(context.Context, Req) (json.RawMessage, error) {
+	t := responseTrimmer(opts)
+	limit := t.Config().TotalLimit
+	return func(ctx context.Context, req Req) (json.RawMessage, error) {
+		resp, err := h(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) <= limit {
+			return raw, nil
+		}
+		out, err := t.Trim(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrResponseTooLarge, err)
+		}
+		return out, nil
+	}
+}
+
+// responseTrimmer returns the Trimmer for opts, capped at the limit. Limits
+// the caller left at their defaults, on a Trimmer passed for its Blacklist,
+// say, are raised like those of the Trimmer used when there's none: a
+// TotalLimit to the limit, and a FieldLimit to the TotalLimit.
+func responseTrimmer(opts Options) *jsontrim.Trimmer {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = ResponseLimit
+	}
+	if opts.Trimmer == nil {
+		return jsontrim.New(jsontrim.Config{TotalLimit: limit, FieldLimit: limit})
+	}
+	cfg := opts.Trimmer.ConfigV2()
+	var raise []jsontrim.Option
+	if cfg.TotalLimit == nil || *cfg.TotalLimit <= 0 || *cfg.TotalLimit > limit {
+		raise = append(raise, jsontrim.WithTotalLimit(limit))
+	} else {
+		limit = *cfg.TotalLimit
+	}
+	if cfg.FieldLimit == nil {
+		raise = append(raise, jsontrim.WithFieldLimit(limit))
+	}
+	if len(raise) == 0 {
+		return opts.Trimmer
+	}
+	return opts.Trimmer.With(raise...)
+}
+
+// ForLogs returns t capped at LogEventLimit, so each trimmed payload fits in
+// one CloudWatch Logs event instead of being split or truncated mid-JSON.
+// Leave room for whatever the log line adds around the payload by giving t a
+// lower TotalLimit.
+func ForLogs(t *jsontrim.Trimmer) *jsontrim.Trimmer {
+	return responseTrimmer(Options{Trimmer: t, Limit: LogEventLimit})
+}
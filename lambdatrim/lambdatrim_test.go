@@ -0,0 +1,94 @@
+package lambdatrim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+type response struct {
+	ID    int      `json:"id"`
+	Items []string `json:"items"`
+}
+
+func TestWrap(t *testing.T) {
+	items := make([]string, 100)
+	for i := range items {
+		items[i] = strings.Repeat("x", 50)
+	}
+	handler := func(ctx context.Context, n int) (response, error) {
+		if n < 0 {
+			return response{}, errors.New("boom")
+		}
+		return response{ID: 1, Items: items[:n]}, nil
+	}
+	wrapped := Wrap(handler, Options{Limit: 1000})
+
+	small, err := wrapped(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := json.Marshal(response{ID: 1, Items: items[:2]}); string(small) != string(want) {
+		t.Errorf("Small responses should be unchanged, got %s", small)
+	}
+
+	big, err := wrapped(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got response
+	if err := json.Unmarshal(big, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(big) > 1000 || got.ID != 1 {
+		t.Errorf("Expected a trimmed response under 1000 bytes keeping id, got %d bytes: %s", len(big), big)
+	}
+
+	if _, err := wrapped(context.Background(), -1); err == nil || err.Error() != "boom" {
+		t.Errorf("Handler errors should pass through, got %v", err)
+	}
+
+	strict := Wrap(handler, Options{Limit: 10, Trimmer: jsontrim.New(jsontrim.Config{Required: []string{"items"}})})
+	if _, err := strict(context.Background(), 100); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestForLogs(t *testing.T) {
+	if got := ForLogs(jsontrim.New(jsontrim.Config{TotalLimit: 1 << 30})).Config().TotalLimit; got != LogEventLimit {
+		t.Errorf("TotalLimit %d, want %d", got, LogEventLimit)
+	}
+	small := jsontrim.New(jsontrim.Config{TotalLimit: 4096, FieldLimit: 500})
+	if ForLogs(small) != small {
+		t.Error("Trimmers already under the limit should be returned as is")
+	}
+}
+
+func TestDefaultLimitsRaised(t *testing.T) {
+	blacklist := jsontrim.New(jsontrim.Config{Blacklist: []string{"token"}})
+	if got := responseTrimmer(Options{Trimmer: blacklist}).Config().TotalLimit; got != ResponseLimit {
+		t.Errorf("A default TotalLimit should be raised to ResponseLimit, got %d", got)
+	}
+	explicit := jsontrim.New(jsontrim.Config{TotalLimit: 1024, FieldLimit: 500})
+	if got := responseTrimmer(Options{Trimmer: explicit}); got != explicit {
+		t.Errorf("An explicit TotalLimit under the cap should be kept, got %+v", got.Config())
+	}
+
+	if got := responseTrimmer(Options{Trimmer: blacklist}).Config().FieldLimit; got != ResponseLimit {
+		t.Errorf("A default FieldLimit should be raised to ResponseLimit, got %d", got)
+	}
+	capped := jsontrim.New(jsontrim.Config{TotalLimit: 4096})
+	if got := responseTrimmer(Options{Trimmer: capped}).Config(); got.FieldLimit != 4096 || got.TotalLimit != 4096 {
+		t.Errorf("A default FieldLimit should be raised to the TotalLimit, got %d and %d", got.FieldLimit, got.TotalLimit)
+	}
+
+	big := `{"token":"t","body":"` + strings.Repeat("b", 2000) + `","pad":"` + strings.Repeat("p", 2000) + `"}`
+	wrapped := Wrap(func(context.Context, int) (json.RawMessage, error) { return json.RawMessage(big), nil }, Options{Trimmer: blacklist, Limit: 3000})
+	if out, err := wrapped(context.Background(), 0); err != nil || (!strings.Contains(string(out), `"body"`) && !strings.Contains(string(out), `"pad"`)) {
+		t.Errorf("Expected a field over the default FieldLimit kept, got %.80s (%v)", out, err)
+	}
+}
@@ -0,0 +1,362 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LenientJSONCodec is a JSON codec whose decoder also accepts // and /* */
+// comments, single-quoted strings, unquoted (identifier) object keys, trailing
+// commas and duplicate keys, so hand-written config blobs and HAR-like
+// captures can be trimmed without pre-cleaning. Output is always strict JSON.
+type LenientJSONCodec struct {
+	DuplicateKeys DuplicateKeyPolicy // Which value a repeated object key keeps (default: LastWins)
+	OnFix         func(fix Fix)      // Called for each trailing comma or duplicate key tolerated (optional)
+}
+
+// DuplicateKeyPolicy decides how repeated keys within one object are resolved.
+type DuplicateKeyPolicy int
+
+const (
+	// LastWins keeps the last value, like encoding/json.
+	LastWins DuplicateKeyPolicy = iota
+	// FirstWins keeps the first value and ignores later ones.
+	FirstWins
+	// ErrorOnDuplicate fails decoding with ErrDuplicateKey.
+	ErrorOnDuplicate
+	// MergeDuplicates keeps every value: objects are merged key by key, and
+	// other values are collected into an array, in input order.
+	MergeDuplicates
+)
+
+// add stores v under key in m, resolving a repeated key by the policy, and
+// reports whether key repeated. ErrorOnDuplicate is left to the caller.
+// merged is the document's mergedArrays; it's allocated when first needed.
+func (p DuplicateKeyPolicy) add(m map[string]interface{}, merged *mergedArrays, key string, v interface{}) bool {
+	prev, dup := m[key]
+	switch {
+	case !dup || p == LastWins:
+		m[key] = v
+	case p == MergeDuplicates:
+		if *merged == nil {
+			*merged = make(mergedArrays)
+		}
+		m[key] = merged.merge(prev, v)
+	}
+	return dup
+}
+
+// mergedArrays holds the first element of each array MergeDuplicates
+// collected in one document, so a further value joins the array instead of
+// nesting it, however deep the objects it was merged into.
+type mergedArrays map[*interface{}]bool
+
+// merge returns what a key holds under MergeDuplicates once it repeats with
+// v: two objects merge key by key, anything else is collected into an array.
+func (a mergedArrays) merge(prev, v interface{}) interface{} {
+	if arr, ok := prev.([]interface{}); ok && len(arr) > 0 && a[&arr[0]] {
+		arr = append(arr, v)
+		a[&arr[0]] = true
+		return arr
+	}
+	pm, ok1 := prev.(map[string]interface{})
+	vm, ok2 := v.(map[string]interface{})
+	if !ok1 || !ok2 {
+		arr := []interface{}{prev, v}
+		a[&arr[0]] = true
+		return arr
+	}
+	for k, val := range vm {
+		if old, dup := pm[k]; dup {
+			pm[k] = a.merge(old, val)
+		} else {
+			pm[k] = val
+		}
+	}
+	return pm
+}
+
+// Fix describes one malformation LenientJSONCodec tolerated.
+type Fix struct {
+	Offset      int    // Byte offset in the input
+	Description string // e.g. `trailing comma` or `duplicate key "id"`
+}
+
+var (
+	// ErrLenientJSON indicates input that even LenientJSONCodec can't parse.
+	ErrLenientJSON = errors.New("invalid lenient json")
+	// ErrDuplicateKey is returned for a repeated key under ErrorOnDuplicate.
+	ErrDuplicateKey = errors.New("duplicate key")
+)
+
+// Decode implements Codec.
+func (c LenientJSONCodec) Decode(data []byte) (interface{}, error) {
+	p := &lenientParser{buf: data, codec: c}
+	v, err := p.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.buf) {
+		return nil, p.errorf("unexpected %q after document", p.buf[p.pos])
+	}
+	return v, nil
+}
+
+// Encode implements Codec.
+func (LenientJSONCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+type lenientParser struct {
+	buf    []byte
+	pos    int
+	codec  LenientJSONCodec
+	merged mergedArrays
+}
+
+func (p *lenientParser) fixed(offset int, description string) {
+	if p.codec.OnFix != nil {
+		p.codec.OnFix(Fix{Offset: offset, Description: description})
+	}
+}
+
+func (p *lenientParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: offset %d: %s", ErrLenientJSON, p.pos, fmt.Sprintf(format, args...))
+}
+
+// skip advances past whitespace and comments.
+func (p *lenientParser) skip() error {
+	for p.pos < len(p.buf) {
+		switch c := p.buf[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.pos++
+		case c == '/' && p.pos+1 < len(p.buf) && p.buf[p.pos+1] == '/':
+			for p.pos < len(p.buf) && p.buf[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '/' && p.pos+1 < len(p.buf) && p.buf[p.pos+1] == '*':
+			end := strings.Index(string(p.buf[p.pos+2:]), "*/")
+			if end < 0 {
+				return p.errorf("unterminated comment")
+			}
+			p.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// peek skips insignificant input and returns the next byte, or 0 at EOF.
+func (p *lenientParser) peek() (byte, error) {
+	if err := p.skip(); err != nil {
+		return 0, err
+	}
+	if p.pos >= len(p.buf) {
+		return 0, nil
+	}
+	return p.buf[p.pos], nil
+}
+
+func (p *lenientParser) value(depth int) (interface{}, error) {
+	if depth > maxNesting {
+		return nil, p.errorf("nesting too deep")
+	}
+	c, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c == '{':
+		return p.object(depth)
+	case c == '[':
+		return p.array(depth)
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.number()
+	case isIdentStart(c):
+		switch word := p.ident(); word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		default:
+			return nil, p.errorf("unexpected identifier %q", word)
+		}
+	case c == 0:
+		return nil, p.errorf("unexpected end of input")
+	}
+	return nil, p.errorf("unexpected %q", c)
+}
+
+func (p *lenientParser) object(depth int) (interface{}, error) {
+	p.pos++ // '{'
+	out := make(map[string]interface{})
+	comma := -1 // Offset of the preceding comma, if any
+	for {
+		c, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if c == '}' {
+			if comma >= 0 {
+				p.fixed(comma, "trailing comma")
+			}
+			p.pos++
+			return out, nil
+		}
+
+		keyOffset := p.pos
+		var key string
+		switch {
+		case c == '"' || c == '\'':
+			if key, err = p.str(); err != nil {
+				return nil, err
+			}
+		case isIdentStart(c):
+			key = p.ident()
+		default:
+			return nil, p.errorf("expected object key, got %q", c)
+		}
+
+		if c, err = p.peek(); err != nil {
+			return nil, err
+		}
+		if c != ':' {
+			return nil, p.errorf("expected ':' after key %q", key)
+		}
+		p.pos++
+		v, err := p.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := out[key]; dup && p.codec.DuplicateKeys == ErrorOnDuplicate {
+			p.pos = keyOffset
+			return nil, fmt.Errorf("%w %q at offset %d", ErrDuplicateKey, key, keyOffset)
+		}
+		if p.codec.DuplicateKeys.add(out, &p.merged, key, v) {
+			p.fixed(keyOffset, fmt.Sprintf("duplicate key %q", key))
+		}
+
+		if c, err = p.peek(); err != nil {
+			return nil, err
+		}
+		comma = p.pos
+		p.pos++
+		switch c {
+		case ',':
+		case '}':
+			return out, nil
+		default:
+			p.pos--
+			return nil, p.errorf("expected ',' or '}' in object")
+		}
+	}
+}
+
+func (p *lenientParser) array(depth int) (interface{}, error) {
+	p.pos++ // '['
+	out := []interface{}{}
+	comma := -1
+	for {
+		c, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if c == ']' {
+			if comma >= 0 {
+				p.fixed(comma, "trailing comma")
+			}
+			p.pos++
+			return out, nil
+		}
+		v, err := p.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+
+		if c, err = p.peek(); err != nil {
+			return nil, err
+		}
+		comma = p.pos
+		p.pos++
+		switch c {
+		case ',':
+		case ']':
+			return out, nil
+		default:
+			p.pos--
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// str parses a double- or single-quoted string. Single-quoted strings are
+// rewritten as double-quoted ones so encoding/json handles the escapes.
+func (p *lenientParser) str() (string, error) {
+	quote := p.buf[p.pos]
+	start := p.pos
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for p.pos++; p.pos < len(p.buf); p.pos++ {
+		c := p.buf[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			sb.WriteByte('"')
+			var s string
+			if err := json.Unmarshal([]byte(sb.String()), &s); err != nil {
+				p.pos = start
+				return "", p.errorf("bad string: %v", err)
+			}
+			return s, nil
+		case c == '\\' && p.pos+1 < len(p.buf):
+			p.pos++
+			if p.buf[p.pos] != '\'' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(p.buf[p.pos])
+		case c == '"':
+			sb.WriteString(`\"`) // Only reachable inside a single-quoted string
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+func (p *lenientParser) number() (interface{}, error) {
+	start := p.pos
+	for p.pos < len(p.buf) && strings.IndexByte("+-0123456789.eE", p.buf[p.pos]) >= 0 {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(string(p.buf[start:p.pos]), 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("bad number")
+	}
+	return f, nil
+}
+
+func (p *lenientParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.buf) && (isIdentStart(p.buf[p.pos]) || (p.buf[p.pos] >= '0' && p.buf[p.pos] <= '9')) {
+		p.pos++
+	}
+	return string(p.buf[start:p.pos])
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,74 @@
+package jsontrim
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLenientDecode(t *testing.T) {
+	in := `// captured from devtools
+	{
+		name: 'it\'s', /* inline */ "quoted": "a\"b",
+		$ref: 'say "hi" \'there\'',
+		list: [1, -2.5e1, true, null], // trailing note
+		nested: {_k: {}}
+	}`
+	v, err := LenientJSONCodec{}.Decode([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":   "it's",
+		"quoted": `a"b`,
+		"$ref":   `say "hi" 'there'`,
+		"list":   []interface{}{1.0, -25.0, true, nil},
+		"nested": map[string]interface{}{"_k": map[string]interface{}{}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Got %#v, want %#v", v, want)
+	}
+
+	for _, bad := range []string{`{a: 1`, `{a 1}`, `/* open`, `{a: undefined}`, `[,]`, `{"a": 1} x`} {
+		if _, err := (LenientJSONCodec{}).Decode([]byte(bad)); !errors.Is(err, ErrLenientJSON) {
+			t.Errorf("Decode(%q): expected ErrLenientJSON, got %v", bad, err)
+		}
+	}
+}
+
+func TestLenientTrailingCommasAndDuplicates(t *testing.T) {
+	in := []byte(`{"id": 1, "tags": ["a", "b",], "id": 2,}`)
+
+	var fixes []Fix
+	v, err := LenientJSONCodec{OnFix: func(f Fix) { fixes = append(fixes, f) }}.Decode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.(map[string]interface{})["id"] != 2.0 {
+		t.Errorf("LastWins: got id %v", v.(map[string]interface{})["id"])
+	}
+	want := []Fix{{27, "trailing comma"}, {31, `duplicate key "id"`}, {38, "trailing comma"}}
+	if !reflect.DeepEqual(fixes, want) {
+		t.Errorf("Fixes = %v, want %v", fixes, want)
+	}
+
+	v, err = LenientJSONCodec{DuplicateKeys: FirstWins}.Decode(in)
+	if err != nil || v.(map[string]interface{})["id"] != 1.0 {
+		t.Errorf("FirstWins: got %v, %v", v, err)
+	}
+	if _, err := (LenientJSONCodec{DuplicateKeys: ErrorOnDuplicate}).Decode(in); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("Expected ErrDuplicateKey, got %v", err)
+	}
+}
+
+func TestTrimLenient(t *testing.T) {
+	in := `{user: 'jane', password: 'hunter2', /* debug */ blob: '` + strings.Repeat("x", 50) + `'}`
+	out, err := New(Config{FieldLimit: 10, ReplaceWithMarker: true, Codec: LenientJSONCodec{}, Blacklist: []string{"password"}}).Trim([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"blob":"[TRIMMED]","password":"[TRIMMED]","user":"jane"}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
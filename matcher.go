@@ -0,0 +1,66 @@
+package jsontrim
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPattern indicates a malformed pattern given to CompilePatterns.
+var ErrPattern = errors.New("malformed path pattern")
+
+// Patterns is a compiled set of dotted path patterns, with exactly the
+// semantics of Blacklist, Required and the other path options: "*" matches
+// any one key or array index, and a pattern matches only paths with as many
+// segments as it has. It is safe for concurrent use.
+type Patterns struct {
+	parts [][]string
+}
+
+// CompilePatterns compiles dotted path patterns such as "users.*.email", e.g.
+// to make routing or filtering decisions that agree with a Trimmer's rules.
+// Malformed paths ("a..b") and jq-style queries, which select nodes of one
+// particular document, are rejected; every problem is reported, joined, in an
+// error wrapping ErrPattern.
+func CompilePatterns(patterns []string) (*Patterns, error) {
+	p := &Patterns{parts: make([][]string, 0, len(patterns))}
+	var errs []error
+	for _, pattern := range patterns {
+		switch {
+		case isQuery(pattern):
+			errs = append(errs, fmt.Errorf("%w: %q is a query", ErrPattern, pattern))
+		case pattern == "" || strings.Contains("."+pattern+".", ".."):
+			errs = append(errs, fmt.Errorf("%w: %q", ErrPattern, pattern))
+		default:
+			p.parts = append(p.parts, strings.Split(pattern, "."))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return p, nil
+}
+
+// Match reports whether a pattern matches the dotted path, e.g.
+// "users.0.email".
+func (p *Patterns) Match(path string) bool {
+	return p.MatchParts(strings.Split(path, "."))
+}
+
+// MatchParts is Match for a path already split into keys and indexes, which
+// may contain dots themselves.
+func (p *Patterns) MatchParts(path []string) bool {
+	for _, rule := range p.parts {
+		if matchParts(rule, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether one of the Trimmer's Blacklist paths matches the
+// dotted path, exactly as trimming applies them. jq-style Blacklist entries
+// depend on the document, so they aren't considered.
+func (t *Trimmer) Matches(path string) bool {
+	return t.current().matchesBlacklist(strings.Split(path, "."))
+}
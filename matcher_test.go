@@ -0,0 +1,39 @@
+package jsontrim
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	p, err := CompilePatterns([]string{"users.*.email", "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"users.0.email":        true,
+		"users.x.email":        true,
+		"token":                true,
+		"users.0":              false,
+		"users.0.email.domain": false,
+		"tokens":               false,
+	} {
+		if got := p.Match(path); got != want {
+			t.Errorf("Match(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if !p.MatchParts([]string{"users", "a.b", "email"}) {
+		t.Error("Expected MatchParts to take keys with dots")
+	}
+
+	if _, err := CompilePatterns([]string{"a..b", ".items[]", "ok"}); !errors.Is(err, ErrPattern) {
+		t.Errorf("Expected ErrPattern, got %v", err)
+	}
+}
+
+func TestTrimmerMatches(t *testing.T) {
+	trimmer := New(Config{Blacklist: []string{"*.password", `.events[] | select(.level == "debug")`}})
+	if !trimmer.Matches("user.password") || trimmer.Matches("password") || trimmer.Matches("events.0") {
+		t.Error("Expected Matches to follow the plain Blacklist paths only")
+	}
+}
@@ -0,0 +1,104 @@
+package jsontrim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrMemoryLimit indicates decoding the input would materialize more than
+// Config.MaxMemory.
+var ErrMemoryLimit = errors.New("memory limit exceeded")
+
+// checkMemory estimates what decoding raw materializes and fails with
+// ErrMemoryLimit if that's over MaxMemory.
+func (t *Trimmer) checkMemory(raw []byte) error {
+	if t.cfg.MaxMemory <= 0 {
+		return nil
+	}
+	need := len(raw) * 4 // Binary codecs are denser than their decoded trees
+	if t.jsonCodec() {
+		need = jsonDecodedSize(raw)
+	}
+	if need > t.cfg.MaxMemory {
+		return fmt.Errorf("%w: ~%d > %d bytes", ErrMemoryLimit, need, t.cfg.MaxMemory)
+	}
+	return nil
+}
+
+// jsonCodec reports whether t reads plain JSON, which TrimStream can take over.
+func (t *Trimmer) jsonCodec() bool {
+	switch t.cfg.Codec.(type) {
+	case JSONCodec, LenientJSONCodec:
+		return true
+	}
+	return false
+}
+
+// streamsOver reports whether err should be answered with trimStreamed.
+func (t *Trimmer) streamsOver(err error) bool {
+	return t.cfg.StreamOverMemory && t.jsonCodec() && errors.Is(err, ErrMemoryLimit)
+}
+
+// trimStreamed trims raw with TrimStream, whose memory use is bounded by the
+// largest scalar rather than the whole tree.
+func (t *Trimmer) trimStreamed(raw []byte) ([]byte, TrimResult, error) {
+	var buf bytes.Buffer
+	if err := t.TrimStream(bytes.NewReader(raw), &buf); err != nil {
+		return nil, TrimResult{}, err
+	}
+	out := buf.Bytes()
+	in, size := t.measure(raw), t.measure(out)
+	return out, TrimResult{InputSize: in, OutputSize: size, BytesRemoved: in - size}, nil
+}
+
+// jsonDecodedSize estimates the bytes encoding/json allocates decoding raw
+// into interface{} values, in one pass and without allocating: string data
+// plus headers, map entries, slice elements and boxed scalars. It is a rough
+// upper bound for 64-bit platforms, not an exact count.
+func jsonDecodedSize(raw []byte) int {
+	const (
+		boxed     = 16 // An interface{} holding a scalar or header
+		strHeader = 16
+		mapEntry  = 48 // Key, value and bucket overhead
+		mapHeader = 48
+		sliceHdr  = 24
+	)
+	size := 0
+	inString, escaped := false, false
+	prev := byte(0) // Last structural byte outside strings
+	for _, c := range raw {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			default:
+				size++
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+			size += strHeader + boxed
+		case '{':
+			size += mapHeader + boxed
+		case '[':
+			size += sliceHdr + boxed
+		case ':':
+			size += mapEntry
+		case ' ', '\t', '\n', '\r':
+			continue
+		default:
+			if prev == ':' || prev == '[' || prev == ',' || prev == 0 {
+				size += boxed + 8 // A number, true, false or null
+			}
+		}
+		prev = c
+	}
+	return size
+}
@@ -0,0 +1,34 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaxMemory(t *testing.T) {
+	items := make([]interface{}, 1000)
+	for i := range items {
+		items[i] = map[string]interface{}{"id": i, "name": strings.Repeat("n", 20)}
+	}
+	raw, _ := json.Marshal(map[string]interface{}{"items": items})
+
+	if size := jsonDecodedSize(raw); size < len(raw) {
+		t.Errorf("Expected the decoded estimate (%d) to exceed the input (%d)", size, len(raw))
+	}
+	if _, err := New(Config{MaxMemory: len(raw)}).Trim(raw); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("Expected ErrMemoryLimit, got %v", err)
+	}
+	if _, err := New(Config{MaxMemory: 100 * len(raw)}).Trim(raw); err != nil {
+		t.Errorf("Expected a generous MaxMemory to pass, got %v", err)
+	}
+
+	out, err := New(Config{TotalLimit: 500, MaxMemory: len(raw), StreamOverMemory: true}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 500 || !json.Valid(out) {
+		t.Errorf("Expected valid streamed output under 500 bytes, got %s", out)
+	}
+}
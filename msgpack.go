@@ -0,0 +1,406 @@
+package jsontrim
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// MsgPackCodec decodes and encodes MessagePack, so payloads from binary RPC
+// layers can be trimmed without a detour through JSON text. Limits are measured
+// against the MessagePack size.
+//
+// Integers decode as int64 (uint64 above math.MaxInt64), bin as []byte and
+// extension types as MsgPackExt, all of which round-trip unchanged. Map keys
+// that aren't strings are converted with fmt.Sprint, since the trimmer works on
+// string-keyed maps.
+type MsgPackCodec struct{}
+
+// MsgPackExt is an undecoded MessagePack extension value.
+type MsgPackExt struct {
+	Type int8
+	Data []byte
+}
+
+// ErrMsgPack indicates malformed MessagePack input.
+var ErrMsgPack = errors.New("invalid msgpack")
+
+// maxNesting bounds recursion when decoding binary formats.
+const maxNesting = 10000
+
+// Decode implements Codec.
+func (MsgPackCodec) Decode(data []byte) (interface{}, error) {
+	d := &mpDecoder{buf: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.buf) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrMsgPack, len(d.buf)-d.pos)
+	}
+	return v, nil
+}
+
+// Encode implements Codec.
+func (MsgPackCodec) Encode(v interface{}) ([]byte, error) {
+	return mpAppend(nil, v)
+}
+
+type mpDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *mpDecoder) take(n int) ([]byte, error) {
+	if n < 0 || n > len(d.buf)-d.pos {
+		return nil, fmt.Errorf("%w: unexpected end of input", ErrMsgPack)
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of n bytes.
+func (d *mpDecoder) uint(n int) (uint64, error) {
+	b, err := d.take(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (d *mpDecoder) value(depth int) (interface{}, error) {
+	if depth > maxNesting {
+		return nil, fmt.Errorf("%w: nesting too deep", ErrMsgPack)
+	}
+	tb, err := d.take(1)
+	if err != nil {
+		return nil, err
+	}
+	c := tb[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c >= 0x80 && c <= 0x8f:
+		return d.mapOf(int(c&0x0f), depth)
+	case c >= 0x90 && c <= 0x9f:
+		return d.arrayOf(int(c&0x0f), depth)
+	case c >= 0xa0 && c <= 0xbf:
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.take(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(int(n))
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if u > math.MaxInt64 {
+			return u, nil
+		}
+		return int64(u), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		u, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from size bytes
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayOf(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapOf(int(n), depth)
+	}
+	return nil, fmt.Errorf("%w: unknown type byte 0x%02x", ErrMsgPack, c)
+}
+
+func (d *mpDecoder) str(n int) (string, error) {
+	b, err := d.take(n)
+	return string(b), err
+}
+
+func (d *mpDecoder) ext(n int) (interface{}, error) {
+	tb, err := d.take(1)
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.take(n)
+	if err != nil {
+		return nil, err
+	}
+	return MsgPackExt{Type: int8(tb[0]), Data: append([]byte(nil), b...)}, nil
+}
+
+func (d *mpDecoder) arrayOf(n int, depth int) (interface{}, error) {
+	// Every element takes at least one byte; reject absurd lengths before allocating
+	if n > len(d.buf)-d.pos {
+		return nil, fmt.Errorf("%w: unexpected end of input", ErrMsgPack)
+	}
+	out := make([]interface{}, n)
+	for i := range out {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (d *mpDecoder) mapOf(n int, depth int) (interface{}, error) {
+	if n > (len(d.buf)-d.pos)/2 {
+		return nil, fmt.Errorf("%w: unexpected end of input", ErrMsgPack)
+	}
+	out := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// mpAppend appends the MessagePack encoding of v to b.
+func mpAppend(b []byte, v interface{}) ([]byte, error) {
+	switch vv := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if vv {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case string:
+		return mpAppendStr(b, vv), nil
+	case []byte:
+		return mpAppendBin(b, vv), nil
+	case float64:
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(vv)), nil
+	case float32:
+		b = append(b, 0xca)
+		return binary.BigEndian.AppendUint32(b, math.Float32bits(vv)), nil
+	case int:
+		return mpAppendInt(b, int64(vv)), nil
+	case int8:
+		return mpAppendInt(b, int64(vv)), nil
+	case int16:
+		return mpAppendInt(b, int64(vv)), nil
+	case int32:
+		return mpAppendInt(b, int64(vv)), nil
+	case int64:
+		return mpAppendInt(b, vv), nil
+	case uint:
+		return mpAppendUint(b, uint64(vv)), nil
+	case uint8:
+		return mpAppendUint(b, uint64(vv)), nil
+	case uint16:
+		return mpAppendUint(b, uint64(vv)), nil
+	case uint32:
+		return mpAppendUint(b, uint64(vv)), nil
+	case uint64:
+		return mpAppendUint(b, vv), nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(vv), 10, 64); err == nil {
+			return mpAppendInt(b, i), nil
+		}
+		f, err := vv.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return mpAppend(b, f)
+	case MsgPackExt:
+		return mpAppendExt(b, vv), nil
+	case []interface{}:
+		b = mpAppendLen(b, len(vv), 0x90, 0xdc)
+		var err error
+		for _, item := range vv {
+			if b, err = mpAppend(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		b = mpAppendLen(b, len(vv), 0x80, 0xde)
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // Deterministic output, like encoding/json
+		var err error
+		for _, k := range keys {
+			b = mpAppendStr(b, k)
+			if b, err = mpAppend(b, vv[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+}
+
+// mpAppendLen writes an array/map header: fix form below 16, else 16/32-bit.
+func mpAppendLen(b []byte, n int, fix, wide byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, wide), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, wide+1), uint32(n))
+	}
+}
+
+func mpAppendStr(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func mpAppendBin(b []byte, data []byte) []byte {
+	n := len(data)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, data...)
+}
+
+func mpAppendExt(b []byte, e MsgPackExt) []byte {
+	n := len(e.Data)
+	switch n {
+	case 1:
+		b = append(b, 0xd4)
+	case 2:
+		b = append(b, 0xd5)
+	case 4:
+		b = append(b, 0xd6)
+	case 8:
+		b = append(b, 0xd7)
+	case 16:
+		b = append(b, 0xd8)
+	default:
+		switch {
+		case n <= math.MaxUint8:
+			b = append(b, 0xc7, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xc8), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xc9), uint32(n))
+		}
+	}
+	b = append(b, byte(e.Type))
+	return append(b, e.Data...)
+}
+
+func mpAppendInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0:
+		return mpAppendUint(b, uint64(i))
+	case i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+func mpAppendUint(b []byte, u uint64) []byte {
+	switch {
+	case u <= 0x7f:
+		return append(b, byte(u))
+	case u <= math.MaxUint8:
+		return append(b, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(u))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), u)
+	}
+}
@@ -0,0 +1,69 @@
+package jsontrim
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"nil":   nil,
+		"bools": []interface{}{true, false},
+		"ints":  []interface{}{int64(0), int64(127), int64(-32), int64(-33), int64(300), int64(-70000), int64(math.MaxInt64), uint64(math.MaxUint64)},
+		"float": 1.5,
+		"str":   strings.Repeat("s", 40),
+		"bin":   []byte{1, 2, 3},
+		"ext":   MsgPackExt{Type: -1, Data: []byte{0, 0, 0, 1}},
+		"big":   make([]interface{}, 20),
+	}
+	encoded, err := MsgPackCodec{}.Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := MsgPackCodec{}.Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Round trip mismatch:\n in: %#v\nout: %#v", in, out)
+	}
+}
+
+func TestMsgPackDecodeErrors(t *testing.T) {
+	for _, raw := range [][]byte{{0xdc, 0xff, 0xff}, {0xa5, 'a'}, {0xc1}, {0x01, 0x02}} {
+		if _, err := (MsgPackCodec{}).Decode(raw); !errors.Is(err, ErrMsgPack) {
+			t.Errorf("Decode(% x): expected ErrMsgPack, got %v", raw, err)
+		}
+	}
+}
+
+func TestTrimMsgPack(t *testing.T) {
+	doc := map[string]interface{}{"id": int64(7), "blob": strings.Repeat("x", 300), "tags": []interface{}{"a", "b"}}
+	raw, _ := MsgPackCodec{}.Encode(doc)
+
+	out, err := New(Config{TotalLimit: 100, Codec: MsgPackCodec{}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 100 {
+		t.Errorf("MsgPack output over limit: %d", len(out))
+	}
+	v, err := MsgPackCodec{}.Decode(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	if m["id"] != int64(7) {
+		t.Errorf("Lost integer type or field: %#v", m)
+	}
+	if _, ok := m["blob"]; ok {
+		t.Errorf("Oversized field kept: %#v", m)
+	}
+	if bytes.HasPrefix(out, []byte("{")) {
+		t.Error("Output is JSON, expected msgpack")
+	}
+}
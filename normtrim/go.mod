@@ -0,0 +1,10 @@
+module github.com/arun0009/jsontrim/normtrim
+
+go 1.25.4
+
+replace github.com/arun0009/jsontrim => ..
+
+require (
+	github.com/arun0009/jsontrim v0.0.0-00010101000000-000000000000
+	golang.org/x/text v0.36.0
+)
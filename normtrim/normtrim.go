@@ -0,0 +1,45 @@
+// Package normtrim provides a jsontrim Transformer that rewrites string values
+// to a Unicode normalization form, so strings that look identical but are
+// encoded differently compare and deduplicate equal downstream. It uses
+// golang.org/x/text/unicode/norm, and is a module of its own so jsontrim
+// itself has no dependencies.
+package normtrim
+
+import (
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// Normalizer is a jsontrim.Transformer normalizing string values:
+//
+//	n, err := normtrim.New(norm.NFKC, "user.name", "items.*.title")
+//	trimmer := jsontrim.New(jsontrim.Config{Transformers: []jsontrim.Transformer{n}})
+type Normalizer struct {
+	form  norm.Form
+	paths *jsontrim.Patterns // nil: every string
+}
+
+// New returns a Normalizer rewriting strings to form, e.g. norm.NFC or
+// norm.NFKC. With paths given, only values at those paths are rewritten;
+// malformed paths are reported in an error wrapping jsontrim.ErrPattern.
+func New(form norm.Form, paths ...string) (*Normalizer, error) {
+	n := &Normalizer{form: form}
+	if len(paths) > 0 {
+		p, err := jsontrim.CompilePatterns(paths)
+		if err != nil {
+			return nil, err
+		}
+		n.paths = p
+	}
+	return n, nil
+}
+
+// Transform implements jsontrim.Transformer.
+func (n *Normalizer) Transform(path []string, v interface{}) interface{} {
+	str, ok := v.(string)
+	if !ok || n.paths != nil && !n.paths.MatchParts(path) {
+		return v
+	}
+	return n.form.String(str) // Returns str itself if it's already normalized
+}
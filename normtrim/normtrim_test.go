@@ -0,0 +1,50 @@
+package normtrim
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/arun0009/jsontrim"
+)
+
+func TestNormalizer(t *testing.T) {
+	tests := []struct {
+		form    norm.Form
+		in, out string
+	}{
+		{norm.NFC, "Cafe\u0301", "Caf\u00e9"},
+		{norm.NFC, "e\u0323", "\u1eb9"},
+		{norm.NFC, "e\u0301\u0323", "\u1eb9\u0301"}, // Marks reordered canonically before composing
+		{norm.NFC, "\u212b\u2126", "\u00c5\u03a9"},  // Angstrom and Ohm signs
+		{norm.NFC, "\u1100\u1161\u11a8", "\uac01"},
+		{norm.NFC, "\ufb01le", "\ufb01le"},
+		{norm.NFKC, "\ufb01le \uff4e\uff41\uff4d\uff45\u2026", "file name..."},
+	}
+	for _, tt := range tests {
+		n, err := New(tt.form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := n.Transform(nil, tt.in); got != tt.out {
+			t.Errorf("%v %+q: got %+q, want %+q", tt.form, tt.in, got, tt.out)
+		}
+	}
+
+	n, err := New(norm.NFC, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := jsontrim.New(jsontrim.Config{TotalLimit: 4096, Transformers: []jsontrim.Transformer{n}}).Trim([]byte("{\"name\":\"Jose\u0301\",\"note\":\"Jose\u0301\"}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"name\":\"Jos\u00e9\",\"note\":\"Jose\u0301\"}"; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+
+	if _, err := New(norm.NFC, "a..b"); !errors.Is(err, jsontrim.ErrPattern) {
+		t.Errorf("Expected ErrPattern, got %v", err)
+	}
+}
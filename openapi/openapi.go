@@ -0,0 +1,228 @@
+// Package openapi derives per-endpoint jsontrim configurations from an
+// OpenAPI document, so an API gateway can trim bodies per route without
+// hand-written rules.
+//
+// Schema properties marked `x-sensitive: true` are blacklisted and required
+// properties become Required paths. Both OpenAPI 3 (`content` /
+// `components/schemas`) and Swagger 2 (`schema` / `definitions`) layouts are
+// understood. Only JSON documents are accepted; convert YAML specs first.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// Profile holds the configs for one operation.
+type Profile struct {
+	Request  jsontrim.Config // Applied to the request body
+	Response jsontrim.Config // Applied to response bodies (all status codes combined)
+}
+
+// methods are the operation keys of an OpenAPI path item.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Profiles reads spec and returns one Profile per operation, keyed by
+// "METHOD /path" (e.g., "GET /users/{id}"). Every Config starts as a copy of
+// base with the derived Blacklist and Required paths appended.
+func Profiles(spec []byte, base jsontrim.Config) (map[string]Profile, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	paths, ok := doc["paths"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("openapi: spec has no paths")
+	}
+
+	maxDepth := base.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = 10 // jsontrim's default
+	}
+
+	out := make(map[string]Profile)
+	for route, item := range paths {
+		ops, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, method := range methods {
+			op, ok := ops[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			req, resp := rules{maxDepth: maxDepth}, rules{maxDepth: maxDepth}
+			if body, ok := op["requestBody"].(map[string]interface{}); ok {
+				req.walkMedia(doc, body)
+			}
+			// Swagger 2: body parameters carry the schema
+			if params, ok := op["parameters"].([]interface{}); ok {
+				for _, p := range params {
+					if pm, ok := p.(map[string]interface{}); ok && pm["in"] == "body" {
+						req.walk(doc, pm["schema"], nil, true, nil)
+					}
+				}
+			}
+			if responses, ok := op["responses"].(map[string]interface{}); ok {
+				for _, r := range responses {
+					if rm, ok := r.(map[string]interface{}); ok {
+						resp.walkMedia(doc, resolve(doc, rm))
+					}
+				}
+			}
+
+			out[strings.ToUpper(method)+" "+route] = Profile{
+				Request:  req.apply(base),
+				Response: resp.apply(base),
+			}
+		}
+	}
+	return out, nil
+}
+
+// rules collects the paths derived from a schema.
+type rules struct {
+	maxDepth  int
+	blacklist map[string]bool
+	required  map[string]bool
+}
+
+// apply returns a copy of base extended with the collected paths.
+func (r *rules) apply(base jsontrim.Config) jsontrim.Config {
+	cfg := base
+	cfg.Blacklist = append(append([]string(nil), base.Blacklist...), sortedKeys(r.blacklist)...)
+	cfg.Required = append(append([]string(nil), base.Required...), sortedKeys(r.required)...)
+	return cfg
+}
+
+// walkMedia walks the schema of a request body or response object, in either
+// the OpenAPI 3 ("content" by media type) or Swagger 2 ("schema") layout.
+func (r *rules) walkMedia(doc, obj map[string]interface{}) {
+	if obj == nil {
+		return
+	}
+	obj = resolve(doc, obj)
+	if schema, ok := obj["schema"]; ok {
+		r.walk(doc, schema, nil, true, nil)
+	}
+	if content, ok := obj["content"].(map[string]interface{}); ok {
+		for mediaType, media := range content {
+			if !strings.Contains(mediaType, "json") {
+				continue
+			}
+			if mm, ok := media.(map[string]interface{}); ok {
+				r.walk(doc, mm["schema"], nil, true, nil)
+			}
+		}
+	}
+}
+
+// maxRefDepth bounds chains of $ref pointing at other $refs.
+const maxRefDepth = 32
+
+// walk records sensitive and required paths under schema. required tracks
+// whether every ancestor was itself required, since protecting a field only
+// makes sense if its parent is guaranteed to be present.
+//
+// Recursive schemas are expanded until the path reaches maxDepth: anything
+// deeper is dropped by the Trimmer's MaxDepth anyway. refs holds the $refs
+// expanded at the current path, so a ref cycle that doesn't add properties
+// (A allOf A) still terminates.
+func (r *rules) walk(doc map[string]interface{}, schema interface{}, path []string, required bool, refs []string) {
+	s, ok := schema.(map[string]interface{})
+	if !ok || len(path) >= r.maxDepth {
+		return
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		for _, seen := range refs {
+			if seen == ref {
+				return
+			}
+		}
+		refs = append(refs[:len(refs):len(refs)], ref)
+	}
+	s = resolve(doc, s)
+
+	if len(path) > 0 {
+		key := strings.Join(path, ".")
+		if sensitive, _ := s["x-sensitive"].(bool); sensitive {
+			if r.blacklist == nil {
+				r.blacklist = make(map[string]bool)
+			}
+			r.blacklist[key] = true
+			return
+		}
+		if required {
+			if r.required == nil {
+				r.required = make(map[string]bool)
+			}
+			r.required[key] = true
+		}
+	}
+
+	for _, combinator := range []string{"allOf", "oneOf", "anyOf"} {
+		if subs, ok := s[combinator].([]interface{}); ok {
+			for _, sub := range subs {
+				// Only allOf members are all guaranteed to apply
+				r.walk(doc, sub, path, required && combinator == "allOf", refs)
+			}
+		}
+	}
+
+	if props, ok := s["properties"].(map[string]interface{}); ok {
+		req := map[string]bool{}
+		if list, ok := s["required"].([]interface{}); ok {
+			for _, name := range list {
+				if n, ok := name.(string); ok {
+					req[n] = true
+				}
+			}
+		}
+		for name, prop := range props {
+			r.walk(doc, prop, append(path[:len(path):len(path)], name), required && req[name], nil)
+		}
+	}
+	if items, ok := s["items"]; ok {
+		// Array items are never individually required
+		r.walk(doc, items, append(path[:len(path):len(path)], "*"), false, nil)
+	}
+}
+
+// resolve follows a local "$ref" (e.g., "#/components/schemas/User").
+func resolve(doc, obj map[string]interface{}) map[string]interface{} {
+	for i := 0; i < maxRefDepth; i++ {
+		ref, ok := obj["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return obj
+		}
+		var cur interface{} = doc
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				return obj
+			}
+			cur = m[part]
+		}
+		next, ok := cur.(map[string]interface{})
+		if !ok {
+			return obj
+		}
+		obj = next
+	}
+	return obj
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
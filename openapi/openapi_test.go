@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+const spec = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/users/{id}": {
+      "get": {
+        "responses": {
+          "200": {
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+          }
+        }
+      },
+      "put": {
+        "requestBody": {
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+        },
+        "responses": {"204": {"description": "ok"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "User": {
+        "type": "object",
+        "required": ["id", "profile"],
+        "properties": {
+          "id": {"type": "string"},
+          "password": {"type": "string", "x-sensitive": true},
+          "profile": {
+            "type": "object",
+            "required": ["name"],
+            "properties": {
+              "name": {"type": "string"},
+              "ssn": {"type": "string", "x-sensitive": true}
+            }
+          },
+          "friends": {"type": "array", "items": {"$ref": "#/components/schemas/User"}}
+        }
+      }
+    }
+  }
+}`
+
+func TestProfiles(t *testing.T) {
+	profiles, err := Profiles([]byte(spec), jsontrim.Config{TotalLimit: 4096, MaxDepth: 5, Blacklist: []string{"debug"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get, ok := profiles["GET /users/{id}"]
+	if !ok {
+		t.Fatalf("Missing GET profile: %v", profiles)
+	}
+	wantBlacklist := []string{"debug", "friends.*.password", "friends.*.profile.ssn", "password", "profile.ssn"}
+	if !reflect.DeepEqual(get.Response.Blacklist, wantBlacklist) {
+		t.Errorf("Blacklist = %v, want %v", get.Response.Blacklist, wantBlacklist)
+	}
+	wantRequired := []string{"id", "profile", "profile.name"}
+	if !reflect.DeepEqual(get.Response.Required, wantRequired) {
+		t.Errorf("Required = %v, want %v", get.Response.Required, wantRequired)
+	}
+	if get.Response.TotalLimit != 4096 {
+		t.Error("Base config not carried over")
+	}
+
+	put := profiles["PUT /users/{id}"]
+	if len(put.Request.Blacklist) != len(wantBlacklist) {
+		t.Errorf("Request body rules missing: %v", put.Request.Blacklist)
+	}
+
+	out, err := jsontrim.New(get.Response).Trim([]byte(`{"id":"1","password":"x","profile":{"name":"a","ssn":"123"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"id":"1","profile":{"name":"a"}}` {
+		t.Errorf("Unexpected trimmed body: %s", out)
+	}
+}
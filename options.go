@@ -0,0 +1,120 @@
+package jsontrim
+
+import (
+	"maps"
+	"slices"
+)
+
+// Option changes one aspect of a Config. Any func(*Config) can be used as an
+// Option for fields without a helper below.
+type Option func(*Config)
+
+// With returns a new Trimmer with t's configuration changed by opts; t itself
+// is not modified. Compiled Blacklist and Whitelist rules are shared with t
+// when the options leave them unchanged, so per-endpoint variants of a base
+// Trimmer are cheap. Limits set to zero fall back to their defaults, as in
+// New, unless t came from NewV2 with them explicitly zero.
+func (t *Trimmer) With(opts ...Option) *Trimmer {
+	t = t.current()
+	cfg := t.cfg
+	// Options may append to or write into these; keep t's copies intact
+	cfg.Blacklist = slices.Clip(cfg.Blacklist)
+	cfg.Whitelist = slices.Clip(cfg.Whitelist)
+	cfg.Required = slices.Clip(cfg.Required)
+	cfg.Atomic = slices.Clip(cfg.Atomic)
+	cfg.Transformers = slices.Clip(cfg.Transformers)
+	cfg.Rename = maps.Clone(cfg.Rename)
+	cfg.SubtreeLimits = maps.Clone(cfg.SubtreeLimits)
+	cfg.FieldHooks = maps.Clone(cfg.FieldHooks)
+	cfg.DropIf = maps.Clone(cfg.DropIf)
+	cfg.Weights = maps.Clone(cfg.Weights)
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	defaults := defaultLimits(&cfg, &t.cfg)
+	// A default the options left alone is still a default
+	defaults.fieldLimit = defaults.fieldLimit || t.defaults.fieldLimit && cfg.FieldLimit == t.cfg.FieldLimit
+	defaults.totalLimit = defaults.totalLimit || t.defaults.totalLimit && cfg.TotalLimit == t.cfg.TotalLimit
+	defaults.maxDepth = defaults.maxDepth || t.defaults.maxDepth && cfg.MaxDepth == t.cfg.MaxDepth
+	derived := build(cfg, t)
+	derived.defaults = defaults
+	return derived
+}
+
+// WithFieldLimit sets FieldLimit.
+func WithFieldLimit(n int) Option {
+	return func(c *Config) { c.FieldLimit = n }
+}
+
+// WithTotalLimit sets TotalLimit.
+func WithTotalLimit(n int) Option {
+	return func(c *Config) { c.TotalLimit = n }
+}
+
+// WithMaxDepth sets MaxDepth.
+func WithMaxDepth(n int) Option {
+	return func(c *Config) { c.MaxDepth = n }
+}
+
+// WithStrategy sets Strategy.
+func WithStrategy(s TruncStrategy) Option {
+	return func(c *Config) { c.Strategy = s }
+}
+
+// WithBlacklist adds paths to Blacklist.
+func WithBlacklist(paths ...string) Option {
+	return func(c *Config) { c.Blacklist = append(c.Blacklist, paths...) }
+}
+
+// WithRequired adds paths to Required.
+func WithRequired(paths ...string) Option {
+	return func(c *Config) { c.Required = append(c.Required, paths...) }
+}
+
+// WithMarker sets ReplaceWithMarker.
+func WithMarker(on bool) Option {
+	return func(c *Config) { c.ReplaceWithMarker = on }
+}
+
+// WithTruncateStrings sets TruncateStrings.
+func WithTruncateStrings(on bool) Option {
+	return func(c *Config) { c.TruncateStrings = on }
+}
+
+// WithHooks sets Hooks.
+func WithHooks(h Hooks) Option {
+	return func(c *Config) { c.Hooks = h }
+}
+
+// WithCodec sets Codec.
+func WithCodec(codec Codec) Option {
+	return func(c *Config) { c.Codec = codec }
+}
+
+// Update changes t's configuration by opts, as With does, in place: trims
+// already running finish with the configuration they started with, and later
+// ones use the new one. Concurrent Updates are applied one after another, none
+// lost.
+func (t *Trimmer) Update(opts ...Option) {
+	for {
+		cur := t.live.Load()
+		base := cur
+		if base == nil {
+			base = t
+		}
+		if t.live.CompareAndSwap(cur, base.With(opts...)) {
+			return
+		}
+	}
+}
+
+// current returns the configuration snapshot a call on t runs with: the
+// latest Update, or t itself.
+func (t *Trimmer) current() *Trimmer {
+	if t.live != nil {
+		if cur := t.live.Load(); cur != nil {
+			return cur
+		}
+	}
+	return t
+}
@@ -0,0 +1,61 @@
+package jsontrim
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWith(t *testing.T) {
+	base := New(Config{TotalLimit: 4096, Blacklist: []string{"password"}})
+	raw := []byte(`{"password":"x","token":"y","note":"hello world"}`)
+
+	same := base.With(WithFieldLimit(8))
+	if &same.blacklistParts[0] != &base.blacklistParts[0] {
+		t.Error("Unchanged blacklist should be shared with the base Trimmer")
+	}
+	if out, _ := same.Trim(raw); string(out) != `{"token":"y"}` {
+		t.Errorf("Got %s", out)
+	}
+
+	derived := base.With(WithBlacklist("token"), func(c *Config) { c.Rename = map[string]string{"note": "msg"} })
+	if out, _ := derived.Trim(raw); string(out) != `{"msg":"hello world"}` {
+		t.Errorf("Got %s", out)
+	}
+
+	// The base Trimmer is unaffected
+	if out, _ := base.Trim(raw); string(out) != `{"note":"hello world","token":"y"}` {
+		t.Errorf("Base changed: %s", out)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tr := New(Config{TotalLimit: 4096, FieldLimit: 4096})
+	raw := []byte(`{"a":"` + strings.Repeat("x", 100) + `","b":1}`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if out, err := tr.Trim(raw); err != nil || len(out) > 4096 {
+					t.Errorf("Unexpected trim result %s, %v", out, err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			tr.Update(func(c *Config) { c.MaxDepth++ })
+		}()
+	}
+	wg.Wait()
+	if got := tr.Config().MaxDepth; got != 18 {
+		t.Errorf("Expected all 8 Updates applied, got MaxDepth %d", got)
+	}
+
+	tr.Update(WithFieldLimit(50))
+	if out, _ := tr.Trim(raw); string(out) != `{"b":1}` {
+		t.Errorf("Expected the updated FieldLimit to apply, got %s", out)
+	}
+}
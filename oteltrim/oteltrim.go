@@ -0,0 +1,104 @@
+// Package oteltrim applies a Trimmer to OpenTelemetry span and log
+// attributes, replacing the SDK's per-value character truncation with
+// structure-aware trimming: attributes holding JSON are trimmed as JSON,
+// and when a span's attributes are over budget whole attributes are dropped
+// by the Trimmer's strategy rather than every value being cut.
+//
+// Attributes are passed as a map of key to value (string, bool, int64,
+// float64 or a slice of those), so the package doesn't depend on the OTel
+// SDK. The oteltrim/sdktrim module wraps a Limiter in SDK span and log
+// processors.
+package oteltrim
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// Limiter trims attribute sets. Each attribute is a top-level field of the
+// document the Trimmer sees, so FieldLimit is the per-attribute limit and
+// TotalLimit the attribute budget of one span or log record.
+type Limiter struct {
+	t *jsontrim.Trimmer
+}
+
+// NewLimiter returns a Limiter using t. A nil t uses 4KB attributes, a 64KB
+// total, truncated strings and parsed embedded JSON.
+func NewLimiter(t *jsontrim.Trimmer) *Limiter {
+	if t == nil {
+		t = jsontrim.New(jsontrim.Config{FieldLimit: 4096, TotalLimit: 64 << 10, TruncateStrings: true, ParseEmbedded: true})
+	}
+	return &Limiter{t: t}
+}
+
+// Limit returns attrs trimmed to the Limiter's budget. Attributes missing
+// from the result were dropped. Numbers and booleans are kept or dropped
+// whole, never cut, and slices of them lose items but keep their type. An
+// attribute the Trimmer replaced with its marker (ReplaceWithMarker) holds
+// the marker string. attrs is not modified.
+func (l *Limiter) Limit(attrs map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(attrs)
+	if err != nil {
+		return nil, err
+	}
+	out, err := l.t.Trim(raw)
+	if err != nil {
+		return nil, err
+	}
+	var trimmed map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.UseNumber() // Slices rebuilt from the output keep int64 precision
+	if err := dec.Decode(&trimmed); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(trimmed))
+	for k, v := range trimmed {
+		if v, ok := restore(attrs[k], v); ok {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+// restore converts a trimmed attribute value back to the type of the
+// original. It reports false if nothing of it is left.
+func restore(orig, v interface{}) (interface{}, bool) {
+	if orig == nil {
+		return nil, false
+	}
+	if s, ok := v.(string); ok {
+		return s, true // Cut, or replaced with the marker
+	}
+	if _, ok := orig.(string); ok {
+		// Parsed embedded JSON comes back as a value; store it as JSON again
+		b, err := json.Marshal(v)
+		return string(b), err == nil
+	}
+	ov := reflect.ValueOf(orig)
+	if ov.Kind() != reflect.Slice {
+		return orig, true // Numbers and booleans are never cut
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	if len(items) == ov.Len() {
+		return orig, true
+	}
+	out := reflect.MakeSlice(ov.Type(), 0, len(items))
+	for _, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			continue
+		}
+		elem := reflect.New(ov.Type().Elem())
+		if json.Unmarshal(b, elem.Interface()) == nil {
+			out = reflect.Append(out, elem.Elem()) // Omission markers inside number slices are skipped
+		}
+	}
+	return out.Interface(), true
+}
@@ -0,0 +1,58 @@
+package oteltrim
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(jsontrim.New(jsontrim.Config{FieldLimit: 200, TotalLimit: 600, TruncateStrings: true, ParseEmbedded: true}))
+	attrs := map[string]interface{}{
+		"http.status_code": int64(200),
+		"sampled":          true,
+		"ratio":            0.25,
+		"tags":             []string{"a", "b"},
+		"ids":              []int64{9007199254740993, 2},
+		"db.statement":     strings.Repeat("s", 400),
+		"request.body":     `{"user":"u","blob":"` + strings.Repeat("b", 400) + `"}`,
+		"response.body":    strings.Repeat("r", 1000),
+	}
+	got, err := l.Limit(attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"http.status_code", "sampled", "ratio", "tags", "ids"} {
+		if !reflect.DeepEqual(got[k], attrs[k]) {
+			t.Errorf("%s: got %#v, want %#v", k, got[k], attrs[k])
+		}
+	}
+	if s, _ := got["db.statement"].(string); len(s) > 200 || !strings.HasPrefix(s, "sss") {
+		t.Errorf("Expected db.statement truncated, got %q", s)
+	}
+	if s, _ := got["request.body"].(string); !strings.Contains(s, `"user":"u"`) || strings.Contains(s, strings.Repeat("b", 400)) {
+		t.Errorf("Expected request.body trimmed as JSON, got %q", s)
+	}
+	if len(attrs) != 8 {
+		t.Error("Limit must not modify its input")
+	}
+}
+
+func TestLimiterMarker(t *testing.T) {
+	for _, fieldLimit := range []int{100000, 100} {
+		l := NewLimiter(jsontrim.New(jsontrim.Config{FieldLimit: fieldLimit, TotalLimit: 200, ReplaceWithMarker: true}))
+		got, err := l.Limit(map[string]interface{}{"ids": make([]int64, 2000), "name": "n"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got["ids"] != "[TRIMMED]" {
+			t.Errorf("FieldLimit %d: expected the marker for ids, got %T", fieldLimit, got["ids"])
+		}
+		if got["name"] != "n" {
+			t.Errorf("FieldLimit %d: name lost, got %v", fieldLimit, got)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package sdktrim plugs an oteltrim.Limiter into the OpenTelemetry Go SDK as
+// a span processor and a log processor, so span and log record attributes are
+// trimmed by the Limiter's Trimmer before they're exported. It is a module of
+// its own, so jsontrim itself doesn't depend on the OTel SDK.
+//
+// Attributes of the string, bool, int64 and float64 types and slices of them
+// are trimmed. Byte slices, maps and mixed slices are passed on unchanged.
+package sdktrim
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/arun0009/jsontrim/oteltrim"
+)
+
+// NewSpanProcessor returns a SpanProcessor that trims the attributes of each
+// ended span with l and passes the span on to next, usually the batch
+// processor of an exporter:
+//
+//	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(
+//		sdktrim.NewSpanProcessor(sdktrace.NewBatchSpanProcessor(exporter), limiter)))
+//
+// Dropped attributes are added to the span's DroppedAttributes. Spans whose
+// attributes can't be trimmed, because Required attributes exceed the total,
+// say, are passed on unchanged.
+func NewSpanProcessor(next sdktrace.SpanProcessor, l *oteltrim.Limiter) sdktrace.SpanProcessor {
+	return &spanProcessor{SpanProcessor: next, l: l}
+}
+
+type spanProcessor struct {
+	sdktrace.SpanProcessor
+	l *oteltrim.Limiter
+}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (p *spanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	attrs, dropped, ok := limit(p.l, s.Attributes())
+	if !ok {
+		p.SpanProcessor.OnEnd(s)
+		return
+	}
+	p.SpanProcessor.OnEnd(&span{ReadOnlySpan: s, attrs: attrs, dropped: s.DroppedAttributes() + dropped})
+}
+
+// span is a ReadOnlySpan with trimmed attributes.
+type span struct {
+	sdktrace.ReadOnlySpan
+	attrs   []attribute.KeyValue
+	dropped int
+}
+
+func (s *span) Attributes() []attribute.KeyValue { return s.attrs }
+func (s *span) DroppedAttributes() int           { return s.dropped }
+
+// NewLogProcessor returns a log Processor that trims the attributes of each
+// emitted record with l. The SDK runs processors in the order they're
+// registered, so register it before the exporting processor:
+//
+//	lp := sdklog.NewLoggerProvider(
+//		sdklog.WithProcessor(sdktrim.NewLogProcessor(limiter)),
+//		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+//
+// Records whose attributes can't be trimmed are left unchanged.
+func NewLogProcessor(l *oteltrim.Limiter) sdklog.Processor {
+	return &logProcessor{l: l}
+}
+
+type logProcessor struct {
+	l *oteltrim.Limiter
+}
+
+// OnEmit implements sdklog.Processor.
+func (p *logProcessor) OnEmit(_ context.Context, r *sdklog.Record) error {
+	attrs := make([]attribute.KeyValue, 0, r.AttributesLen())
+	r.WalkAttributes(func(kv attribute.KeyValue) bool {
+		attrs = append(attrs, kv)
+		return true
+	})
+	if trimmed, _, ok := limit(p.l, attrs); ok {
+		r.SetAttributes(trimmed...)
+	}
+	return nil
+}
+
+// Enabled implements sdklog.Processor.
+func (p *logProcessor) Enabled(context.Context, sdklog.EnabledParameters) bool { return true }
+
+// Shutdown implements sdklog.Processor.
+func (p *logProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements sdklog.Processor.
+func (p *logProcessor) ForceFlush(context.Context) error { return nil }
+
+// limit trims attrs with l, keeping their order, and returns the number of
+// attributes dropped. It reports false if the Limiter failed.
+func limit(l *oteltrim.Limiter, attrs []attribute.KeyValue) ([]attribute.KeyValue, int, bool) {
+	values := make(map[string]interface{}, len(attrs))
+	for _, kv := range attrs {
+		if trimmable(kv.Value.Type()) {
+			values[string(kv.Key)] = kv.Value.AsInterface()
+		}
+	}
+	if len(values) == 0 {
+		return attrs, 0, true
+	}
+	limited, err := l.Limit(values)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	out := make([]attribute.KeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		if !trimmable(kv.Value.Type()) {
+			out = append(out, kv)
+			continue
+		}
+		if v, ok := limited[string(kv.Key)]; ok {
+			out = append(out, keyValue(kv, v))
+		}
+	}
+	return out, len(attrs) - len(out), true
+}
+
+// trimmable reports whether attributes of type typ are passed to the Limiter.
+func trimmable(typ attribute.Type) bool {
+	switch typ {
+	case attribute.BOOL, attribute.INT64, attribute.FLOAT64, attribute.STRING,
+		attribute.BOOLSLICE, attribute.INT64SLICE, attribute.FLOAT64SLICE, attribute.STRINGSLICE:
+		return true
+	}
+	return false
+}
+
+// keyValue returns kv holding v, a value returned by the Limiter.
+func keyValue(kv attribute.KeyValue, v interface{}) attribute.KeyValue {
+	k := string(kv.Key)
+	switch v := v.(type) {
+	case string:
+		return attribute.String(k, v)
+	case []string:
+		return attribute.StringSlice(k, v)
+	case []bool:
+		return attribute.BoolSlice(k, v)
+	case []int64:
+		return attribute.Int64Slice(k, v)
+	case []float64:
+		return attribute.Float64Slice(k, v)
+	}
+	return kv // Numbers and booleans are kept whole
+}
@@ -0,0 +1,95 @@
+package sdktrim
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/arun0009/jsontrim"
+	"github.com/arun0009/jsontrim/oteltrim"
+)
+
+func limiter() *oteltrim.Limiter {
+	return oteltrim.NewLimiter(jsontrim.New(jsontrim.Config{FieldLimit: 100, TotalLimit: 300, TruncateStrings: true, ParseEmbedded: true}))
+}
+
+func attrs() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int64("http.status_code", 200),
+		attribute.String("request.body", `{"user":"u","blob":"`+strings.Repeat("b", 200)+`"}`),
+		attribute.Int64Slice("ids", make([]int64, 200)),
+		attribute.ByteSlice("raw", []byte(strings.Repeat("r", 400))),
+	}
+}
+
+func check(t *testing.T, got []attribute.KeyValue) {
+	t.Helper()
+	byKey := map[attribute.Key]attribute.Value{}
+	for _, kv := range got {
+		byKey[kv.Key] = kv.Value
+	}
+	if byKey["http.status_code"].AsInt64() != 200 {
+		t.Errorf("Expected http.status_code kept, got %v", got)
+	}
+	if body := byKey["request.body"].AsString(); !strings.Contains(body, `"user":"u"`) || len(body) > 100 {
+		t.Errorf("Expected request.body trimmed as JSON, got %q", body)
+	}
+	if ids := byKey["ids"]; ids.Type() == attribute.INT64SLICE && len(ids.AsInt64Slice()) == 200 {
+		t.Error("Expected ids dropped or shortened")
+	}
+	if byKey["raw"].Type() != attribute.BYTESLICE {
+		t.Error("Byte slices should be passed on unchanged")
+	}
+}
+
+func TestSpanProcessor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(NewSpanProcessor(recorder, limiter())))
+	_, span := tp.Tracer("test").Start(context.Background(), "op")
+	span.SetAttributes(attrs()...)
+	span.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(ended))
+	}
+	check(t, ended[0].Attributes())
+	if kept := len(ended[0].Attributes()); ended[0].DroppedAttributes() != len(attrs())-kept {
+		t.Errorf("DroppedAttributes %d, want %d", ended[0].DroppedAttributes(), len(attrs())-kept)
+	}
+}
+
+// recorder is a log Processor keeping the attributes of each record.
+type recorder struct {
+	sdklog.Processor
+	emitted [][]attribute.KeyValue
+}
+
+func (r *recorder) OnEmit(_ context.Context, record *sdklog.Record) error {
+	var attrs []attribute.KeyValue
+	record.WalkAttributes(func(kv attribute.KeyValue) bool {
+		attrs = append(attrs, kv)
+		return true
+	})
+	r.emitted = append(r.emitted, attrs)
+	return nil
+}
+
+func TestLogProcessor(t *testing.T) {
+	rec := &recorder{Processor: NewLogProcessor(nil)}
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(NewLogProcessor(limiter())), sdklog.WithProcessor(rec))
+	var r log.Record
+	r.AddAttributes(attrs()...)
+	lp.Logger("test").Emit(context.Background(), r)
+
+	if len(rec.emitted) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(rec.emitted))
+	}
+	check(t, rec.emitted[0])
+}
@@ -0,0 +1,54 @@
+package jsontrim
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OversizePolicy selects what happens to a document over TotalLimit.
+type OversizePolicy int
+
+const (
+	// OversizeTrim trims the document down to TotalLimit (default).
+	OversizeTrim OversizePolicy = iota
+	// OversizeDrop returns no output and an error wrapping ErrOversize.
+	OversizeDrop
+	// OversizeStub replaces the whole document with a small summary object,
+	// {"_oversize":{"size":N,"limit":L}} (see StubKey).
+	OversizeStub
+	// OversizePassThrough keeps the document whole, over TotalLimit. Keys,
+	// Blacklist, Whitelist, DropIf and the hooks still apply.
+	OversizePassThrough
+)
+
+// StubKey is the field of the summary object OversizeStub puts in place of an
+// oversized document.
+const StubKey = "_oversize"
+
+// ErrOversize indicates a document over TotalLimit rejected by OversizeDrop.
+var ErrOversize = errors.New("document over size limit")
+
+// applyOversize applies OversizePolicy to *v once blacklisted paths are
+// stripped. It reports whether the policy handled the document, in which case
+// no limits are enforced on it.
+func (t *Trimmer) applyOversize(v *interface{}) (bool, error) {
+	if t.cfg.OversizePolicy == OversizeTrim {
+		return false, nil
+	}
+	size, err := t.totalSize(*v)
+	if err != nil {
+		return false, err
+	}
+	if size <= t.cfg.TotalLimit {
+		return false, nil
+	}
+
+	switch t.cfg.OversizePolicy {
+	case OversizeDrop:
+		return true, fmt.Errorf("%w: %d > %d", ErrOversize, size, t.cfg.TotalLimit)
+	case OversizeStub:
+		t.record(nil, reasonTotalLimit, *v)
+		*v = map[string]interface{}{StubKey: map[string]interface{}{"size": size, "limit": t.cfg.TotalLimit}}
+	}
+	return true, nil
+}
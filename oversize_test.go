@@ -0,0 +1,32 @@
+package jsontrim
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOversizePolicy(t *testing.T) {
+	big := []byte(`{"secret":"s","a":"` + strings.Repeat("x", 200) + `"}`)
+	small := []byte(`{"a":"x"}`)
+	cfg := Config{TotalLimit: 100, Blacklist: []string{"secret"}}
+
+	cfg.OversizePolicy = OversizeDrop
+	if out, err := New(cfg).Trim(big); out != nil || !errors.Is(err, ErrOversize) {
+		t.Errorf("Expected ErrOversize, got %s, %v", out, err)
+	}
+	if out, err := New(cfg).Trim(small); err != nil || string(out) != `{"a":"x"}` {
+		t.Errorf("Expected small documents trimmed as usual, got %s, %v", out, err)
+	}
+
+	cfg.OversizePolicy = OversizeStub
+	if out, _ := New(cfg).Trim(big); string(out) != `{"_oversize":{"limit":100,"size":208}}` {
+		t.Errorf("Expected a stub, got %s", out)
+	}
+
+	cfg.OversizePolicy = OversizePassThrough
+	out, err := New(cfg).Trim(big)
+	if err != nil || len(out) != 208 || strings.Contains(string(out), "secret") {
+		t.Errorf("Expected the whole document minus the blacklist, got %s, %v", out, err)
+	}
+}
@@ -0,0 +1,253 @@
+package jsontrim
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Pass is one stage of Config.Passes. Run shrinks v, stopping once it has
+// done all it can or the document fits, and returns the result.
+type Pass interface {
+	Run(v interface{}, b PassBudget) interface{}
+}
+
+// PassBudget is what a Pass sees of the trim it runs in.
+type PassBudget interface {
+	// Over returns how far v is over TotalLimit, in Unit or SizeFunc's
+	// measure; <= 0 means it fits.
+	Over(v interface{}) int
+	// Protected reports whether path must stay: it's Required or holds
+	// Required fields.
+	Protected(path []string) bool
+	// Removed records a removal for TrimResult and the audit log.
+	Removed(path []string, original interface{})
+	// Size ranks v against other candidates: Config.SizeFunc, or a byte
+	// estimate.
+	Size(v interface{}) int
+}
+
+// passBudget implements PassBudget for a Trimmer.
+type passBudget struct{ t *Trimmer }
+
+func (b passBudget) Over(v interface{}) int {
+	size, err := b.t.totalSize(v)
+	if err != nil {
+		return 0
+	}
+	return size - b.t.cfg.TotalLimit
+}
+
+func (b passBudget) Protected(path []string) bool { return b.t.protects(path) }
+
+func (b passBudget) Removed(path []string, original interface{}) {
+	b.t.record(path, reasonTotalLimit, original)
+}
+
+func (b passBudget) Size(v interface{}) int { return b.t.estimate(v) }
+
+// runPasses runs Config.Passes in order while the document is over budget.
+func (t *Trimmer) runPasses(v interface{}) interface{} {
+	b := passBudget{t}
+	for _, p := range t.cfg.Passes {
+		if t.expired() || b.Over(v) <= 0 {
+			break
+		}
+		v = p.Run(v, b)
+	}
+	return v
+}
+
+// NullsPass removes null values at any depth.
+type NullsPass struct{}
+
+// Run implements Pass.
+func (NullsPass) Run(v interface{}, b PassBudget) interface{} {
+	var walk func(v interface{}, path []string) interface{}
+	walk = func(v interface{}, path []string) interface{} {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			for k, val := range vv {
+				childPath := append(path, k)
+				if val == nil && !b.Protected(childPath) {
+					delete(vv, k)
+					b.Removed(childPath, nil)
+					continue
+				}
+				vv[k] = walk(val, childPath)
+			}
+		case []interface{}:
+			out := vv[:0]
+			for i, item := range vv {
+				childPath := append(path, strconv.Itoa(i))
+				if item == nil && !b.Protected(childPath) {
+					b.Removed(childPath, nil)
+					continue
+				}
+				out = append(out, walk(item, childPath))
+			}
+			return out
+		}
+		return v
+	}
+	return walk(v, nil)
+}
+
+// PrefixPass removes top-level keys starting with one of Prefixes (e.g.,
+// "debug." or "debug.*"), largest first, until the document fits.
+type PrefixPass struct {
+	Prefixes []string
+}
+
+// Run implements Pass.
+func (p PrefixPass) Run(v interface{}, b PassBudget) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	noise := NoiseFirst{Prefixes: p.Prefixes}
+	for b.Over(m) > 0 {
+		candidates := make(map[string]interface{})
+		for k, val := range m {
+			if noise.isNoise(k) && !b.Protected([]string{k}) {
+				candidates[k] = val
+			}
+		}
+		k := RemoveLargest{}.SelectNextToRemove(candidates)
+		if k == "" {
+			break
+		}
+		b.Removed([]string{k}, m[k])
+		delete(m, k)
+	}
+	return m
+}
+
+// SamplePass thins arrays at any depth evenly (see Sample), always taking
+// from the largest array left, until the document fits. Arrays with MinItems
+// items or fewer are left alone.
+type SamplePass struct {
+	MinItems int // (default: 2)
+}
+
+// Run implements Pass.
+func (p SamplePass) Run(v interface{}, b PassBudget) interface{} {
+	minItems := max(p.MinItems, 2)
+	for b.Over(v) > 0 {
+		path := largestArray(v, nil, minItems, b)
+		if path == nil {
+			break
+		}
+		arr := nodeAt(v, path).([]interface{})
+		idx, _ := strconv.Atoi(strings.TrimPrefix(Sample{}.SelectNextToRemove(arr), "idx:"))
+		b.Removed(append(path, strconv.Itoa(idx)), arr[idx])
+		v = replaceAt(v, path, append(arr[:idx:idx], arr[idx+1:]...))
+	}
+	return v
+}
+
+// StrategyPass removes top-level fields or items with Strategy until the
+// document fits, the way TotalLimit enforcement does.
+type StrategyPass struct {
+	Strategy TruncStrategy
+}
+
+// Run implements Pass.
+func (p StrategyPass) Run(v interface{}, b PassBudget) interface{} {
+	for {
+		over := b.Over(v)
+		if over <= 0 {
+			return v
+		}
+		candidates := v
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			m := make(map[string]interface{}, len(vv))
+			for k, val := range vv {
+				if !b.Protected([]string{k}) {
+					m[k] = val
+				}
+			}
+			candidates = m
+		case []interface{}:
+		default:
+			return v
+		}
+		s := p.Strategy
+		if pb, ok := b.(passBudget); ok {
+			s = pb.t.sized(s)
+		}
+		sel := selectWithBudget(s, candidates, over)
+		if sel == "" {
+			return v
+		}
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			b.Removed([]string{sel}, vv[sel])
+			delete(vv, sel)
+		case []interface{}:
+			idx, err := strconv.Atoi(strings.TrimPrefix(sel, "idx:"))
+			if err != nil || idx < 0 || idx >= len(vv) || b.Protected([]string{strconv.Itoa(idx)}) {
+				return v
+			}
+			b.Removed([]string{strconv.Itoa(idx)}, vv[idx])
+			v = append(vv[:idx:idx], vv[idx+1:]...)
+		}
+	}
+}
+
+// largestArray returns the path of the largest unprotected array under v
+// with more than minItems items, or nil if there is none.
+func largestArray(v interface{}, path []string, minItems int, b PassBudget) []string {
+	var best []string
+	bestSize, found := 0, false
+	var walk func(v interface{}, path []string)
+	walk = func(v interface{}, path []string) {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			for k, val := range vv {
+				walk(val, append(path, k))
+			}
+		case []interface{}:
+			if len(vv) > minItems && !b.Protected(path) {
+				if size := b.Size(vv); !found || size > bestSize {
+					best, bestSize, found = append([]string{}, path...), size, true
+				}
+			}
+			for i, item := range vv {
+				walk(item, append(path, strconv.Itoa(i)))
+			}
+		}
+	}
+	walk(v, path)
+	return best
+}
+
+// nodeAt returns the node at path under v.
+func nodeAt(v interface{}, path []string) interface{} {
+	for _, seg := range path {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			v = vv[seg]
+		case []interface{}:
+			idx, _ := strconv.Atoi(seg)
+			v = vv[idx]
+		}
+	}
+	return v
+}
+
+// replaceAt sets the node at path under v to repl and returns the new root.
+func replaceAt(v interface{}, path []string, repl interface{}) interface{} {
+	if len(path) == 0 {
+		return repl
+	}
+	parent := nodeAt(v, path[:len(path)-1])
+	switch pv := parent.(type) {
+	case map[string]interface{}:
+		pv[path[len(path)-1]] = repl
+	case []interface{}:
+		idx, _ := strconv.Atoi(path[len(path)-1])
+		pv[idx] = repl
+	}
+	return v
+}
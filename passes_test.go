@@ -0,0 +1,58 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPasses(t *testing.T) {
+	items := make([]interface{}, 20)
+	for i := range items {
+		items[i] = strings.Repeat("i", 20)
+	}
+	doc := map[string]interface{}{
+		"id": 1, "gone": nil, "debug.sql": strings.Repeat("q", 200),
+		"items": items, "body": strings.Repeat("b", 100),
+	}
+	raw, _ := json.Marshal(doc)
+
+	cfg := Config{TotalLimit: 400, FieldLimit: 4096, Passes: []Pass{
+		NullsPass{}, PrefixPass{Prefixes: []string{"debug.*"}}, SamplePass{}, StrategyPass{Strategy: RemoveLargest{}},
+	}}
+	out, res, err := New(cfg).TrimWithResult(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 400 {
+		t.Fatalf("Expected at most 400 bytes, got %d", len(out))
+	}
+	var got map[string]interface{}
+	json.Unmarshal(out, &got)
+	if _, ok := got["gone"]; ok {
+		t.Error("Expected the null dropped")
+	}
+	if _, ok := got["debug.sql"]; ok {
+		t.Error("Expected the debug field dropped")
+	}
+	kept, _ := got["items"].([]interface{})
+	if got["body"] == nil || got["id"] != 1.0 || len(kept) == 0 || len(kept) >= 20 {
+		t.Errorf("Expected items sampled and the rest kept, got %v", got)
+	}
+	if res.PathsAffected[0] != "gone" || res.PathsAffected[1] != "debug.sql" {
+		t.Errorf("Expected passes to run in order, got %v", res.PathsAffected)
+	}
+}
+
+func TestPassesStopUnderBudget(t *testing.T) {
+	raw := []byte(`{"a":null,"debug.x":"` + strings.Repeat("x", 50) + `","id":1}`)
+	out, err := New(Config{TotalLimit: 80, Passes: []Pass{NullsPass{}, PrefixPass{Prefixes: []string{"debug."}}}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(out, &got)
+	if _, ok := got["debug.x"]; !ok {
+		t.Errorf("Expected later passes skipped once under budget, got %s", out)
+	}
+}
@@ -0,0 +1,255 @@
+package jsontrim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrInvalidPolicy indicates a policy document that can't be compiled.
+	ErrInvalidPolicy = errors.New("invalid policy")
+	// ErrPolicyNotFound indicates a PolicyStore lookup for an unknown policy or version.
+	ErrPolicyNotFound = errors.New("policy not found")
+)
+
+// Policy is the declarative form of a Config, so redaction rules can live in
+// a JSON document owned outside the code:
+//
+//	{
+//	  "name": "payments",
+//	  "total_limit": 4096,
+//	  "blacklist": ["card.number", "*.cvv"],
+//	  "limits": {"request.headers": 1024},
+//	  "marker": true,
+//	  "transformers": [{"type": "mask_email"}, {"type": "stack_trace", "frames": 3}]
+//	}
+//
+// Zero or missing fields keep the usual Config defaults.
+type Policy struct {
+	Name            string            `json:"name"`
+	FieldLimit      int               `json:"field_limit"`
+	TotalLimit      int               `json:"total_limit"`
+	MaxDepth        int               `json:"max_depth"`
+	Blacklist       []string          `json:"blacklist"`
+	Whitelist       []string          `json:"whitelist"`
+	Required        []string          `json:"required"`
+	Atomic          []string          `json:"atomic"`
+	Limits          map[string]int    `json:"limits"` // SubtreeLimits
+	DropIf          map[string]string `json:"drop_if"`
+	Rename          map[string]string `json:"rename"`
+	Marker          bool              `json:"marker"` // ReplaceWithMarker
+	TruncateStrings bool              `json:"truncate_strings"`
+	PreserveURLs    bool              `json:"preserve_urls"`
+	PruneEmpty      bool              `json:"prune_empty"`
+	Strategy        string            `json:"strategy"` // "largest" (default) or "fifo"
+	Transformers    []TransformerSpec `json:"transformers"`
+}
+
+// TransformerSpec names a built-in transformer and its settings. Type is one
+// of "stack_trace", "base64_blob", "mask_email" or "anonymize_ip".
+type TransformerSpec struct {
+	Type    string   `json:"type"`
+	Paths   []string `json:"paths"`    // mask_email, anonymize_ip
+	Frames  int      `json:"frames"`   // stack_trace
+	MinSize int      `json:"min_size"` // base64_blob
+	Format  string   `json:"format"`   // base64_blob
+}
+
+// PolicyBundle is a versioned set of named policies, the unit a PolicyStore
+// loads:
+//
+//	{"version": "2024-05-01", "policies": [{"name": "payments", ...}, ...]}
+type PolicyBundle struct {
+	Version  string   `json:"version"`
+	Policies []Policy `json:"policies"`
+}
+
+// PolicyStore holds compiled policy bundles by version. Loading a bundle is
+// all-or-nothing: readers see either the old set or the new one, never a
+// mix. It is safe for concurrent use.
+type PolicyStore struct {
+	mu       sync.RWMutex
+	versions map[string]map[string]*Trimmer // version -> policy name -> Trimmer
+	latest   string
+}
+
+// NewPolicyStore returns an empty PolicyStore.
+func NewPolicyStore() *PolicyStore {
+	return &PolicyStore{versions: map[string]map[string]*Trimmer{}}
+}
+
+// Load compiles the bundle read from r and adds it, replacing any bundle with
+// the same version. It becomes the latest version. Load returns the bundle's
+// version.
+func (s *PolicyStore) Load(r io.Reader) (string, error) {
+	version, trimmers, err := compileBundle(r)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.versions[version] = trimmers
+	s.latest = version
+	s.mu.Unlock()
+	return version, nil
+}
+
+// LoadFile is Load for a bundle on disk.
+func (s *PolicyStore) LoadFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return s.Load(f)
+}
+
+// Replace compiles the bundle read from r and swaps it in for every loaded
+// version at once. On error the store is left unchanged.
+func (s *PolicyStore) Replace(r io.Reader) (string, error) {
+	version, trimmers, err := compileBundle(r)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.versions = map[string]map[string]*Trimmer{version: trimmers}
+	s.latest = version
+	s.mu.Unlock()
+	return version, nil
+}
+
+// Get returns the Trimmer for the named policy in the given bundle version.
+// An empty version means the most recently loaded bundle.
+func (s *PolicyStore) Get(name, version string) (*Trimmer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if version == "" {
+		version = s.latest
+	}
+	if t, ok := s.versions[version][name]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: %q version %q", ErrPolicyNotFound, name, version)
+}
+
+// Versions returns the loaded bundle versions, sorted.
+func (s *PolicyStore) Versions() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := make([]string, 0, len(s.versions))
+	for v := range s.versions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// compileBundle decodes a PolicyBundle and compiles each of its policies.
+func compileBundle(r io.Reader) (string, map[string]*Trimmer, error) {
+	var b PolicyBundle
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&b); err != nil {
+		return "", nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+	if b.Version == "" {
+		return "", nil, fmt.Errorf("%w: bundle has no version", ErrInvalidPolicy)
+	}
+	trimmers := make(map[string]*Trimmer, len(b.Policies))
+	for _, p := range b.Policies {
+		if p.Name == "" {
+			return "", nil, fmt.Errorf("%w: bundle %q: policy has no name", ErrInvalidPolicy, b.Version)
+		}
+		if _, dup := trimmers[p.Name]; dup {
+			return "", nil, fmt.Errorf("%w: bundle %q: duplicate policy %q", ErrInvalidPolicy, b.Version, p.Name)
+		}
+		t, err := p.compile()
+		if err != nil {
+			return "", nil, err
+		}
+		trimmers[p.Name] = t
+	}
+	return b.Version, trimmers, nil
+}
+
+// CompilePolicy parses a JSON policy document and returns a Trimmer for it.
+// Unknown fields, strategies and transformer types are rejected.
+func CompilePolicy(doc []byte) (*Trimmer, error) {
+	var p Policy
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+	return p.compile()
+}
+
+// compile builds the Trimmer for p.
+func (p Policy) compile() (*Trimmer, error) {
+	cfg, err := p.Config()
+	if err != nil {
+		return nil, err
+	}
+	t, err := (&Builder{cfg: cfg}).Build()
+	if err != nil {
+		return nil, fmt.Errorf("%w: policy %q: %w", ErrInvalidPolicy, p.Name, err)
+	}
+	return t, nil
+}
+
+// Config converts the policy into a Config.
+func (p Policy) Config() (Config, error) {
+	cfg := Config{
+		FieldLimit:        p.FieldLimit,
+		TotalLimit:        p.TotalLimit,
+		MaxDepth:          p.MaxDepth,
+		Blacklist:         p.Blacklist,
+		Whitelist:         p.Whitelist,
+		Required:          p.Required,
+		Atomic:            p.Atomic,
+		SubtreeLimits:     p.Limits,
+		DropIf:            p.DropIf,
+		Rename:            p.Rename,
+		ReplaceWithMarker: p.Marker,
+		TruncateStrings:   p.TruncateStrings,
+		PreserveURLs:      p.PreserveURLs,
+		PruneEmpty:        p.PruneEmpty,
+	}
+
+	switch p.Strategy {
+	case "", "largest":
+		cfg.Strategy = RemoveLargest{}
+	case "fifo":
+		cfg.Strategy = FIFO{}
+	default:
+		return Config{}, fmt.Errorf("%w: policy %q: unknown strategy %q", ErrInvalidPolicy, p.Name, p.Strategy)
+	}
+
+	for i, spec := range p.Transformers {
+		tf, err := spec.transformer()
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: policy %q: transformer %d: %w", ErrInvalidPolicy, p.Name, i, err)
+		}
+		cfg.Transformers = append(cfg.Transformers, tf)
+	}
+	return cfg, nil
+}
+
+func (s TransformerSpec) transformer() (Transformer, error) {
+	switch s.Type {
+	case "stack_trace":
+		return StackTrace{Frames: s.Frames}, nil
+	case "base64_blob":
+		return Base64Blob{MinSize: s.MinSize, Format: s.Format}, nil
+	case "mask_email":
+		return MaskEmail{Paths: s.Paths}, nil
+	case "anonymize_ip":
+		return AnonymizeIP{Paths: s.Paths}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", s.Type)
+}
@@ -0,0 +1,80 @@
+package jsontrim
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompilePolicy(t *testing.T) {
+	doc := []byte(`{
+		"name": "payments",
+		"total_limit": 2048,
+		"blacklist": ["card.number"],
+		"limits": {"notes": 20},
+		"marker": true,
+		"truncate_strings": true,
+		"transformers": [{"type": "mask_email", "paths": ["user.email"]}]
+	}`)
+	trimmer, err := CompilePolicy(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := trimmer.Trim([]byte(`{"card":{"number":"4111"},"user":{"email":"jane@example.com"},"notes":"` + strings.Repeat("n", 50) + `"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"card":{"number":"[TRIMMED]"},"notes":"nnnnnnnnnnnnnnn...","user":{"email":"j***@example.com"}}`
+	if string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
+
+func TestCompilePolicyErrors(t *testing.T) {
+	for _, doc := range []string{
+		`{"blacklist": "not-a-list"}`,
+		`{"blaklist": ["typo"]}`,
+		`{"strategy": "random"}`,
+		`{"transformers": [{"type": "rot13"}]}`,
+		`{"limits": {"body": -1}}`,
+	} {
+		if _, err := CompilePolicy([]byte(doc)); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("CompilePolicy(%s): expected ErrInvalidPolicy, got %v", doc, err)
+		}
+	}
+}
+
+func TestPolicyStore(t *testing.T) {
+	store := NewPolicyStore()
+	if _, err := store.Load(strings.NewReader(`{"version":"v1","policies":[{"name":"logs","blacklist":["token"]}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(strings.NewReader(`{"version":"v2","policies":[{"name":"logs","blacklist":["token","ip"]}]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := []byte(`{"ip":"1.2.3.4","token":"x"}`)
+	for version, want := range map[string]string{"v1": `{"ip":"1.2.3.4"}`, "v2": `{}`, "": `{}`} {
+		trimmer, err := store.Get("logs", version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out, _ := trimmer.Trim(raw); string(out) != want {
+			t.Errorf("version %q: got %s, want %s", version, out, want)
+		}
+	}
+
+	// A bad bundle leaves the store as it was
+	if _, err := store.Replace(strings.NewReader(`{"version":"v3","policies":[{"name":"logs"},{"name":"logs"}]}`)); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("Expected ErrInvalidPolicy for duplicate names, got %v", err)
+	}
+	if _, err := store.Replace(strings.NewReader(`{"version":"v3","policies":[{"name":"audit"}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.Versions(); len(got) != 1 || got[0] != "v3" {
+		t.Errorf("Replace should drop old versions, got %v", got)
+	}
+	if _, err := store.Get("logs", "v1"); !errors.Is(err, ErrPolicyNotFound) {
+		t.Errorf("Expected ErrPolicyNotFound, got %v", err)
+	}
+}
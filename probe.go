@@ -0,0 +1,213 @@
+package jsontrim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotObject indicates a document whose top-level value isn't an object.
+var ErrNotObject = errors.New("top-level value is not an object")
+
+// ScanFields walks the top-level fields of the JSON object in raw without
+// decoding or allocating, calling fn with each raw key (without quotes,
+// escapes left as is) and raw value, until fn returns false. len(value) is
+// the field's encoded size as given, so strategy decisions for large flat
+// documents (which fields are largest, how many must go) can be made before
+// anything is materialized. key and value alias raw. Trim uses it to drop
+// oversized top-level strings before decoding (see dropLongFields).
+//
+// String contents are skipped with bytes.IndexByte, which the runtime
+// vectorizes on amd64 and arm64. Input is checked only as far as the scan
+// needs.
+func ScanFields(raw []byte, fn func(key, value []byte) bool) error {
+	i := skipSpace(raw, 0)
+	if i >= len(raw) || raw[i] != '{' {
+		return ErrNotObject
+	}
+	if i = skipSpace(raw, i+1); i < len(raw) && raw[i] == '}' {
+		return nil
+	}
+	for {
+		if i >= len(raw) || raw[i] != '"' {
+			return malformedAt(i)
+		}
+		end, err := skipString(raw, i)
+		if err != nil {
+			return err
+		}
+		key := raw[i+1 : end-1]
+		if i = skipSpace(raw, end); i >= len(raw) || raw[i] != ':' {
+			return malformedAt(i)
+		}
+		i = skipSpace(raw, i+1)
+		vend, err := skipJSONValue(raw, i)
+		if err != nil {
+			return err
+		}
+		if !fn(key, raw[i:vend]) {
+			return nil
+		}
+		if i = skipSpace(raw, vend); i >= len(raw) {
+			return malformedAt(i)
+		}
+		switch raw[i] {
+		case ',':
+			i = skipSpace(raw, i+1)
+		case '}':
+			return nil
+		default:
+			return malformedAt(i)
+		}
+	}
+}
+
+func malformedAt(i int) error {
+	return fmt.Errorf("malformed JSON at offset %d", i)
+}
+
+func skipSpace(raw []byte, i int) int {
+	for i < len(raw) && (raw[i] == ' ' || raw[i] == '\t' || raw[i] == '\n' || raw[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// skipString returns the offset just past the string starting at raw[i].
+func skipString(raw []byte, i int) (int, error) {
+	for j := i + 1; ; j++ {
+		k := bytes.IndexByte(raw[j:], '"')
+		if k < 0 {
+			return 0, malformedAt(i)
+		}
+		j += k
+		// The quote is escaped if an odd number of backslashes precede it
+		n := 0
+		for p := j - 1; p > i && raw[p] == '\\'; p-- {
+			n++
+		}
+		if n%2 == 0 {
+			return j + 1, nil
+		}
+	}
+}
+
+// skipJSONValue returns the offset just past the value starting at raw[i].
+func skipJSONValue(raw []byte, i int) (int, error) {
+	if i >= len(raw) {
+		return 0, malformedAt(i)
+	}
+	switch raw[i] {
+	case '"':
+		return skipString(raw, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(raw); j++ {
+			switch raw[j] {
+			case '"':
+				end, err := skipString(raw, j)
+				if err != nil {
+					return 0, err
+				}
+				j = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+		return 0, malformedAt(i)
+	}
+	j := i
+	for j < len(raw) && strings.IndexByte(",}] \t\n\r", raw[j]) < 0 {
+		j++
+	}
+	if j == i {
+		return 0, malformedAt(i)
+	}
+	return j, nil
+}
+
+// scansFields reports whether cfg lets dropLongFields drop oversized top-level
+// strings from the input before decoding: nothing that runs ahead of
+// FieldLimit (hooks, Transformers, key rewrites, queries, DropIf) could shrink
+// or select on them, FieldLimit removes them rather than truncating or
+// marking them, and no SizeFunc measures them. cfg is taken before build
+// fills in its hooks.
+func scansFields(cfg *Config) bool {
+	if _, ok := cfg.Codec.(JSONCodec); cfg.Codec != nil && !ok {
+		return false
+	}
+	for _, p := range append(cfg.Blacklist[:len(cfg.Blacklist):len(cfg.Blacklist)], cfg.Whitelist...) {
+		if isQuery(p) {
+			return false
+		}
+	}
+	return cfg.Hooks.PreTrim == nil && cfg.Hooks.BeforeTrim == nil && cfg.Hooks.OnLimitExceeded == nil &&
+		len(cfg.Transformers) == 0 && len(cfg.FieldHooks) == 0 && len(cfg.DropIf) == 0 && !cfg.Keys.enabled() &&
+		!cfg.TruncateStrings && !cfg.ReplaceWithMarker && !cfg.ParseEmbedded && !cfg.StripControlChars &&
+		!cfg.ProtectTimestamps && len(cfg.TimestampPaths) == 0 && cfg.OversizePolicy == OversizeTrim &&
+		cfg.SizeFunc == nil && (cfg.MaxDepth == 0 || cfg.MaxDepth >= 2)
+}
+
+// dropLongFields returns raw without the top-level string fields FieldLimit
+// would remove anyway, found with ScanFields so they're never decoded. Only
+// strings without escapes qualify: their encoded size is at least their raw
+// size. Keys that are Required, stripped by the Blacklist or Whitelist, or
+// repeated are left to the normal path. raw is returned as is if nothing is
+// dropped.
+func (t *Trimmer) dropLongFields(raw []byte) []byte {
+	if len(raw) <= t.cfg.FieldLimit {
+		return raw
+	}
+	// offset returns where b, a slice of raw, starts in it
+	offset := func(b []byte) int { return cap(raw) - cap(b) }
+	var long [][2][]byte // Key and value of each field to drop
+	escaped := false
+	err := ScanFields(raw, func(key, value []byte) bool {
+		if bytes.IndexByte(key, '\\') >= 0 {
+			escaped = true // Keys written differently may repeat
+			return false
+		}
+		if value[0] != '"' || t.measure(value) <= t.cfg.FieldLimit || bytes.IndexByte(value, '\\') >= 0 {
+			return true
+		}
+		path := []string{string(key)}
+		if _, blacklisted := t.blacklistRule(path); blacklisted || !t.whitelisted(path) || t.protects(path) {
+			return true
+		}
+		long = append(long, [2][]byte{key, value})
+		return true
+	})
+	if err != nil || len(long) == 0 || escaped {
+		return raw // Malformed input is left for the decoder to report
+	}
+
+	out := append(make([]byte, 0, len(raw)), '{')
+	next, repeated := 0, false
+	err = ScanFields(raw, func(key, value []byte) bool {
+		for i, l := range long {
+			if bytes.Equal(l[0], key) && (i != next || offset(key) != offset(l[0])) {
+				repeated = true // Which value a repeated key keeps is up to the decoder
+				return false
+			}
+		}
+		if next < len(long) && offset(key) == offset(long[next][0]) {
+			next++
+			return true
+		}
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+		out = append(out, raw[offset(key)-1:offset(value)+len(value)]...) // From the key's opening quote
+		return true
+	})
+	if err != nil || repeated {
+		return raw
+	}
+	t.longFields = long // Recorded with the other FieldLimit removals, in trimFields' phase
+	return append(out, '}')
+}
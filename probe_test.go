@@ -0,0 +1,90 @@
+package jsontrim
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestScanFields(t *testing.T) {
+	raw := []byte(`{ "a" : "x\"y" , "b":[1,{"c":"]"}], "d\\":true,"e":-1.5e3 }`)
+	want := map[string]string{"a": `"x\"y"`, "b": `[1,{"c":"]"}]`, `d\\`: "true", "e": "-1.5e3"}
+	got := map[string]string{}
+	if err := ScanFields(raw, func(k, v []byte) bool {
+		got[string(k)] = string(v)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Expected %s for %s, got %s", v, k, got[k])
+		}
+	}
+
+	largest := 0
+	allocs := testing.AllocsPerRun(10, func() {
+		ScanFields(raw, func(_, v []byte) bool {
+			largest = max(largest, len(v))
+			return true
+		})
+	})
+	if allocs != 0 || largest != 13 {
+		t.Errorf("Expected an allocation-free scan finding 13, got %v allocs and %d", allocs, largest)
+	}
+
+	if err := ScanFields([]byte(`[1]`), func(_, _ []byte) bool { return true }); !errors.Is(err, ErrNotObject) {
+		t.Errorf("Expected ErrNotObject, got %v", err)
+	}
+	if err := ScanFields([]byte(`{"a":"open`), func(_, _ []byte) bool { return true }); err == nil {
+		t.Error("Expected an error for an unterminated string")
+	}
+}
+
+func TestDropLongFields(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	docs := []string{
+		`{"id":1, "body":"` + long + `","tags":["` + long + `"],"name":"n"}`,
+		`{"body":"` + long + `","body":"short"}`,
+		`{"body":"short","body":"` + long + `"}`,
+		`{"body":"` + long + `","body":"short"}`,
+		`{"esc":"\n` + long + `","token":"` + long + `","msg":"` + long + `"}`,
+		`[1,"` + long + `"]`,
+		`{"a":"` + long + `"`,
+	}
+	for i, cfg := range []Config{
+		{FieldLimit: 100},
+		{FieldLimit: 100, TotalLimit: 50, Unit: UTF16},
+		{FieldLimit: 100, Required: []string{"msg"}, Blacklist: []string{"token"}},
+		{FieldLimit: 100, Whitelist: []string{"body", "id"}, StopAtLimit: true},
+		{FieldLimit: 100, Codec: JSONCodec{DuplicateKeys: FirstWins}},
+	} {
+		if !New(cfg).scansFields {
+			t.Fatalf("Expected %+v to drop long fields before decoding", cfg)
+		}
+		for _, doc := range docs {
+			got, res, err := New(cfg).TrimWithResult([]byte(doc))
+			cfg := cfg
+			cfg.Hooks.OnLimitExceeded = func([]byte, int) {} // Disables the scan
+			want, wantRes, wantErr := New(cfg).TrimWithResult([]byte(doc))
+			sort.Strings(res.PathsAffected)
+			sort.Strings(wantRes.PathsAffected)
+			if string(got) != string(want) || (err == nil) != (wantErr == nil) || !reflect.DeepEqual(res.PathsAffected, wantRes.PathsAffected) {
+				t.Errorf("Config %d, %.40s: got %.60s %v (%v), want %.60s %v (%v)", i, doc, got, res.PathsAffected, err, want, wantRes.PathsAffected, wantErr)
+			}
+		}
+	}
+
+	tr := New(Config{FieldLimit: 100})
+	if out := tr.dropLongFields([]byte(docs[0])); string(out) != `{"id":1,"tags":["`+long+`"],"name":"n"}` {
+		t.Errorf("Expected body dropped before decoding, got %.80s", out)
+	}
+	if out := tr.dropLongFields([]byte(docs[1])); string(out) != docs[1] {
+		t.Error("Expected repeated keys left to the decoder")
+	}
+}
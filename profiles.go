@@ -0,0 +1,126 @@
+package jsontrim
+
+// ProfileSet holds the built-in profiles; use it through Profiles.
+type ProfileSet struct{}
+
+// Profiles are curated starting points for common uses. Each returns a plain
+// Config, so fields can be adjusted before calling New:
+//
+//	cfg := jsontrim.Profiles.Logging()
+//	cfg.Blacklist = []string{"*.password"}
+//	trimmer := jsontrim.New(cfg)
+var Profiles ProfileSet
+
+// Logging trims hard for log pipelines: small fields, a shallow depth, and
+// markers wherever something was cut so readers know the line is incomplete.
+// Stack traces and base64 blobs are condensed first.
+func (ProfileSet) Logging() Config {
+	return Config{
+		FieldLimit:        256,
+		TotalLimit:        4096,
+		MaxDepth:          6,
+		Strategy:          RemoveLargest{},
+		TruncateStrings:   true,
+		ReplaceWithMarker: true,
+		PreserveURLs:      true,
+		Transformers:      []Transformer{StackTrace{Frames: 5}, Base64Blob{}},
+	}
+}
+
+// Analytics keeps the shape of the data for aggregation: arrays are thinned
+// to an even sample instead of losing their tail, numeric fields are never
+// removed (strings and containers go instead, even if they hold numbers),
+// and containers emptied by trimming are pruned.
+func (ProfileSet) Analytics() Config {
+	return Config{
+		FieldLimit:      1024,
+		TotalLimit:      16384,
+		MaxDepth:        10,
+		Strategy:        Sample{KeepNumbers: true},
+		TruncateStrings: true,
+		PruneEmpty:      true,
+	}
+}
+
+// Debug keeps as much as possible: generous limits, a deep MaxDepth, and
+// long strings truncated rather than dropped.
+func (ProfileSet) Debug() Config {
+	return Config{
+		FieldLimit:        4096,
+		TotalLimit:        65536,
+		MaxDepth:          32,
+		Strategy:          FIFO{},
+		TruncateStrings:   true,
+		ReplaceWithMarker: true,
+		PreserveURLs:      true,
+	}
+}
+
+const (
+	// CloudLoggingEntryLimit is the maximum size of a Google Cloud Logging
+	// LogEntry.
+	CloudLoggingEntryLimit = 256 << 10
+	// cloudLoggingOverhead is room for the rest of the LogEntry around
+	// jsonPayload: logName, resource, timestamp, severity, insertId, trace
+	// and the protobuf framing.
+	cloudLoggingOverhead = 2048
+)
+
+// CloudLogging fits entries under Cloud Logging's 256KB LogEntry limit. The
+// payload budget is what's left after the entry envelope and the given
+// labels, which count towards the limit too. Otherwise it trims like Logging,
+// with fields large enough for request and response bodies.
+func (ProfileSet) CloudLogging(labels map[string]string) Config {
+	budget := CloudLoggingEntryLimit - cloudLoggingOverhead
+	for k, v := range labels {
+		budget -= len(k) + len(v) + 8 // Map entry framing
+	}
+	cfg := Profiles.Logging()
+	cfg.FieldLimit = 32 << 10
+	cfg.TotalLimit = max(budget, 1024)
+	cfg.MaxDepth = 16
+	return cfg
+}
+
+const (
+	// AzureFieldLimit is the largest value Azure Log Analytics stores in a
+	// column; longer values are silently truncated at ingestion.
+	AzureFieldLimit = 32 << 10
+	// AzureRecordLimit is the largest record the Logs Ingestion API accepts.
+	AzureRecordLimit = 1 << 20
+)
+
+// AzureMonitor fits records to Azure Monitor Log Analytics. Every top-level
+// field becomes a column, and Log Analytics cuts any column over 32KB at
+// ingestion, mid-JSON for dynamic columns, so a nested object can arrive
+// unparseable. Trimming columns to just under the limit first keeps each one
+// valid JSON with a marker showing where it was cut: strings are truncated,
+// and objects and arrays lose their largest entries through a "*"
+// SubtreeLimits rule instead of being dropped whole. The record as a whole is
+// capped at AzureRecordLimit.
+func (ProfileSet) AzureMonitor() Config {
+	cfg := Profiles.Logging()
+	cfg.FieldLimit = AzureFieldLimit - 64 // Room for the marker and escaping
+	cfg.TotalLimit = AzureRecordLimit
+	cfg.MaxDepth = 16
+	cfg.SubtreeLimits = map[string]int{"*": cfg.FieldLimit}
+	return cfg
+}
+
+// WideEvents fits wide events (one flat object per unit of work, as sent to
+// Honeycomb) to Honeycomb's limits: 2000 fields, 64KB strings and 1MB
+// events. Fields whose keys start with one of noise (default: "debug.") are
+// dropped before any other, so sampled high-value fields survive.
+func (ProfileSet) WideEvents(noise ...string) Config {
+	if len(noise) == 0 {
+		noise = []string{"debug."}
+	}
+	return Config{
+		FieldLimit:      64 << 10,
+		TotalLimit:      1 << 20,
+		MaxDepth:        10,
+		MaxFields:       2000,
+		Strategy:        NoiseFirst{Prefixes: noise},
+		TruncateStrings: true,
+	}
+}
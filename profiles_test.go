@@ -0,0 +1,112 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	raw := []byte(`{"id":42,"msg":"` + strings.Repeat("m", 5000) + `","n":[1,2,3]}`)
+	for name, cfg := range map[string]Config{
+		"Logging":   Profiles.Logging(),
+		"Analytics": Profiles.Analytics(),
+		"Debug":     Profiles.Debug(),
+	} {
+		if err := validateConfig(cfg); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		out, err := New(cfg).Trim(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got["id"] != 42.0 || !strings.HasSuffix(got["msg"].(string), "...") {
+			t.Errorf("%s: expected id kept and msg truncated, got %s", name, out[:min(len(out), 80)])
+		}
+	}
+}
+
+func TestSampleStrategy(t *testing.T) {
+	items := make([]interface{}, 100)
+	for i := range items {
+		items[i] = float64(i)
+	}
+	raw, _ := json.Marshal(items)
+
+	out, err := New(Config{TotalLimit: 60, Strategy: Sample{}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []float64
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) < 5 || got[0] != 0 || got[len(got)-1] != 99 {
+		t.Fatalf("Expected a sample spanning the array, got %v", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if gap := got[i] - got[i-1]; gap > 2*99/float64(len(got)-1) {
+			t.Errorf("Uneven sample, gap of %v in %v", gap, got)
+		}
+	}
+}
+
+func TestCloudLogging(t *testing.T) {
+	if err := validateConfig(Profiles.CloudLogging(nil)); err != nil {
+		t.Error(err)
+	}
+	bare := Profiles.CloudLogging(nil).TotalLimit
+	labeled := Profiles.CloudLogging(map[string]string{"service": strings.Repeat("s", 1000)}).TotalLimit
+	if bare >= CloudLoggingEntryLimit || labeled > bare-1007 {
+		t.Errorf("Expected envelope and labels subtracted from the limit, got %d and %d", bare, labeled)
+	}
+}
+
+func TestAzureMonitor(t *testing.T) {
+	cfg := Profiles.AzureMonitor()
+	if err := validateConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(map[string]interface{}{
+		"message": strings.Repeat("m", 40<<10),
+		"props":   map[string]interface{}{"a": strings.Repeat("a", 20<<10), "b": strings.Repeat("b", 20<<10)},
+		"level":   "info",
+	})
+	out, err := New(cfg).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	for col, v := range got {
+		if len(v) > AzureFieldLimit {
+			t.Errorf("Column %s is %d bytes, over the %d byte limit", col, len(v), AzureFieldLimit)
+		}
+	}
+	if string(got["level"]) != `"info"` {
+		t.Errorf("Small columns should be untouched, got level %s", got["level"])
+	}
+	var props map[string]interface{}
+	if err := json.Unmarshal(got["props"], &props); err != nil || len(props) != 2 || (props["a"] != Marker && props["b"] != Marker) {
+		t.Errorf("Expected the oversized props column shrunk inside with a marker, got %.80s", got["props"])
+	}
+}
+
+func TestAnalyticsKeepsNumbers(t *testing.T) {
+	raw := []byte(`{"total":123456789012,"avg":3.14159,"note":"short","tags":["a","b"]}`)
+	cfg := Profiles.Analytics()
+	cfg.TotalLimit = 40
+	out, err := New(cfg).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"avg":3.14159,"total":123456789012}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
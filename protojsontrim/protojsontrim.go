@@ -0,0 +1,177 @@
+// Package protojsontrim trims protojson output while respecting proto
+// semantics:
+//
+//   - a populated oneof member is kept whole or removed whole, never trimmed
+//     inside, so the oneof case survives intact or not at all;
+//   - well-known types (Timestamp, Duration, FieldMask, Any's "@type") keep
+//     their canonical formatting instead of being truncated mid-string;
+//   - non-string scalars that protojson renders as strings (int64, bytes,
+//     enums) are never truncated;
+//   - fields can be blacklisted by fully-qualified proto name
+//     ("acme.v1.User.password") instead of JSON path.
+//
+// The package has no protobuf dependency: callers describe the message shape
+// with Message and Field (typically generated once from descriptors), and the
+// package translates it into jsontrim paths.
+package protojsontrim
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// Kind is the proto type category of a field, as far as trimming cares.
+type Kind int
+
+const (
+	// String fields may be truncated like any JSON string.
+	String Kind = iota
+	// Scalar covers numbers, bools, enums and bytes. Values that protojson
+	// renders as strings (int64, enums, base64 bytes) are never truncated.
+	Scalar
+	// MessageKind fields hold a nested message described by Field.Message.
+	MessageKind
+)
+
+// Field describes one field of a message.
+type Field struct {
+	Name     string   // Proto field name, e.g. "user_id"
+	JSONName string   // protojson name (default: lowerCamelCase of Name)
+	Kind     Kind     // Type category
+	Message  *Message // Nested message for MessageKind fields
+	Repeated bool     // Repeated or map field; elements are matched with "*"
+	Oneof    string   // Containing oneof, if any
+}
+
+// Message describes a proto message.
+type Message struct {
+	FullName string // e.g. "acme.v1.User"
+	Fields   []Field
+}
+
+// Options configure New.
+type Options struct {
+	Base      jsontrim.Config // Limits, strategy and other settings to start from
+	Blacklist []string        // Fully-qualified field names, e.g. "acme.v1.User.password"
+}
+
+// wellKnownAtomic are well-known types whose JSON form must not be altered.
+var wellKnownAtomic = map[string]bool{
+	"google.protobuf.Timestamp": true,
+	"google.protobuf.Duration":  true,
+	"google.protobuf.FieldMask": true,
+}
+
+// Config translates the proto rules for root into a jsontrim.Config derived
+// from opts.Base. Recursive messages are expanded up to Base.MaxDepth.
+func Config(root *Message, opts Options) jsontrim.Config {
+	maxDepth := opts.Base.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = 10 // jsontrim's default
+	}
+	b := builder{
+		maxDepth:  maxDepth,
+		blacklist: map[string]bool{},
+		atomic:    map[string]bool{},
+		required:  map[string]bool{},
+		banned:    map[string]bool{},
+	}
+	for _, name := range opts.Blacklist {
+		b.banned[name] = true
+	}
+	b.walk(root, nil)
+
+	cfg := opts.Base
+	cfg.Blacklist = append(append([]string(nil), opts.Base.Blacklist...), sortedKeys(b.blacklist)...)
+	cfg.Atomic = append(append([]string(nil), opts.Base.Atomic...), sortedKeys(b.atomic)...)
+	cfg.Required = append(append([]string(nil), opts.Base.Required...), sortedKeys(b.required)...)
+	return cfg
+}
+
+// New is jsontrim.New(Config(root, opts)).
+func New(root *Message, opts Options) *jsontrim.Trimmer {
+	return jsontrim.New(Config(root, opts))
+}
+
+type builder struct {
+	maxDepth  int
+	banned    map[string]bool // Fully-qualified names to blacklist
+	blacklist map[string]bool
+	atomic    map[string]bool
+	required  map[string]bool
+}
+
+// walk collects rules for the fields of m located at path.
+func (b *builder) walk(m *Message, path []string) {
+	if m == nil || len(path) >= b.maxDepth {
+		return
+	}
+	if m.FullName == "google.protobuf.Any" {
+		// The payload is opaque to us, but "@type" is needed to decode it at all
+		b.required[join(append(path, "@type"))] = true
+		return
+	}
+
+	for _, f := range m.Fields {
+		fieldPath := append(path[:len(path):len(path)], jsonName(f))
+		key := join(fieldPath)
+		if b.banned[m.FullName+"."+f.Name] {
+			b.blacklist[key] = true
+			continue
+		}
+
+		elemPath := fieldPath
+		if f.Repeated {
+			elemPath = append(fieldPath, "*")
+		}
+		switch {
+		case f.Oneof != "":
+			b.atomic[key] = true
+		case f.Kind == Scalar:
+			b.atomic[join(elemPath)] = true
+		case f.Kind == MessageKind && f.Message != nil && wellKnownAtomic[f.Message.FullName]:
+			b.atomic[join(elemPath)] = true
+		}
+		if f.Kind == MessageKind {
+			b.walk(f.Message, elemPath)
+		}
+	}
+}
+
+// jsonName returns the protojson field name, deriving lowerCamelCase from the
+// proto name the way protoc does when JSONName is unset.
+func jsonName(f Field) string {
+	if f.JSONName != "" {
+		return f.JSONName
+	}
+	var sb strings.Builder
+	upper := false
+	for _, r := range f.Name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func join(path []string) string {
+	return strings.Join(path, ".")
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+package protojsontrim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+var (
+	timestamp = &Message{FullName: "google.protobuf.Timestamp"}
+	anyMsg    = &Message{FullName: "google.protobuf.Any"}
+	card      = &Message{FullName: "acme.v1.Card", Fields: []Field{
+		{Name: "number", Kind: String},
+		{Name: "holder_name", Kind: String},
+	}}
+	user = &Message{FullName: "acme.v1.User", Fields: []Field{
+		{Name: "user_id", Kind: Scalar},
+		{Name: "password", Kind: String},
+		{Name: "bio", Kind: String},
+		{Name: "created_at", Kind: MessageKind, Message: timestamp},
+		{Name: "card", Kind: MessageKind, Message: card, Oneof: "payment"},
+		{Name: "details", Kind: MessageKind, Message: anyMsg, Repeated: true},
+	}}
+)
+
+func TestConfig(t *testing.T) {
+	cfg := Config(user, Options{Blacklist: []string{"acme.v1.User.password"}})
+
+	if strings.Join(cfg.Blacklist, ",") != "password" {
+		t.Errorf("Blacklist = %v", cfg.Blacklist)
+	}
+	if strings.Join(cfg.Atomic, ",") != "card,createdAt,userId" {
+		t.Errorf("Atomic = %v", cfg.Atomic)
+	}
+	if strings.Join(cfg.Required, ",") != "details.*.@type" {
+		t.Errorf("Required = %v", cfg.Required)
+	}
+}
+
+func TestTrimRespectsProtoSemantics(t *testing.T) {
+	raw := []byte(`{
+		"userId": "9007199254740993",
+		"password": "hunter2",
+		"bio": "` + strings.Repeat("b", 100) + `",
+		"createdAt": "2024-05-01T12:34:56.789012345Z",
+		"card": {"number": "4111", "holderName": "` + strings.Repeat("n", 100) + `"}
+	}`)
+	trimmer := New(user, Options{
+		Base:      jsontrim.Config{FieldLimit: 40, TruncateStrings: true},
+		Blacklist: []string{"acme.v1.User.password"},
+	})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["password"]; ok {
+		t.Error("Blacklisted proto field kept")
+	}
+	if m["createdAt"] != "2024-05-01T12:34:56.789012345Z" {
+		t.Errorf("Timestamp altered: %v", m["createdAt"])
+	}
+	if _, ok := m["card"]; ok {
+		t.Errorf("Oversized oneof member should be removed whole, got %v", m["card"])
+	}
+	if bio, _ := m["bio"].(string); !strings.HasSuffix(bio, "...") {
+		t.Errorf("Plain string field should still be truncated: %v", m["bio"])
+	}
+}
@@ -0,0 +1,650 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidQuery indicates a jq-style Blacklist, Whitelist or Select query,
+// or a DropIf condition, that can't be parsed.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// query is a compiled jq-style selection: a pipeline of path steps and
+// select() filters, e.g. `.events[] | select(.level == "debug")`. It is a
+// subset of jq with this grammar; anything else fails with ErrInvalidQuery:
+//
+//	query   = filter { "|" filter }
+//	filter  = path | "select" "(" cond ")"
+//	path    = "." | step { step }
+//	step    = "." key | "." string | [ "." ] "[" [ int ] "]"
+//	cond    = and { "or" and }
+//	and     = compare { "and" compare }
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand ]
+//	operand = path | string | number | "true" | "false" | "null" | "(" cond ")"
+//
+// Semantics differ from jq in two ways: a step that doesn't apply (a key on
+// an array, a missing index) selects nothing instead of failing, as if
+// followed by ?, and < <= > >= between values of different types, or other
+// than numbers and strings, are false rather than following jq's ordering.
+type query []queryStep
+
+type stepKind int
+
+const (
+	stepKey stepKind = iota
+	stepIndex
+	stepIterate
+	stepSelect
+)
+
+// queryStep is one path step or select() filter.
+type queryStep struct {
+	kind  stepKind
+	key   string
+	index int
+	cond  queryExpr
+}
+
+// queryNode is a value reached by a query, with its path in the document.
+type queryNode struct {
+	path []string
+	v    interface{}
+}
+
+// queryExpr is a condition operand, evaluated against the node being filtered.
+type queryExpr interface {
+	eval(v interface{}) interface{}
+}
+
+type (
+	pathExpr    []queryStep // First value the path reaches, or null
+	literalExpr struct{ v interface{} }
+	compareExpr struct {
+		op   string
+		l, r queryExpr
+	}
+	logicExpr struct {
+		and  bool
+		l, r queryExpr
+	}
+	notExpr struct{ e queryExpr }
+)
+
+// isQuery reports whether a Blacklist/Whitelist entry is a query rather than
+// a dotted path. Dotted paths never start with '.'.
+func isQuery(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), ".")
+}
+
+// Select applies Transformer only to the nodes Query selects in each document,
+// for rewrites that depend on values, e.g.
+//
+//	Select{Query: `.events[] | select(.level == "error") | .stack`, Transformer: StackTrace{}}
+//
+// Query takes the same jq subset as Blacklist queries. It is resolved once
+// per trim, before Transformers run; TrimStream never holds the whole
+// document, so it applies no Select.
+type Select struct {
+	Query       string
+	Transformer Transformer
+}
+
+// Transform implements Transformer. The Trimmer resolves Query and calls
+// Transformer itself, so a Select used on its own leaves v alone.
+func (s Select) Transform(path []string, v interface{}) interface{} {
+	return v
+}
+
+// compileSelects compiles the query of each Select in Transformers.
+func (t *Trimmer) compileSelects() {
+	for i, tf := range t.cfg.Transformers {
+		sel, ok := tf.(Select)
+		if !ok {
+			continue
+		}
+		if t.selectQuery == nil {
+			t.selectQuery = make([]query, len(t.cfg.Transformers))
+		}
+		q, err := parseQuery(sel.Query)
+		if err != nil {
+			if t.err == nil {
+				t.err = err
+			}
+			continue
+		}
+		t.selectQuery[i] = q
+	}
+}
+
+// selected reports whether the Select at Transformers[i] selected path.
+func (t *Trimmer) selected(i int, path []string) bool {
+	if i >= len(t.selectPaths) {
+		return false
+	}
+	for _, p := range t.selectPaths[i] {
+		if matchParts(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuery compiles a jq-style expression.
+func parseQuery(expr string) (query, error) {
+	p := &queryParser{s: expr}
+	var q query
+	for {
+		p.space()
+		if p.keyword("select") {
+			cond, err := p.selectArgs()
+			if err != nil {
+				return nil, err
+			}
+			q = append(q, queryStep{kind: stepSelect, cond: cond})
+		} else {
+			steps, err := p.path()
+			if err != nil {
+				return nil, err
+			}
+			q = append(q, steps...)
+		}
+		p.space()
+		if p.done() {
+			return q, nil
+		}
+		if !p.consume("|") {
+			return nil, p.errorf("expected '|'")
+		}
+	}
+}
+
+// parseCondition compiles a DropIf condition such as
+// `response.status < 400 && !(request.debug == true)`. Conditions are a
+// small expression language of their own, not CEL, with this grammar:
+//
+//	cond    = and { "||" and }
+//	and     = compare { "&&" compare }
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand ]
+//	operand = "!" operand | "(" cond ")" | path | string | number | "true" | "false" | "null"
+//	path    = ident { "." ident | "[" int "]" }
+//
+// Paths start at the document root; a missing field, or an index out of
+// range (negative ones count from the end), is null. Strings are JSON string
+// literals. Numbers of any decoded type compare as float64, strings compare
+// bytewise, and < <= > >= between other values are false. && || and ! treat
+// everything but false and null as true.
+func parseCondition(expr string) (queryExpr, error) {
+	p := &queryParser{s: expr, cond: true}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.space(); !p.done() {
+		return nil, p.errorf("unexpected input")
+	}
+	return e, nil
+}
+
+// literalStar stands for a "*" key in paths resolved from queries, which
+// matchParts compares exactly rather than as a wildcard.
+const literalStar = "\x00*"
+
+// paths returns the paths of every node q selects in v, ready for matchParts.
+func (q query) paths(v interface{}) [][]string {
+	nodes := q.eval(queryNode{path: []string{}, v: v})
+	out := make([][]string, len(nodes))
+	for i, n := range nodes {
+		for j, seg := range n.path {
+			if seg == "*" {
+				n.path[j] = literalStar
+			}
+		}
+		out[i] = n.path
+	}
+	return out
+}
+
+func (q query) eval(start queryNode) []queryNode {
+	nodes := []queryNode{start}
+	for _, step := range q {
+		var next []queryNode
+		for _, n := range nodes {
+			next = step.apply(n, next)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// apply appends the nodes step produces from n to out. Missing keys and
+// out-of-range indexes produce nothing.
+func (s queryStep) apply(n queryNode, out []queryNode) []queryNode {
+	child := func(seg string, v interface{}) queryNode {
+		path := make([]string, len(n.path)+1)
+		copy(path, n.path)
+		path[len(n.path)] = seg
+		return queryNode{path: path, v: v}
+	}
+
+	switch s.kind {
+	case stepKey:
+		if m, ok := n.v.(map[string]interface{}); ok {
+			if v, ok := m[s.key]; ok {
+				out = append(out, child(s.key, v))
+			}
+		}
+	case stepIndex:
+		if arr, ok := n.v.([]interface{}); ok {
+			i := s.index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i >= 0 && i < len(arr) {
+				out = append(out, child(strconv.Itoa(i), arr[i]))
+			}
+		}
+	case stepIterate:
+		switch vv := n.v.(type) {
+		case []interface{}:
+			for i, item := range vv {
+				out = append(out, child(strconv.Itoa(i), item))
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(vv))
+			for k := range vv {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				out = append(out, child(k, vv[k]))
+			}
+		}
+	case stepSelect:
+		if truthy(s.cond.eval(n.v)) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (e pathExpr) eval(v interface{}) interface{} {
+	if nodes := query(e).eval(queryNode{v: v}); len(nodes) > 0 {
+		return nodes[0].v
+	}
+	return nil
+}
+
+func (e literalExpr) eval(interface{}) interface{} { return e.v }
+
+func (e compareExpr) eval(v interface{}) interface{} {
+	l, r := e.l.eval(v), e.r.eval(v)
+	switch e.op {
+	case "==":
+		return queryEqual(l, r)
+	case "!=":
+		return !queryEqual(l, r)
+	}
+	var c int
+	if lf, ok := toFloat(l); ok {
+		rf, ok := toFloat(r)
+		if !ok {
+			return false
+		}
+		c = compareFloat(lf, rf)
+	} else if ls, ok := l.(string); ok {
+		rs, ok := r.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(ls, rs)
+	} else {
+		return false
+	}
+	switch e.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+func (e notExpr) eval(v interface{}) interface{} { return !truthy(e.e.eval(v)) }
+
+func (e logicExpr) eval(v interface{}) interface{} {
+	if e.and {
+		return truthy(e.l.eval(v)) && truthy(e.r.eval(v))
+	}
+	return truthy(e.l.eval(v)) || truthy(e.r.eval(v))
+}
+
+// truthy follows jq: everything but false and null is true.
+func truthy(v interface{}) bool {
+	return v != nil && v != false
+}
+
+func queryEqual(l, r interface{}) bool {
+	if lf, ok := toFloat(l); ok {
+		rf, ok := toFloat(r)
+		return ok && lf == rf
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+// toFloat converts the numeric types a Codec may decode to float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// queryParser is a small recursive-descent parser over a query string. With
+// cond set it reads DropIf conditions: bare dotted identifiers, &&, || and !.
+type queryParser struct {
+	s    string
+	pos  int
+	cond bool
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %q at offset %d: %s", ErrInvalidQuery, p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) done() bool { return p.pos >= len(p.s) }
+
+func (p *queryParser) space() {
+	for !p.done() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+// consume skips tok if it comes next.
+func (p *queryParser) consume(tok string) bool {
+	p.space()
+	if strings.HasPrefix(p.s[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// keyword consumes word if it comes next as a whole identifier.
+func (p *queryParser) keyword(word string) bool {
+	p.space()
+	start := p.pos
+	if p.ident() == word {
+		return true
+	}
+	p.pos = start
+	return false
+}
+
+// logical consumes the jq or DropIf spelling of a logical operator.
+func (p *queryParser) logical(jq, cond string) bool {
+	if p.cond {
+		return p.consume(cond)
+	}
+	return p.keyword(jq)
+}
+
+func (p *queryParser) ident() string {
+	start := p.pos
+	for !p.done() {
+		c := p.s[p.pos]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || p.pos > start && c >= '0' && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.s[start:p.pos]
+}
+
+// path parses `.`, `.a.b`, `."key"`, `.[N]` and `.[]` steps.
+func (p *queryParser) path() ([]queryStep, error) {
+	p.space()
+	if p.done() || p.s[p.pos] != '.' {
+		return nil, p.errorf("expected path")
+	}
+	start := p.pos
+	var steps []queryStep
+	for !p.done() {
+		switch p.s[p.pos] {
+		case '.':
+			identity := p.pos == start
+			p.pos++
+			switch {
+			case !p.done() && p.s[p.pos] == '"':
+				key, err := p.str()
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, queryStep{kind: stepKey, key: key})
+			case !p.done() && p.s[p.pos] == '[':
+			default:
+				key := p.ident()
+				if key == "" && !identity {
+					return nil, p.errorf("expected key after '.'") // Rejects jq's .. and a trailing .
+				}
+				if key != "" {
+					steps = append(steps, queryStep{kind: stepKey, key: key})
+				}
+			}
+		case '[':
+			p.pos++
+			if p.consume("]") {
+				steps = append(steps, queryStep{kind: stepIterate})
+				continue
+			}
+			i, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, queryStep{kind: stepIndex, index: i})
+		default:
+			return steps, nil
+		}
+	}
+	return steps, nil
+}
+
+// index parses the `N]` after an opening bracket.
+func (p *queryParser) index() (int, error) {
+	p.space()
+	start := p.pos
+	if !p.done() && p.s[p.pos] == '-' {
+		p.pos++
+	}
+	for !p.done() && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+	i, err := strconv.Atoi(p.s[start:p.pos])
+	if err != nil {
+		return 0, p.errorf("expected index")
+	}
+	if !p.consume("]") {
+		return 0, p.errorf("expected ']'")
+	}
+	return i, nil
+}
+
+// str parses a JSON string literal.
+func (p *queryParser) str() (string, error) {
+	start := p.pos
+	for p.pos++; !p.done(); p.pos++ {
+		switch p.s[p.pos] {
+		case '\\':
+			p.pos++
+		case '"':
+			p.pos++
+			var s string
+			if err := json.Unmarshal([]byte(p.s[start:p.pos]), &s); err != nil {
+				return "", p.errorf("bad string: %v", err)
+			}
+			return s, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *queryParser) selectArgs() (queryExpr, error) {
+	if !p.consume("(") {
+		return nil, p.errorf("expected '(' after select")
+	}
+	cond, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume(")") {
+		return nil, p.errorf("expected ')'")
+	}
+	return cond, nil
+}
+
+func (p *queryParser) or() (queryExpr, error) {
+	l, err := p.and()
+	for err == nil && p.logical("or", "||") {
+		var r queryExpr
+		if r, err = p.and(); err == nil {
+			l = logicExpr{l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *queryParser) and() (queryExpr, error) {
+	l, err := p.comparison()
+	for err == nil && p.logical("and", "&&") {
+		var r queryExpr
+		if r, err = p.comparison(); err == nil {
+			l = logicExpr{and: true, l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *queryParser) comparison() (queryExpr, error) {
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	// Two-character operators first, so "<=" isn't read as "<"
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			r, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return compareExpr{op: op, l: l, r: r}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *queryParser) operand() (queryExpr, error) {
+	p.space()
+	if p.done() {
+		return nil, p.errorf("unexpected end")
+	}
+	switch c := p.s[p.pos]; {
+	case p.cond && c == '!' && !strings.HasPrefix(p.s[p.pos:], "!="):
+		p.pos++
+		e, err := p.operand()
+		return notExpr{e}, err
+	case c == '.' && !p.cond:
+		steps, err := p.path()
+		return pathExpr(steps), err
+	case c == '"':
+		s, err := p.str()
+		return literalExpr{s}, err
+	case c == '(':
+		p.pos++
+		e, err := p.or()
+		if err == nil && !p.consume(")") {
+			err = p.errorf("expected ')'")
+		}
+		return e, err
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos++; !p.done() && strings.IndexByte("0123456789.eE+-", p.s[p.pos]) >= 0; p.pos++ {
+		}
+		f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return nil, p.errorf("bad number %q", p.s[start:p.pos])
+		}
+		return literalExpr{f}, nil
+	}
+	start := p.pos
+	switch word := p.ident(); word {
+	case "true":
+		return literalExpr{true}, nil
+	case "false":
+		return literalExpr{false}, nil
+	case "null":
+		return literalExpr{nil}, nil
+	case "":
+	default:
+		if p.cond {
+			p.pos = start
+			return p.rootPath()
+		}
+	}
+	return nil, p.errorf("unexpected input")
+}
+
+// rootPath parses a root-relative identifier path like `response.items[0].id`.
+func (p *queryParser) rootPath() (queryExpr, error) {
+	steps := []queryStep{{kind: stepKey, key: p.ident()}}
+	for !p.done() {
+		switch p.s[p.pos] {
+		case '.':
+			p.pos++
+			key := p.ident()
+			if key == "" {
+				return nil, p.errorf("expected field name")
+			}
+			steps = append(steps, queryStep{kind: stepKey, key: key})
+			continue
+		case '[':
+			p.pos++
+			i, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, queryStep{kind: stepIndex, index: i})
+			continue
+		}
+		break
+	}
+	return pathExpr(steps), nil
+}
@@ -0,0 +1,113 @@
+package jsontrim
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQueryBlacklist(t *testing.T) {
+	raw := []byte(`{"events":[{"level":"debug","msg":"a"},{"level":"error","msg":"b"},{"level":"debug","msg":"c"}],` +
+		`"response":{"status":200,"body":"ok"}}`)
+	trimmer := New(Config{
+		TotalLimit: 4096,
+		Blacklist: []string{
+			`.events[] | select(.level == "debug")`,
+			`.response | select(.status < 400 and .body != null) | .body`,
+		},
+	})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"events":[{"level":"error","msg":"b"}],"response":{"status":200}}`
+	if string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
+
+func TestQueryWhitelist(t *testing.T) {
+	raw := []byte(`{"id":1,"users":[{"name":"a","admin":true,"token":"x"},{"name":"b","admin":false}]}`)
+	trimmer := New(Config{TotalLimit: 4096, Whitelist: []string{"id", `.users[] | select(.admin) | ."name"`}})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":1,"users":[{"name":"a"}]}`
+	if string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
+
+func TestQueryInvalid(t *testing.T) {
+	for _, expr := range []string{`.a[`, `.a | select(.b ==)`, `.a | select(.b`, `.a | foo`, `..`, `.a..b`, `.a.`, `.a[0:2]`, `.["a"]`, `.a | select(.b | not)`, `.a?`} {
+		_, err := New(Config{Blacklist: []string{expr}}).Trim([]byte(`{}`))
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: expected ErrInvalidQuery, got %v", expr, err)
+		}
+	}
+}
+
+func TestDropIf(t *testing.T) {
+	trimmer := New(Config{
+		TotalLimit: 4096,
+		DropIf: map[string]string{
+			"response.body":     `response.status < 400 && !(request.debug == true)`,
+			"request.headers":   `request.method == "GET" || request.headers.cookie != null`,
+			"request.items.*.x": `request.items[-1].x >= 2`,
+		},
+	})
+	tests := []struct{ in, want string }{
+		{
+			`{"request":{"method":"GET","headers":{"a":"b"}},"response":{"status":200,"body":"ok"}}`,
+			`{"request":{"method":"GET"},"response":{"status":200}}`,
+		},
+		{
+			`{"request":{"method":"POST","headers":{"a":"b"},"debug":true},"response":{"status":200,"body":"ok"}}`,
+			`{"request":{"debug":true,"headers":{"a":"b"},"method":"POST"},"response":{"body":"ok","status":200}}`,
+		},
+		{
+			`{"request":{"items":[{"x":1},{"x":2}]},"response":{"status":500,"body":"boom"}}`,
+			`{"request":{"items":[{},{}]},"response":{"body":"boom","status":500}}`,
+		},
+	}
+	for _, tt := range tests {
+		out, err := trimmer.Trim([]byte(tt.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("Trim(%s) = %s, want %s", tt.in, out, tt.want)
+		}
+	}
+
+	for _, cond := range []string{"b <", "size(a) > 1", `a in ["x"]`, "has(a.b)", "a ? b : c"} { // CEL beyond the grammar
+		if _, err := New(Config{DropIf: map[string]string{"a": cond}}).Trim([]byte(`{}`)); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: expected ErrInvalidQuery, got %v", cond, err)
+		}
+	}
+}
+
+func TestQuerySelect(t *testing.T) {
+	raw := []byte(`{"users":[{"email":"ann@example.com","vip":true},{"email":"bob@example.com"}]}`)
+	trimmer := New(Config{TotalLimit: 4096, Transformers: []Transformer{
+		Select{Query: `.users[] | select(.vip) | .email`, Transformer: MaskEmail{}},
+	}})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"users":[{"email":"a***@example.com","vip":true},{"email":"bob@example.com"}]}`
+	if string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+
+	// A resolved "*" key is that key, not a wildcard
+	raw = []byte(`{"*":{"a":1},"x":{"a":2}}`)
+	out, err = New(Config{TotalLimit: 4096, Blacklist: []string{`."*" | .a`}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"*":{},"x":{"a":2}}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+}
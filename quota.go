@@ -0,0 +1,112 @@
+package jsontrim
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExhausted indicates a tenant has used its whole BudgetManager
+// quota for the current window.
+var ErrQuotaExhausted = errors.New("tenant quota exhausted")
+
+// BudgetManager trims documents for many tenants (services, customers, API
+// keys) against a per-tenant quota of output bytes per time window, on top of
+// the per-document TotalLimit. While a tenant has used less than half its
+// quota, documents get the full TotalLimit; past that the limit shrinks in
+// proportion to what's left, down to a tenth of TotalLimit, so a noisy
+// tenant degrades gradually instead of starving everyone else's share of a
+// log pipeline. It is safe for concurrent
+// use; concurrent trims for one tenant may overshoot its quota by up to one
+// document each.
+type BudgetManager struct {
+	t      *Trimmer
+	quota  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	tenants map[string]*tenantUsage
+	swept   time.Time
+}
+
+// tenantUsage is one tenant's usage in its current window.
+type tenantUsage struct {
+	start time.Time
+	used  int
+}
+
+// NewBudgetManager returns a BudgetManager trimming with t, allowing each
+// tenant quota units of output per window.
+func NewBudgetManager(t *Trimmer, quota int, window time.Duration) *BudgetManager {
+	return &BudgetManager{t: t, quota: quota, window: window, now: time.Now, tenants: make(map[string]*tenantUsage)}
+}
+
+// Trim trims raw for tenant with the limit its remaining quota allows, and
+// charges the output to it. Once the quota is used up it fails with
+// ErrQuotaExhausted until the tenant's window rolls over.
+func (b *BudgetManager) Trim(tenant string, raw []byte) ([]byte, error) {
+	limit := b.Limit(tenant)
+	if limit <= 0 {
+		return nil, ErrQuotaExhausted
+	}
+	t := b.t.current()
+	if limit < t.cfg.TotalLimit {
+		t = t.withTotalLimit(limit)
+	}
+	out, err := t.Trim(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.usage(tenant).used += t.measure(out)
+	b.mu.Unlock()
+	return out, nil
+}
+
+// Limit returns the TotalLimit the next document for tenant gets, or 0 if its
+// quota is used up (less than a tenth of TotalLimit is left).
+func (b *BudgetManager) Limit(tenant string) int {
+	b.mu.Lock()
+	remaining := b.quota - b.usage(tenant).used
+	b.mu.Unlock()
+
+	total := b.t.current().cfg.TotalLimit
+	floor := max(1, total/10)
+	switch half := b.quota / 2; {
+	case remaining < floor:
+		return 0
+	case remaining >= half:
+		return min(total, remaining)
+	default:
+		return max(floor, total*remaining/half)
+	}
+}
+
+// Remaining returns what's left of tenant's quota in its current window.
+func (b *BudgetManager) Remaining(tenant string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return max(0, b.quota-b.usage(tenant).used)
+}
+
+// usage returns tenant's usage, starting a new window if the last one ended.
+// Tenants idle for a whole window are forgotten. b.mu must be held.
+func (b *BudgetManager) usage(tenant string) *tenantUsage {
+	now := b.now()
+	if now.Sub(b.swept) >= b.window {
+		for name, u := range b.tenants {
+			if now.Sub(u.start) >= b.window {
+				delete(b.tenants, name)
+			}
+		}
+		b.swept = now
+	}
+	u, ok := b.tenants[tenant]
+	if !ok || now.Sub(u.start) >= b.window {
+		u = &tenantUsage{start: now}
+		b.tenants[tenant] = u
+	}
+	return u
+}
@@ -0,0 +1,43 @@
+package jsontrim
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBudgetManager(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBudgetManager(New(Config{TotalLimit: 100, FieldLimit: 1000, TruncateStrings: true}), 400, time.Minute)
+	b.now = func() time.Time { return now }
+	var fields []string
+	for i := 0; i < 20; i++ {
+		fields = append(fields, fmt.Sprintf(`"f%02d":"%s"`, i, strings.Repeat("v", 8)))
+	}
+	raw := []byte("{" + strings.Join(fields, ",") + "}")
+
+	var sizes []int
+	for {
+		out, err := b.Trim("noisy", raw)
+		if errors.Is(err, ErrQuotaExhausted) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(out))
+	}
+	if sizes[0] < 60 || sizes[len(sizes)-1] >= sizes[0]/2 {
+		t.Errorf("Expected limits to tighten near the quota, got %v", sizes)
+	}
+	if b.Limit("noisy") != 0 || b.Limit("quiet") != 100 {
+		t.Errorf("Expected only the noisy tenant limited, got %d and %d", b.Limit("noisy"), b.Limit("quiet"))
+	}
+
+	now = now.Add(time.Minute)
+	if b.Limit("noisy") != 100 {
+		t.Error("Expected the quota to reset with the window")
+	}
+}
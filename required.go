@@ -0,0 +1,168 @@
+package jsontrim
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// isRequired reports whether path is exactly a Required path.
+func (t *Trimmer) isRequired(path []string) bool {
+	for _, rule := range t.requiredParts {
+		if matchParts(rule, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// protects reports whether path is a Required field, lies inside one, or is a
+// container on the way to one. Such nodes must not be dropped as a whole.
+func (t *Trimmer) protects(path []string) bool {
+	for _, rule := range t.requiredParts {
+		n := min(len(rule), len(path))
+		if matchParts(rule[:n], path[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectNext asks the strategy for the next removal, hiding protected
+// top-level entries from it so it can only pick removable ones. over is how
+// far the document is over its limit (0 when there's no size budget).
+func (t *Trimmer) selectNext(v interface{}, over int) string {
+	if t.cfg.ProtectTimestamps || len(t.cfg.TimestampPaths) > 0 {
+		if candidates, ok := t.withoutTimestamps(v); ok {
+			v = candidates
+		}
+	}
+	if len(t.requiredParts) == 0 {
+		return selectWithBudget(t.sized(t.cfg.Strategy), v, over)
+	}
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		candidates := make(map[string]interface{}, len(vv))
+		for k, val := range vv {
+			if !t.protects([]string{k}) {
+				candidates[k] = val
+			}
+		}
+		if len(candidates) == 0 {
+			return ""
+		}
+		return selectWithBudget(t.sized(t.cfg.Strategy), candidates, over)
+	case []interface{}:
+		var candidates []interface{}
+		var indexes []int // Candidate position -> original index
+		for i, item := range vv {
+			if !t.protects([]string{strconv.Itoa(i)}) {
+				candidates = append(candidates, item)
+				indexes = append(indexes, i)
+			}
+		}
+		if len(candidates) == 0 {
+			return ""
+		}
+		sel := selectWithBudget(t.sized(t.cfg.Strategy), candidates, over)
+		idx, err := strconv.Atoi(strings.TrimPrefix(sel, "idx:"))
+		if err != nil || idx < 0 || idx >= len(indexes) {
+			return ""
+		}
+		return fmt.Sprintf("idx:%d", indexes[idx])
+	}
+	return selectWithBudget(t.sized(t.cfg.Strategy), v, over)
+}
+
+// enforceRequired runs after enforceTotal when Required is set. If the
+// document is still over TotalLimit, everything left at the top level is
+// protected, so it drops the non-required content nested inside those
+// containers, largest first. If the required fields alone don't fit it
+// returns ErrRequiredTooLarge rather than dropping them.
+func (t *Trimmer) enforceRequired(v interface{}) (interface{}, error) {
+	size, err := t.totalSize(v)
+	if err != nil {
+		return v, nil
+	}
+	over := size - t.cfg.TotalLimit
+	if over <= 0 {
+		return v, nil
+	}
+
+	v = t.shrinkProtected(v, nil, &over)
+	if over > 0 {
+		if size, _ = t.totalSize(v); size > t.cfg.TotalLimit {
+			return nil, fmt.Errorf("%w: %d > %d", ErrRequiredTooLarge, size, t.cfg.TotalLimit)
+		}
+	}
+	return v, nil
+}
+
+// shrinkProtected removes unprotected descendants of a protected container
+// until over drops to zero. Removable children go first (largest first), then
+// it descends into protected children.
+func (t *Trimmer) shrinkProtected(v interface{}, path []string, over *int) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return t.estimate(vv[keys[i]]) > t.estimate(vv[keys[j]]) })
+
+		for _, k := range keys {
+			if *over <= 0 {
+				return vv
+			}
+			if !t.protects(append(path, k)) {
+				*over -= t.strLen(k) + 3 + t.sizeOf(vv[k]) // "key":VALUE, ignoring the comma
+				t.record(append(path, k), reasonTotalLimit, vv[k])
+				delete(vv, k)
+			}
+		}
+		for _, k := range keys {
+			if *over <= 0 {
+				return vv
+			}
+			child := append(path, k)
+			if val, ok := vv[k]; ok && !t.isRequired(child) {
+				vv[k] = t.shrinkProtected(val, child, over)
+			}
+		}
+		return vv
+
+	case []interface{}:
+		order := make([]int, len(vv))
+		for i := range vv {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return t.estimate(vv[order[i]]) > t.estimate(vv[order[j]]) })
+
+		dropped := make(map[int]bool)
+		for _, i := range order {
+			if *over <= 0 {
+				break
+			}
+			if !t.protects(append(path, strconv.Itoa(i))) {
+				*over -= t.sizeOf(vv[i])
+				dropped[i] = true
+				t.record(append(path, strconv.Itoa(i)), reasonTotalLimit, vv[i])
+			}
+		}
+		out := make([]interface{}, 0, len(vv)-len(dropped))
+		for i, item := range vv {
+			if dropped[i] {
+				continue
+			}
+			child := append(path, strconv.Itoa(i))
+			if *over > 0 && !t.isRequired(child) {
+				item = t.shrinkProtected(item, child, over)
+			}
+			out = append(out, item)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,64 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRequiredSurvivesLimits(t *testing.T) {
+	raw := []byte(`{
+		"error": {"message": "` + strings.Repeat("e", 300) + `", "debug": "` + strings.Repeat("d", 300) + `"},
+		"request": "` + strings.Repeat("r", 300) + `",
+		"id": "abc"
+	}`)
+	trimmer := New(Config{
+		FieldLimit: 100,
+		TotalLimit: 400,
+		Required:   []string{"id", "error.message"},
+	})
+
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 400 {
+		t.Errorf("Output over limit: %d", len(out))
+	}
+	var m struct {
+		ID    string            `json:"id"`
+		Error map[string]string `json:"error"`
+	}
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "abc" {
+		t.Errorf("Lost required id: %s", out)
+	}
+	if len(m.Error["message"]) != 300 {
+		t.Errorf("Required field was trimmed: %s", out)
+	}
+	if _, ok := m.Error["debug"]; ok {
+		t.Errorf("Non-required sibling should have been dropped: %s", out)
+	}
+}
+
+func TestRequiredTooLarge(t *testing.T) {
+	raw := []byte(`{"stack":"` + strings.Repeat("s", 500) + `","other":1}`)
+	_, err := New(Config{TotalLimit: 200, Required: []string{"stack"}}).Trim(raw)
+	if !errors.Is(err, ErrRequiredTooLarge) {
+		t.Errorf("Expected ErrRequiredTooLarge, got %v", err)
+	}
+}
+
+func TestRequiredArrayItems(t *testing.T) {
+	raw := []byte(`[{"id":1,"body":"` + strings.Repeat("x", 200) + `"},{"id":2,"body":"` + strings.Repeat("y", 200) + `"}]`)
+	out, err := New(Config{TotalLimit: 100, Required: []string{"*.id"}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `[{"id":1},{"id":2}]` {
+		t.Errorf("Expected only ids kept, got %s", out)
+	}
+}
@@ -0,0 +1,116 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Reasons reported in audit lines.
+const (
+	reasonBlacklist    = "blacklist"
+	reasonWhitelist    = "whitelist"
+	reasonFieldHook    = "field_hook"
+	reasonFieldLimit   = "field_limit"
+	reasonMaxDepth     = "max_depth"
+	reasonEmpty        = "empty"
+	reasonNull         = "null"
+	reasonSubtreeLimit = "subtree_limit"
+	reasonWeight       = "weight"
+	reasonTotalLimit   = "total_limit"
+	reasonFieldCount   = "field_count"
+	reasonKeyDepth     = "key_depth"
+	reasonKeepLast     = "keep_last"
+	reasonRename       = "rename"
+)
+
+// TrimResult summarizes what a single trim did.
+type TrimResult struct {
+	InputSize     int            // Size of the input as given, in Unit
+	OutputSize    int            // Size of the trimmed output, in Unit
+	BytesRemoved  int            // InputSize - OutputSize (negative if re-encoding grew the document)
+	PathsAffected []string       // Dotted paths removed, replaced or truncated, in the order they were trimmed
+	TimedOut      bool           // MaxDuration ran out and the fallback finished the trim
+	RuleHits      map[string]int // Blacklist entry -> nodes it removed, for the entries that matched (nil if none did)
+	Timings       PhaseTimings   // How long each phase took
+}
+
+// trimStats collects what one trim call changed.
+type trimStats struct {
+	events []trimEvent
+	sized  bool // Measure original values (only needed for the audit log)
+}
+
+// trimEvent is one removed, replaced or truncated node.
+type trimEvent struct {
+	path   string
+	reason string
+	rule   string // The Blacklist entry that matched, if any
+	size   int
+}
+
+// auditLine is the JSON form of a trimEvent written to AuditWriter.
+type auditLine struct {
+	Time   time.Time `json:"time"`
+	Path   string    `json:"path"`
+	Reason string    `json:"reason"`
+	Size   int       `json:"size"`
+}
+
+// TrimWithResult is Trim that also reports what was trimmed.
+func (t *Trimmer) TrimWithResult(raw []byte) ([]byte, TrimResult, error) {
+	return t.trim(raw, true)
+}
+
+// record notes that the node at path was removed, replaced or truncated. It is
+// a no-op unless the Trimmer is collecting a TrimResult or audit log.
+func (t *Trimmer) record(path []string, reason string, original interface{}) {
+	t.recordRule(path, reason, "", original)
+}
+
+// recordRule is record for a node removed by the Blacklist entry rule.
+func (t *Trimmer) recordRule(path []string, reason, rule string, original interface{}) {
+	if t.stats == nil {
+		return
+	}
+	e := trimEvent{path: strings.Join(path, "."), reason: reason, rule: rule}
+	if t.stats.sized {
+		e.size = t.sizeOf(original)
+	}
+	t.stats.events = append(t.stats.events, e)
+}
+
+// result builds the TrimResult for the given sizes.
+func (s *trimStats) result(in, out int) TrimResult {
+	paths := make([]string, len(s.events))
+	var hits map[string]int
+	for i, e := range s.events {
+		paths[i] = e.path
+		if e.rule != "" {
+			if hits == nil {
+				hits = make(map[string]int)
+			}
+			hits[e.rule]++
+		}
+	}
+	return TrimResult{InputSize: in, OutputSize: out, BytesRemoved: in - out, PathsAffected: paths, RuleHits: hits}
+}
+
+// writeAudit writes one JSON line per event to AuditWriter in a single Write,
+// so lines from concurrent trims don't interleave.
+func (t *Trimmer) writeAudit() error {
+	if len(t.stats.events) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	var buf []byte
+	for _, e := range t.stats.events {
+		line, err := json.Marshal(auditLine{Time: now, Path: e.path, Reason: e.reason, Size: e.size})
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	_, err := t.cfg.AuditWriter.Write(buf)
+	return err
+}
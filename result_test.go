@@ -0,0 +1,118 @@
+package jsontrim
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrimWithResult(t *testing.T) {
+	raw := []byte(`{"id":1,"password":"x","logs":["` + strings.Repeat("l", 80) + `","ok"],"body":"` + strings.Repeat("b", 300) + `"}`)
+	trimmer := New(Config{FieldLimit: 50, TotalLimit: 60, Blacklist: []string{"password"}})
+
+	out, res, err := trimmer.TrimWithResult(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.InputSize != len(raw) || res.OutputSize != len(out) || res.BytesRemoved != len(raw)-len(out) {
+		t.Errorf("Bad sizes: %+v (in %d, out %d)", res, len(raw), len(out))
+	}
+	got := append([]string(nil), res.PathsAffected...)
+	sort.Strings(got)
+	if want := []string{"body", "logs.0", "password"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PathsAffected = %v, want %v", got, want)
+	}
+
+	// Plain Trim must not share state with result collection
+	if _, err := trimmer.Trim(raw); err != nil || trimmer.stats != nil {
+		t.Errorf("Trim should not collect stats: %v", err)
+	}
+}
+
+func TestAfterTrimReceivesResult(t *testing.T) {
+	var res TrimResult
+	trimmer := New(Config{FieldLimit: 10, Hooks: Hooks{AfterTrim: func(v interface{}, r TrimResult) (interface{}, error) {
+		res = r
+		return v, nil
+	}}})
+	if _, err := trimmer.Trim([]byte(`{"a":"` + strings.Repeat("x", 20) + `","b":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.PathsAffected, []string{"a"}) || res.OutputSize != len(`{"b":1}`) {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+}
+
+func TestAuditWriter(t *testing.T) {
+	var buf bytes.Buffer
+	trimmer := New(Config{FieldLimit: 20, Blacklist: []string{"password"}, AuditWriter: &buf})
+	if _, err := trimmer.Trim([]byte(`{"password":"hunter2","note":"` + strings.Repeat("n", 30) + `","id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []auditLine
+	for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var a auditLine
+		if err := json.Unmarshal([]byte(l), &a); err != nil {
+			t.Fatalf("Bad audit line %q: %v", l, err)
+		}
+		if a.Time.IsZero() {
+			t.Errorf("Missing timestamp: %q", l)
+		}
+		a.Time = time.Time{}
+		lines = append(lines, a)
+	}
+	want := []auditLine{{Path: "password", Reason: "blacklist", Size: 9}, {Path: "note", Reason: "field_limit", Size: 32}}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Audit lines = %+v, want %+v", lines, want)
+	}
+}
+
+func TestPhaseTimings(t *testing.T) {
+	raw := []byte(`{"a":"` + strings.Repeat("x", 5000) + `","b":[1,2,3],"secret":"s"}`)
+	var seen PhaseTimings
+	tr := New(Config{Blacklist: []string{"secret"}, TruncateStrings: true, OnTimings: func(p PhaseTimings) { seen = p }})
+
+	_, res, err := tr.TrimWithResult(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Timings.Decode <= 0 || res.Timings.FieldTrim <= 0 || res.Timings.Encode <= 0 {
+		t.Errorf("Expected decode, field trim and encode timed, got %+v", res.Timings)
+	}
+	if seen != res.Timings || seen.Total() < seen.Decode+seen.Encode {
+		t.Errorf("Expected OnTimings to get %+v, got %+v", res.Timings, seen)
+	}
+
+	seen = PhaseTimings{}
+	if _, err := tr.Trim(raw); err != nil || seen.Total() <= 0 {
+		t.Errorf("Expected OnTimings on Trim too, got %+v (%v)", seen, err)
+	}
+}
+
+func TestRuleHits(t *testing.T) {
+	raw := []byte(`{"users":[{"name":"a","password":"x"},{"name":"b","password":"y"}],"token":"t","events":[{"level":"debug"},{"level":"info"}]}`)
+	blacklist := []string{"users.*.password", "token", "session", `.events[] | select(.level == "debug")`}
+	want := map[string]int{"users.*.password": 2, "token": 1, `.events[] | select(.level == "debug")`: 1}
+	stats := NewStats()
+	_, res, err := New(Config{Blacklist: blacklist, Stats: stats}).TrimWithResult(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.RuleHits, want) {
+		t.Errorf("Got %v, want %v", res.RuleHits, want)
+	}
+	if snap := stats.Snapshot(0); !reflect.DeepEqual(snap.RuleHits, want) {
+		t.Errorf("Expected Stats to aggregate rule hits, got %v", snap.RuleHits)
+	}
+
+	// Plain paths are stripped while decoding
+	_, res, _ = New(Config{Blacklist: blacklist[:3]}).TrimWithResult(raw)
+	if want := map[string]int{"users.*.password": 2, "token": 1}; !reflect.DeepEqual(res.RuleHits, want) {
+		t.Errorf("Got %v while decoding, want %v", res.RuleHits, want)
+	}
+}
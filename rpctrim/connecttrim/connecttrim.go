@@ -0,0 +1,123 @@
+// Package connecttrim logs connect-go request and response messages as JSON
+// trimmed per procedure by an rpctrim.Resolver. It is a module of its own, so
+// jsontrim itself doesn't depend on connect-go.
+package connecttrim
+
+import (
+	"context"
+	"encoding/json"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/arun0009/jsontrim/rpctrim"
+)
+
+// NewInterceptor returns a connect.Interceptor that passes every request and
+// response message to log, encoded with protojson (encoding/json for messages
+// that aren't protobuf) and trimmed for its procedure by r:
+//
+//	mux.Handle(usersv1connect.NewUserServiceHandler(svc,
+//		connect.WithInterceptors(connecttrim.NewInterceptor(resolver, logRPC))))
+//
+// It works on clients and handlers, unary and streaming. Failed calls log no
+// response, and messages that don't encode aren't logged.
+func NewInterceptor(r *rpctrim.Resolver, log rpctrim.LogFunc) connect.Interceptor {
+	return &interceptor{r: r, log: log}
+}
+
+type interceptor struct {
+	r   *rpctrim.Resolver
+	log rpctrim.LogFunc
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		procedure := req.Spec().Procedure
+		i.message(ctx, procedure, false, req.Any())
+		resp, err := next(ctx, req)
+		if err == nil && resp != nil {
+			i.message(ctx, procedure, true, resp.Any())
+		}
+		return resp, err
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return &clientConn{StreamingClientConn: next(ctx, spec), ctx: ctx, i: i}
+	}
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return next(ctx, &handlerConn{StreamingHandlerConn: conn, ctx: ctx, i: i})
+	}
+}
+
+// message encodes msg and logs it.
+func (i *interceptor) message(ctx context.Context, procedure string, response bool, msg interface{}) {
+	var (
+		payload []byte
+		err     error
+	)
+	if m, ok := msg.(proto.Message); ok {
+		payload, err = protojson.Marshal(m)
+	} else {
+		payload, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return
+	}
+	i.r.Log(ctx, i.log, procedure, response, payload)
+}
+
+// clientConn logs the requests a client sends and the responses it receives.
+type clientConn struct {
+	connect.StreamingClientConn
+	ctx context.Context
+	i   *interceptor
+}
+
+func (c *clientConn) Send(msg interface{}) error {
+	err := c.StreamingClientConn.Send(msg)
+	if err == nil {
+		c.i.message(c.ctx, c.Spec().Procedure, false, msg)
+	}
+	return err
+}
+
+func (c *clientConn) Receive(msg interface{}) error {
+	err := c.StreamingClientConn.Receive(msg)
+	if err == nil {
+		c.i.message(c.ctx, c.Spec().Procedure, true, msg)
+	}
+	return err
+}
+
+// handlerConn logs the requests a handler receives and the responses it sends.
+type handlerConn struct {
+	connect.StreamingHandlerConn
+	ctx context.Context
+	i   *interceptor
+}
+
+func (c *handlerConn) Receive(msg interface{}) error {
+	err := c.StreamingHandlerConn.Receive(msg)
+	if err == nil {
+		c.i.message(c.ctx, c.Spec().Procedure, false, msg)
+	}
+	return err
+}
+
+func (c *handlerConn) Send(msg interface{}) error {
+	err := c.StreamingHandlerConn.Send(msg)
+	if err == nil {
+		c.i.message(c.ctx, c.Spec().Procedure, true, msg)
+	}
+	return err
+}
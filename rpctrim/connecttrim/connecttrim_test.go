@@ -0,0 +1,57 @@
+package connecttrim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/arun0009/jsontrim"
+	"github.com/arun0009/jsontrim/rpctrim"
+)
+
+func TestInterceptor(t *testing.T) {
+	const procedure = "/test.v1.EchoService/Echo"
+	resolver := rpctrim.NewResolver(jsontrim.New(jsontrim.Config{TotalLimit: 4096}), map[string][]jsontrim.Option{
+		procedure: {jsontrim.WithBlacklist("password")},
+	})
+	var (
+		mu     sync.Mutex
+		logged []rpctrim.Message
+	)
+	logRPC := func(ctx context.Context, m rpctrim.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, m)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(procedure, connect.NewUnaryHandler(procedure,
+		func(ctx context.Context, req *connect.Request[structpb.Struct]) (*connect.Response[structpb.Struct], error) {
+			return connect.NewResponse(req.Msg), nil
+		},
+		connect.WithInterceptors(NewInterceptor(resolver, logRPC)),
+	))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := connect.NewClient[structpb.Struct, structpb.Struct](server.Client(), server.URL+procedure)
+	msg, _ := structpb.NewStruct(map[string]interface{}{"user": "ann", "password": "hunter2"})
+	if _, err := client.CallUnary(context.Background(), connect.NewRequest(msg)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logged) != 2 || logged[0].Response || !logged[1].Response {
+		t.Fatalf("Expected a request and a response, got %+v", logged)
+	}
+	for _, m := range logged {
+		if m.Procedure != procedure || strings.Contains(string(m.Payload), "hunter2") || !strings.Contains(string(m.Payload), "ann") {
+			t.Errorf("Expected the payload trimmed for %s, got %s", procedure, m.Payload)
+		}
+	}
+}
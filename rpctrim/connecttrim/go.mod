@@ -0,0 +1,11 @@
+module github.com/arun0009/jsontrim/rpctrim/connecttrim
+
+go 1.25.4
+
+replace github.com/arun0009/jsontrim => ../..
+
+require (
+	connectrpc.com/connect v1.21.0
+	github.com/arun0009/jsontrim v0.0.0-00010101000000-000000000000
+	google.golang.org/protobuf v1.36.11
+)
@@ -0,0 +1,59 @@
+// Package gatewaytrim logs grpc-gateway request bodies and response messages
+// as JSON trimmed per procedure by an rpctrim.Resolver. It is a module of its
+// own, so jsontrim itself doesn't depend on grpc-gateway.
+package gatewaytrim
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/arun0009/jsontrim/rpctrim"
+)
+
+// WithLogging returns a ServeMuxOption that passes each request body and
+// response message to log, trimmed for the procedure runtime.RPCMethod
+// reports by r:
+//
+//	mux := runtime.NewServeMux(gatewaytrim.WithLogging(resolver, logRPC))
+//
+// Request bodies are logged as sent, before the gateway decodes them, and
+// are left for it to read; requests without a body (GET) log nothing.
+// Response messages are encoded with protojson, each message of a server
+// stream included.
+func WithLogging(r *rpctrim.Resolver, log rpctrim.LogFunc) runtime.ServeMuxOption {
+	requests := runtime.WithMetadata(func(ctx context.Context, req *http.Request) metadata.MD {
+		procedure, ok := runtime.RPCMethod(ctx)
+		if !ok || req.Body == nil || req.Body == http.NoBody {
+			return nil
+		}
+		body, err := io.ReadAll(req.Body)
+		// The gateway reads what was consumed, then the rest (or the same read error)
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+		if err == nil && len(body) > 0 {
+			r.Log(ctx, log, procedure, false, body)
+		}
+		return nil
+	})
+	responses := runtime.WithForwardResponseOption(func(ctx context.Context, w http.ResponseWriter, resp proto.Message) error {
+		if procedure, ok := runtime.RPCMethod(ctx); ok {
+			if payload, err := protojson.Marshal(resp); err == nil {
+				r.Log(ctx, log, procedure, true, payload)
+			}
+		}
+		return nil
+	})
+	return func(mux *runtime.ServeMux) {
+		requests(mux)
+		responses(mux)
+	}
+}
@@ -0,0 +1,59 @@
+package gatewaytrim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/arun0009/jsontrim"
+	"github.com/arun0009/jsontrim/rpctrim"
+)
+
+func TestWithLogging(t *testing.T) {
+	const procedure = "/test.v1.EchoService/Echo"
+	resolver := rpctrim.NewResolver(jsontrim.New(jsontrim.Config{TotalLimit: 4096}), map[string][]jsontrim.Option{
+		procedure: {jsontrim.WithBlacklist("password")},
+	})
+	var logged []rpctrim.Message
+	mux := runtime.NewServeMux(WithLogging(resolver, func(ctx context.Context, m rpctrim.Message) {
+		logged = append(logged, m)
+	}))
+
+	// What generated handlers do for an rpc with body: "*"
+	err := mux.HandlePath(http.MethodPost, "/v1/echo", func(w http.ResponseWriter, req *http.Request, _ map[string]string) {
+		inbound, outbound := runtime.MarshalerForRequest(mux, req)
+		ctx, err := runtime.AnnotateContext(req.Context(), mux, req, procedure, runtime.WithHTTPPathPattern("/v1/echo"))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, req, err)
+			return
+		}
+		var msg structpb.Struct
+		if err := inbound.NewDecoder(req.Body).Decode(&msg); err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, req, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, req, &msg, mux.GetForwardResponseOptions()...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/echo", strings.NewReader(`{"user":"ann","password":"hunter2"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "hunter2") {
+		t.Fatalf("The gateway should still see the whole body, got %d %s", rec.Code, rec.Body)
+	}
+	if len(logged) != 2 || logged[0].Response || !logged[1].Response {
+		t.Fatalf("Expected a request and a response, got %+v", logged)
+	}
+	for _, m := range logged {
+		if m.Procedure != procedure || strings.Contains(string(m.Payload), "hunter2") || !strings.Contains(string(m.Payload), "ann") {
+			t.Errorf("Expected the payload trimmed for %s, got %s", procedure, m.Payload)
+		}
+	}
+}
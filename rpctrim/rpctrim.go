@@ -0,0 +1,99 @@
+// Package rpctrim picks a Trimmer per RPC procedure, for trimming the request
+// and response JSON that connect-go interceptors and grpc-gateway handlers
+// log. Procedures are full method names as both libraries report them:
+// "/pkg.v1.Service/Method" (connect's Spec().Procedure, grpc's FullMethod,
+// grpc-gateway's runtime.RPCMethod).
+//
+// The package has no dependency on connect-go or grpc-gateway. The
+// connecttrim and gatewaytrim modules under it wire a Resolver into each.
+package rpctrim
+
+import (
+	"context"
+	"strings"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// Message is a request or response logged for a procedure.
+type Message struct {
+	Procedure string
+	Response  bool   // A response rather than a request
+	Payload   []byte // JSON, trimmed for Procedure
+}
+
+// LogFunc receives each trimmed Message. ctx is the call's context.
+type LogFunc func(ctx context.Context, m Message)
+
+// Log trims payload for procedure and passes it to log.
+func (r *Resolver) Log(ctx context.Context, log LogFunc, procedure string, response bool, payload []byte) {
+	log(ctx, Message{Procedure: normalize(procedure), Response: response, Payload: r.Trim(procedure, payload)})
+}
+
+// Resolver derives per-procedure Trimmers from a base Trimmer. Overrides are
+// keyed by full method ("/pkg.Service/Method") or by service
+// ("/pkg.Service/*"). A method gets its service's options first, then its own.
+// Derived Trimmers are built up front, one per override key, so procedure
+// names seen at runtime never grow it. A Resolver is safe for concurrent use.
+type Resolver struct {
+	base     *jsontrim.Trimmer
+	services map[string]*jsontrim.Trimmer // "/pkg.Service" -> Trimmer
+	methods  map[string]*jsontrim.Trimmer // "/pkg.Service/Method" -> Trimmer
+}
+
+// NewResolver returns a Resolver applying overrides on top of base.
+func NewResolver(base *jsontrim.Trimmer, overrides map[string][]jsontrim.Option) *Resolver {
+	r := &Resolver{base: base, services: map[string]*jsontrim.Trimmer{}, methods: map[string]*jsontrim.Trimmer{}}
+	serviceOpts := make(map[string][]jsontrim.Option)
+	methodOpts := make(map[string][]jsontrim.Option)
+	for k, opts := range overrides {
+		k = normalize(k)
+		if service, ok := strings.CutSuffix(k, "/*"); ok {
+			serviceOpts[service] = append(serviceOpts[service], opts...)
+		} else {
+			methodOpts[k] = append(methodOpts[k], opts...)
+		}
+	}
+	for service, opts := range serviceOpts {
+		r.services[service] = base.With(opts...)
+	}
+	for method, opts := range methodOpts {
+		service := serviceOpts[serviceOf(method)]
+		r.methods[method] = base.With(append(service[:len(service):len(service)], opts...)...)
+	}
+	return r
+}
+
+// For returns the Trimmer for procedure.
+func (r *Resolver) For(procedure string) *jsontrim.Trimmer {
+	procedure = normalize(procedure)
+	if t, ok := r.methods[procedure]; ok {
+		return t
+	}
+	if t, ok := r.services[serviceOf(procedure)]; ok {
+		return t
+	}
+	return r.base
+}
+
+// Trim trims a payload logged for procedure, returning it unchanged if it
+// can't be trimmed, so logging never fails because of trimming.
+func (r *Resolver) Trim(procedure string, payload []byte) []byte {
+	return r.For(procedure).TrimOrOriginal(payload)
+}
+
+// serviceOf returns the "/pkg.Service" part of a normalized procedure.
+func serviceOf(procedure string) string {
+	if i := strings.LastIndexByte(procedure, '/'); i > 0 {
+		return procedure[:i]
+	}
+	return procedure
+}
+
+// normalize adds the leading slash some callers leave off.
+func normalize(procedure string) string {
+	if !strings.HasPrefix(procedure, "/") {
+		return "/" + procedure
+	}
+	return procedure
+}
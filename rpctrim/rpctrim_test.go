@@ -0,0 +1,38 @@
+package rpctrim
+
+import (
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+func TestResolver(t *testing.T) {
+	base := jsontrim.New(jsontrim.Config{TotalLimit: 4096, Blacklist: []string{"token"}})
+	r := NewResolver(base, map[string][]jsontrim.Option{
+		"/users.v1.UserService/*":           {jsontrim.WithBlacklist("email")},
+		"users.v1.UserService/CreateUser":   {jsontrim.WithBlacklist("password")},
+		"/billing.v1.BillingService/Charge": {jsontrim.WithBlacklist("card")},
+	})
+	payload := []byte(`{"card":"c","email":"e","name":"n","password":"p","token":"t"}`)
+
+	tests := map[string]string{
+		"/users.v1.UserService/CreateUser":  `{"card":"c","name":"n"}`,
+		"/users.v1.UserService/GetUser":     `{"card":"c","name":"n","password":"p"}`,
+		"/billing.v1.BillingService/Charge": `{"email":"e","name":"n","password":"p"}`,
+		"/billing.v1.BillingService/Refund": `{"card":"c","email":"e","name":"n","password":"p"}`,
+	}
+	for procedure, want := range tests {
+		if got := r.Trim(procedure, payload); string(got) != want {
+			t.Errorf("%s: got %s, want %s", procedure, got, want)
+		}
+	}
+	if r.For("/billing.v1.BillingService/Refund") != base {
+		t.Error("Procedures without overrides should use the base Trimmer")
+	}
+	if r.For("/users.v1.UserService/GetUser") != r.For("users.v1.UserService/ListUsers") {
+		t.Error("Methods without their own overrides should share their service's Trimmer")
+	}
+	if got := r.Trim("/x.Y/Z", []byte(`not json`)); string(got) != `not json` {
+		t.Errorf("Untrimmable payloads should pass through, got %s", got)
+	}
+}
@@ -0,0 +1,157 @@
+// Package sentrytrim trims the free-form data of Sentry events (Extra,
+// Contexts and breadcrumb Data) so events stay under Sentry's 200KB cap
+// and blacklisted values never leave the process:
+//
+//	sentry.Init(sentry.ClientOptions{
+//		BeforeSend: sentrytrim.BeforeSend[sentry.Event, *sentry.EventHint](sentrytrim.Options{
+//			Trimmer: jsontrim.New(jsontrim.Config{Blacklist: []string{"*.password"}}),
+//		}),
+//	})
+//
+// BeforeSend is generic over the event type, so this package doesn't depend on
+// sentry-go. It finds the fields by name. Exceptions, the message, tags and
+// everything else outside those fields are never touched.
+package sentrytrim
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// EventLimit is the maximum size of a Sentry event.
+const EventLimit = 200 << 10
+
+// Options configures BeforeSend.
+type Options struct {
+	Trimmer *jsontrim.Trimmer // Trims each data map; paths are relative to the map, e.g. "password" or "trace.trace_id" (default: FieldLimit 16KB, truncated strings and markers)
+	Limit   int               // Maximum event size in bytes (default: EventLimit)
+}
+
+// BeforeSend returns a BeforeSend hook for events of type E. The Extra map,
+// the Contexts map and each breadcrumb's Data are trimmed as separate
+// documents. When the event is over the limit, they share whatever budget
+// the rest of the event leaves, so small maps are kept whole and the large
+// ones are trimmed. A map that can't be trimmed is left as is, rather than
+// dropping the event and hiding the error it reports.
+func BeforeSend[E, H any](opts Options) func(event *E, hint H) *E {
+	if opts.Trimmer == nil {
+		opts.Trimmer = jsontrim.New(jsontrim.Config{FieldLimit: 16 << 10, TotalLimit: EventLimit, TruncateStrings: true, ReplaceWithMarker: true})
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = EventLimit
+	}
+	return func(event *E, _ H) *E {
+		if event != nil {
+			scrub(reflect.ValueOf(event).Elem(), opts)
+		}
+		return event
+	}
+}
+
+// scrub trims the data fields of the event struct ev in place.
+func scrub(ev reflect.Value, opts Options) {
+	if ev.Kind() != reflect.Struct {
+		return
+	}
+	var sections []section
+	add := func(f reflect.Value) {
+		if !f.IsValid() || f.Len() == 0 {
+			return
+		}
+		if raw, err := json.Marshal(f.Interface()); err == nil {
+			sections = append(sections, section{field: f, raw: raw})
+		}
+	}
+	add(mapField(ev, "Extra"))
+	add(mapField(ev, "Contexts"))
+	if f := ev.FieldByName("Breadcrumbs"); f.IsValid() && f.Kind() == reflect.Slice {
+		for i := 0; i < f.Len(); i++ {
+			if crumb := reflect.Indirect(f.Index(i)); crumb.Kind() == reflect.Struct {
+				add(mapField(crumb, "Data"))
+			}
+		}
+	}
+	if len(sections) == 0 {
+		return
+	}
+
+	whole, err := json.Marshal(ev.Addr().Interface())
+	if err != nil {
+		return
+	}
+	dataSize := 0
+	for _, sec := range sections {
+		dataSize += len(sec.raw)
+	}
+	budget := opts.Limit - (len(whole) - dataSize)
+
+	// Over budget, sections share it fairly: smallest first, each gets its
+	// size or an even split of what's left, whichever is smaller.
+	sort.Slice(sections, func(i, j int) bool { return len(sections[i].raw) < len(sections[j].raw) })
+	for i, sec := range sections {
+		t := opts.Trimmer
+		if budget < dataSize {
+			share := max(2, min(len(sec.raw), budget/(len(sections)-i)))
+			budget -= share
+			if tl := t.Config().TotalLimit; share < tl || tl == 0 {
+				t = t.With(jsontrim.WithTotalLimit(share))
+			}
+		}
+		out, err := t.Trim(sec.raw)
+		if err != nil {
+			continue
+		}
+		var trimmed map[string]interface{}
+		if err := json.Unmarshal(out, &trimmed); err == nil {
+			setMap(sec.field, trimmed)
+		}
+	}
+}
+
+// section is one map field of an event, trimmed as its own document.
+type section struct {
+	field reflect.Value
+	raw   []byte
+}
+
+// mapField returns the named string-keyed map field of struct v, or an
+// invalid Value if there is none.
+func mapField(v reflect.Value, name string) reflect.Value {
+	f := v.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.Map || f.Type().Key().Kind() != reflect.String || !f.CanSet() {
+		return reflect.Value{}
+	}
+	return f
+}
+
+// setMap replaces the map field f with v, converting values to the map's
+// element type (e.g., sentry.Context for Contexts). Values that can't be
+// converted are left out.
+func setMap(f reflect.Value, v interface{}) {
+	if !f.IsValid() || f.Len() == 0 {
+		return
+	}
+	m, _ := v.(map[string]interface{})
+	if m == nil {
+		f.Set(reflect.Zero(f.Type()))
+		return
+	}
+	elem := f.Type().Elem()
+	out := reflect.MakeMapWithSize(f.Type(), len(m))
+	for k, val := range m {
+		rv := reflect.ValueOf(val)
+		switch {
+		case val == nil:
+			rv = reflect.Zero(elem)
+		case rv.Type().ConvertibleTo(elem):
+			rv = rv.Convert(elem)
+		default:
+			continue
+		}
+		out.SetMapIndex(reflect.ValueOf(k).Convert(f.Type().Key()), rv)
+	}
+	f.Set(out)
+}
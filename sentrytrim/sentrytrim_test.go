@@ -0,0 +1,68 @@
+package sentrytrim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/arun0009/jsontrim"
+)
+
+// Mirrors of the sentry-go types BeforeSend works with.
+type (
+	context    map[string]interface{}
+	breadcrumb struct {
+		Message string                 `json:"message"`
+		Data    map[string]interface{} `json:"data,omitempty"`
+	}
+	exception struct {
+		Type, Value string
+	}
+	event struct {
+		Message     string                 `json:"message"`
+		Exception   []exception            `json:"exception"`
+		Extra       map[string]interface{} `json:"extra"`
+		Contexts    map[string]context     `json:"contexts"`
+		Breadcrumbs []*breadcrumb          `json:"breadcrumbs"`
+	}
+	hint struct{}
+)
+
+func TestBeforeSend(t *testing.T) {
+	big := strings.Repeat("x", 4000)
+	ev := &event{
+		Message:   "boom",
+		Exception: []exception{{Type: "panic", Value: big}},
+		Extra:     map[string]interface{}{"password": "secret", "payload": big, "id": 7.0},
+		Contexts:  map[string]context{"trace": {"trace_id": "abc"}, "dump": {"blob": big}},
+		Breadcrumbs: []*breadcrumb{
+			{Message: "kept", Data: map[string]interface{}{"q": "select 1"}},
+			{Message: "big", Data: map[string]interface{}{"body": big + big}},
+		},
+	}
+	before := BeforeSend[event, *hint](Options{
+		Trimmer: jsontrim.New(jsontrim.Config{FieldLimit: 1000, TruncateStrings: true, Blacklist: []string{"password"}, Required: []string{"trace"}}),
+		Limit:   9000,
+	})
+	got := before(ev, nil)
+
+	if got.Message != "boom" || got.Exception[0].Value != big {
+		t.Error("Fields outside the data must not be touched")
+	}
+	if _, ok := got.Extra["password"]; ok {
+		t.Error("Blacklisted extra should be removed")
+	}
+	if got.Extra["id"] != 7.0 || got.Contexts["trace"]["trace_id"] != "abc" {
+		t.Errorf("Small and required data should be kept, got %v %v", got.Extra, got.Contexts)
+	}
+	if got.Breadcrumbs[1].Message != "big" || got.Breadcrumbs[0].Data["q"] != "select 1" {
+		t.Errorf("Breadcrumbs should keep their messages and small data, got %+v %+v", got.Breadcrumbs[0], got.Breadcrumbs[1])
+	}
+	if raw, _ := json.Marshal(got); len(raw) > 9000 {
+		t.Errorf("Event is %d bytes, over the limit", len(raw))
+	}
+
+	if before(nil, nil) != nil {
+		t.Error("A nil event should stay nil")
+	}
+}
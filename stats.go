@@ -0,0 +1,125 @@
+package jsontrim
+
+import (
+	"errors"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxStatsPaths caps the distinct paths a Stats counts.
+const maxStatsPaths = 1024
+
+// Stats aggregates what a Trimmer does across calls: which paths are removed
+// most often, how many bytes trimming saves on average, and how often it
+// fails, e.g. to find the producers worth fixing upstream. Attach it with
+// Config.Stats; Trimmers derived with With share it. It is safe for
+// concurrent use.
+type Stats struct {
+	mu         sync.Mutex
+	calls      int
+	errors     int
+	cannotTrim int
+	saved      int64
+	paths      map[string]int
+	ruleHits   map[string]int
+}
+
+// StatsSnapshot is the state of a Stats at one point in time.
+type StatsSnapshot struct {
+	Calls          int            // Trims attempted
+	Errors         int            // Trims that failed, for any reason
+	CannotTrim     int            // Trims that failed with ErrCannotTrim
+	CannotTrimRate float64        // CannotTrim / Calls
+	AvgBytesSaved  float64        // Mean of InputSize - OutputSize over successful trims
+	TopPaths       []PathCount    // Most often trimmed paths, most frequent first
+	RuleHits       map[string]int // Blacklist entry -> nodes it removed; entries never matched are absent
+}
+
+// PathCount is how many times a path was trimmed. Array indexes are counted
+// together as "*" (e.g. "items.*.payload").
+type PathCount struct {
+	Path  string
+	Count int
+}
+
+// NewStats returns an empty Stats.
+func NewStats() *Stats {
+	return &Stats{paths: make(map[string]int), ruleHits: make(map[string]int)}
+}
+
+// observe records one successful trim.
+func (s *Stats) observe(res TrimResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	s.saved += int64(res.InputSize - res.OutputSize)
+	for _, p := range res.PathsAffected {
+		p = statsPath(p)
+		if _, ok := s.paths[p]; ok || len(s.paths) < maxStatsPaths {
+			s.paths[p]++
+		}
+	}
+	for rule, n := range res.RuleHits {
+		s.ruleHits[rule] += n
+	}
+}
+
+// fail records one failed trim.
+func (s *Stats) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	s.errors++
+	if errors.Is(err, ErrCannotTrim) {
+		s.cannotTrim++
+	}
+}
+
+// Snapshot returns the current aggregates, with the n most trimmed paths
+// (all of them if n <= 0).
+func (s *Stats) Snapshot(n int) StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := StatsSnapshot{Calls: s.calls, Errors: s.errors, CannotTrim: s.cannotTrim, RuleHits: maps.Clone(s.ruleHits)}
+	if s.calls > 0 {
+		snap.CannotTrimRate = float64(s.cannotTrim) / float64(s.calls)
+	}
+	if ok := s.calls - s.errors; ok > 0 {
+		snap.AvgBytesSaved = float64(s.saved) / float64(ok)
+	}
+	for p, c := range s.paths {
+		snap.TopPaths = append(snap.TopPaths, PathCount{Path: p, Count: c})
+	}
+	sort.Slice(snap.TopPaths, func(i, j int) bool {
+		a, b := snap.TopPaths[i], snap.TopPaths[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Path < b.Path
+	})
+	if n > 0 && len(snap.TopPaths) > n {
+		snap.TopPaths = snap.TopPaths[:n]
+	}
+	return snap
+}
+
+// statsPath replaces the array indexes in a dotted path with "*".
+func statsPath(p string) string {
+	parts := strings.Split(p, ".")
+	for i, part := range parts {
+		if part != "" && strings.Trim(part, "0123456789") == "" {
+			parts[i] = "*"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// failed records a failed trim in Config.Stats, if set, and returns err.
+func (t *Trimmer) failed(err error) error {
+	if s := t.current().cfg.Stats; s != nil {
+		s.fail(err)
+	}
+	return err
+}
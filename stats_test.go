@@ -0,0 +1,30 @@
+package jsontrim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	stats := NewStats()
+	tr := New(Config{TotalLimit: 100, FieldLimit: 50, Stats: stats})
+
+	for i := 0; i < 3; i++ {
+		if _, err := tr.Trim([]byte(`{"items":[{"blob":"` + strings.Repeat("b", 60) + `"}],"id":1}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr.Trim([]byte(`{"debug":"` + strings.Repeat("d", 80) + `"}`))
+	tr.With(WithTotalLimit(1)).Trim([]byte(`{"id":123456}`)) // Shares stats; can't trim
+
+	snap := stats.Snapshot(1)
+	if snap.Calls != 5 || snap.Errors != 1 || snap.CannotTrim != 1 || snap.CannotTrimRate != 0.2 {
+		t.Errorf("Unexpected counts %+v", snap)
+	}
+	if len(snap.TopPaths) != 1 || snap.TopPaths[0] != (PathCount{Path: "items.*.blob", Count: 3}) {
+		t.Errorf("Expected items.*.blob most trimmed, got %v", snap.TopPaths)
+	}
+	if snap.AvgBytesSaved <= 60 {
+		t.Errorf("Expected the bytes saved averaged over successful trims, got %v", snap.AvgBytesSaved)
+	}
+}
@@ -0,0 +1,179 @@
+package jsontrim
+
+import (
+	"strconv"
+	"strings"
+)
+
+// strategyAt returns the Strategies entry for path, if any.
+func (t *Trimmer) strategyAt(path []string) (TruncStrategy, bool) {
+	for _, r := range t.strategyRules {
+		if matchParts(r.parts, path) {
+			return r.strategy, true
+		}
+	}
+	return nil, false
+}
+
+// strategyBelow reports whether a Strategies entry lies strictly under path.
+func (t *Trimmer) strategyBelow(path []string) bool {
+	for _, r := range t.strategyRules {
+		if len(r.parts) > len(path) && matchParts(r.parts[:len(path)], path) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeInside handles a removal the strategy picked (sel, a key or "idx:N")
+// in container v at path. If the picked child has its own strategy and still
+// has something in it, that strategy picks what to remove inside it instead,
+// descending as long as subtrees have strategies of their own (levels in
+// between use Strategy). It reports whether it removed something; false
+// leaves the removal to the caller.
+func (t *Trimmer) removeInside(v interface{}, sel string, path []string, over int) bool {
+	child, childPath := childAt(v, sel, path)
+	if childPath == nil {
+		return false
+	}
+	s, ok := t.strategyAt(childPath)
+	if !ok {
+		if !t.strategyBelow(childPath) {
+			return false
+		}
+		s = t.cfg.Strategy
+	}
+	next := selectWithBudget(t.sized(s), child, over)
+	if next == "" {
+		return false
+	}
+	if t.removeInside(child, next, childPath, over) {
+		return true
+	}
+
+	grandchild, grandPath := childAt(child, next, childPath)
+	switch cv := child.(type) {
+	case map[string]interface{}:
+		if t.cfg.ReplaceWithMarker && grandchild != marked {
+			cv[next] = marked
+		} else {
+			delete(cv, next)
+		}
+	case []interface{}:
+		idx, _ := strconv.Atoi(strings.TrimPrefix(next, "idx:"))
+		if t.cfg.ReplaceWithMarker && grandchild != marked {
+			cv[idx] = marked
+		} else {
+			setChild(v, sel, append(cv[:idx:idx], cv[idx+1:]...))
+		}
+	}
+	t.record(grandPath, reasonTotalLimit, grandchild)
+	return true
+}
+
+// childAt returns the child of v selected by sel (a key or "idx:N") and its
+// path, or nil if there is none.
+func childAt(v interface{}, sel string, path []string) (interface{}, []string) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if !strings.HasPrefix(sel, "idx:") {
+			return vv[sel], append(path[:len(path):len(path)], sel)
+		}
+	case []interface{}:
+		if idx, err := strconv.Atoi(strings.TrimPrefix(sel, "idx:")); err == nil && idx >= 0 && idx < len(vv) {
+			return vv[idx], append(path[:len(path):len(path)], strconv.Itoa(idx))
+		}
+	}
+	return nil, nil
+}
+
+// setChild replaces the child of v selected by sel.
+func setChild(v interface{}, sel string, child interface{}) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		vv[sel] = child
+	case []interface{}:
+		if idx, err := strconv.Atoi(strings.TrimPrefix(sel, "idx:")); err == nil {
+			vv[idx] = child
+		}
+	}
+}
+
+// selectWithBudget asks s for the next removal, passing the overage to
+// strategies that take it.
+func selectWithBudget(s TruncStrategy, v interface{}, over int) string {
+	if bs, ok := s.(BudgetStrategy); ok && over > 0 {
+		return bs.SelectWithBudget(v, over)
+	}
+	return s.SelectNextToRemove(v)
+}
+
+// BestFit removes the smallest field or item whose removal gets the
+// document under its limit, so a small overage costs a small field rather
+// than the largest one. When no single removal is enough it removes the
+// largest, as RemoveLargest does. Sizes are estimates.
+type BestFit struct {
+	Size func(v interface{}) int // Ranks entries; set from Config.SizeFunc when nil (default: a byte estimate)
+}
+
+// SelectNextToRemove for BestFit: Without a budget, the largest.
+func (s BestFit) SelectNextToRemove(v interface{}) string {
+	return RemoveLargest(s).SelectNextToRemove(v)
+}
+
+// SelectWithBudget for BestFit: The smallest entry saving at least over.
+func (s BestFit) SelectWithBudget(v interface{}, over int) string {
+	best, bestSize := "", 0
+	consider := func(sel string, size int) {
+		if size >= over && (best == "" || size < bestSize || size == bestSize && sel < best) {
+			best, bestSize = sel, size
+		}
+	}
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, val := range vv {
+			consider(k, len(k)+3+sizeWith(s.Size, val))
+		}
+	case []interface{}:
+		for i, item := range vv {
+			consider("idx:"+strconv.Itoa(i), sizeWith(s.Size, item)+1)
+		}
+	}
+	if best == "" {
+		return RemoveLargest(s).SelectNextToRemove(v)
+	}
+	return best
+}
+
+// sized returns s with Config.SizeFunc filled in, for the built-in strategies
+// that rank by size and have no Size of their own.
+func (t *Trimmer) sized(s TruncStrategy) TruncStrategy {
+	if t.cfg.SizeFunc == nil {
+		return s
+	}
+	switch ss := s.(type) {
+	case RemoveLargest:
+		if ss.Size == nil {
+			return RemoveLargest{Size: t.cfg.SizeFunc}
+		}
+	case BestFit:
+		if ss.Size == nil {
+			return BestFit{Size: t.cfg.SizeFunc}
+		}
+	}
+	return s
+}
+
+// estimate returns the size of v used to rank it against its siblings:
+// SizeFunc if set, otherwise a byte estimate.
+func (t *Trimmer) estimate(v interface{}) int {
+	return sizeWith(t.cfg.SizeFunc, v)
+}
+
+// sizeWith returns size(v), or estimateSize(v) if size is nil.
+func sizeWith(size func(v interface{}) int, v interface{}) int {
+	if size != nil {
+		return size(v)
+	}
+	return estimateSize(v)
+}
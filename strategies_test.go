@@ -0,0 +1,117 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStrategies(t *testing.T) {
+	doc := map[string]interface{}{
+		"events":   []interface{}{"e0-aaaaaaaa", "e1-aaaaaaaa", "e2-aaaaaaaa", "e3-aaaaaaaa", "e4-aaaaaaaa", "e5-aaaaaaaa"},
+		"metadata": map[string]interface{}{"host": "h", "blob": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"},
+	}
+	raw, _ := json.Marshal(doc)
+
+	out, err := New(Config{
+		TotalLimit: 150,
+		Strategy:   FIFO{},
+		Strategies: map[string]TruncStrategy{"events": FIFO{}, "metadata": RemoveLargest{}},
+	}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Events   []string
+		Metadata map[string]string
+	}
+	json.Unmarshal(out, &got)
+	if len(got.Events) == 0 || got.Events[len(got.Events)-1] != "e5-aaaaaaaa" || got.Events[0] == "e0-aaaaaaaa" {
+		t.Errorf("Expected the oldest events dropped first, got %s", out)
+	}
+	if len(out) > 150 {
+		t.Errorf("Output is %d bytes, over the limit", len(out))
+	}
+
+	// Strategies apply below wildcard levels, and within SubtreeLimits
+	raw = []byte(`{"svc":{"a":{"logs":["old","mid","new"]}}}`)
+	out, err = New(Config{
+		TotalLimit:    4096,
+		SubtreeLimits: map[string]int{"svc.a.logs": 14},
+		Strategies:    map[string]TruncStrategy{"svc.*.logs": FIFO{}},
+	}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"svc":{"a":{"logs":["mid","new"]}}}` {
+		t.Errorf("Got %s", out)
+	}
+	out, err = New(Config{TotalLimit: 32, Strategies: map[string]TruncStrategy{"svc.*.logs": FIFO{}}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"svc":{"a":{"logs":["new"]}}}` {
+		t.Errorf("Got %s", out)
+	}
+
+	for i := 0; i < 20; i++ {
+		if sel := (FIFO{}).SelectNextToRemove(map[string]interface{}{"c": 1, "a": 2, "b": 3}); sel != "a" {
+			t.Fatalf("Expected FIFO to pick the first key in output order, got %s", sel)
+		}
+	}
+}
+
+func TestBestFit(t *testing.T) {
+	raw := []byte(`{"big":"` + strings.Repeat("b", 200) + `","mid":"` + strings.Repeat("m", 30) + `","small":"ss","id":1}`)
+	limit := len(raw) - 20
+
+	out, err := New(Config{TotalLimit: limit, FieldLimit: 1000, Strategy: BestFit{}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(out, &got)
+	if _, ok := got["big"]; !ok || got["mid"] != nil || got["small"] != "ss" {
+		t.Errorf("Expected only mid removed, got %s", out)
+	}
+
+	if sel := (BestFit{}).SelectWithBudget([]interface{}{"aaaa", "bb", "cccccc"}, 6); sel != "idx:0" {
+		t.Errorf("Expected the smallest item saving 6, got %s", sel)
+	}
+	if sel := (BestFit{}).SelectWithBudget(map[string]interface{}{"a": "x", "b": "yy"}, 100); sel != "b" {
+		t.Errorf("Expected the largest when nothing is enough, got %s", sel)
+	}
+}
+
+func TestSizeFunc(t *testing.T) {
+	var words func(v interface{}) int // Count words, like a token budget
+	words = func(v interface{}) int {
+		n := 1
+		switch vv := v.(type) {
+		case string:
+			n = len(strings.Fields(vv))
+		case []interface{}:
+			for _, item := range vv {
+				n += words(item)
+			}
+		case map[string]interface{}:
+			for _, val := range vv {
+				n += 1 + words(val)
+			}
+		}
+		return n
+	}
+	raw := []byte(`{"msg":"a b c d e f g h","id":"` + strings.Repeat("x", 100) + `","rows":[1,2,3]}`)
+
+	out, err := New(Config{FieldLimit: 5, TotalLimit: 1000, SizeFunc: words}).Trim(raw)
+	if err != nil || strings.Contains(string(out), "msg") || !strings.Contains(string(out), `"id"`) {
+		t.Errorf("Expected FieldLimit counted in words, got %s (%v)", out, err)
+	}
+	out, err = New(Config{FieldLimit: 1000, TotalLimit: 8, SizeFunc: words}).Trim(raw)
+	if err != nil || strings.Contains(string(out), "msg") || !strings.Contains(string(out), "rows") {
+		t.Errorf("Expected TotalLimit counted in words and msg removed first, got %s (%v)", out, err)
+	}
+	if out, err = New(Config{FieldLimit: 1000, TotalLimit: 55}).Trim(raw); err != nil || strings.Contains(string(out), `"id"`) {
+		t.Errorf("Expected the longest field removed by default, got %s (%v)", out, err)
+	}
+}
@@ -0,0 +1,250 @@
+package jsontrim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// summaryKey is the field written in the trailing NDJSON summary line.
+const summaryKey = "trimmed_lines"
+
+// TrimStreamShared trims NDJSON from r into w, treating totalBudget as the limit
+// for the whole stream rather than per line. Lines are trimmed progressively;
+// each one is capped at TotalLimit and at whatever budget is left. Once the
+// budget is exhausted, remaining lines are dropped and a final
+// {"trimmed_lines":N} line reports how many were lost.
+func (t *Trimmer) TrimStreamShared(r io.Reader, w io.Writer, totalBudget int) error {
+	t = t.current()
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	// Reserve room for the summary line so it always fits once lines get dropped.
+	reserve := len(fmt.Sprintf(`{"%s":%d}`, summaryKey, int64(1)<<62)) + 1
+	budget := totalBudget - reserve
+	if budget < 0 {
+		budget = totalBudget
+		reserve = 0
+	}
+
+	dropped := 0
+	lineNo := 0
+	for {
+		line, readErr := br.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			lineNo++
+			// Each line costs its trimmed size plus the newline.
+			limit := min(t.cfg.TotalLimit, budget-1)
+			if limit <= 0 {
+				dropped++
+			} else {
+				out, err := t.withTotalLimit(limit).Trim(line)
+				switch {
+				case errors.Is(err, ErrCannotTrim):
+					dropped++
+				case err != nil:
+					return fmt.Errorf("line %d: %w", lineNo, err)
+				default:
+					if _, err := bw.Write(append(out, '\n')); err != nil {
+						return err
+					}
+					budget -= t.measure(out) + 1
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if dropped > 0 && reserve > 0 {
+		if _, err := bw.WriteString(`{"` + summaryKey + `":` + strconv.Itoa(dropped) + "}\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// TrimStream trims a single JSON document from r into w without ever
+// materializing the tree, for dump files larger than memory. Tokens are read
+// with json.Decoder and written as they arrive:
+//
+//   - Blacklist and Rename are applied by path as keys are seen.
+//   - Scalars go through the usual field trimming (FieldLimit,
+//     TruncateStrings, Transformers, ...). Containers are never buffered, so
+//     FieldLimit, Transformers and Atomic don't apply to them as a whole.
+//   - TotalLimit is enforced greedily in document order: an element is written
+//     only if it fits the remaining budget, with room reserved for the closing
+//     brackets of every open container. Strategy, Required, Hooks and Codec
+//     are not used.
+//
+// Memory use is bounded by the largest single scalar plus the nesting depth.
+// If not even the root fits, nothing is written and ErrCannotTrim is returned.
+func (t *Trimmer) TrimStream(r io.Reader, w io.Writer) error {
+	t = t.current()
+	dec := json.NewDecoder(r)
+	dec.UseNumber() // Numbers pass through verbatim
+	st := &tokenStream{t: t, dec: dec, w: bufio.NewWriter(w), budget: t.cfg.TotalLimit}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	written, err := st.value(tok, 1, nil, nil)
+	if err != nil {
+		return err
+	}
+	if !written {
+		return ErrCannotTrim
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after JSON document")
+		}
+		return err
+	}
+	return st.w.Flush()
+}
+
+// tokenStream is the state of one TrimStream call.
+type tokenStream struct {
+	t       *Trimmer
+	dec     *json.Decoder
+	w       *bufio.Writer
+	budget  int // Output units left
+	reserve int // Closing brackets owed to open containers
+}
+
+// fits reports whether b can be written while keeping the closers reserved.
+func (s *tokenStream) fits(b []byte) bool {
+	return s.t.measure(b) <= s.budget-s.reserve
+}
+
+func (s *tokenStream) write(b []byte) error {
+	s.budget -= s.t.measure(b)
+	_, err := s.w.Write(b)
+	return err
+}
+
+// value writes prefix (separator and key) followed by the value starting with
+// tok, or consumes the value without writing anything if it is dropped.
+func (s *tokenStream) value(tok json.Token, depth int, path []string, prefix []byte) (bool, error) {
+	delim, isDelim := tok.(json.Delim)
+	if (len(path) > 0 && s.t.matchesBlacklist(path)) || (isDelim && depth > s.t.cfg.MaxDepth) {
+		if isDelim {
+			if err := s.skip(); err != nil {
+				return false, err
+			}
+		}
+		if !s.t.cfg.ReplaceWithMarker {
+			return false, nil
+		}
+		tok, isDelim = Marker, false
+	}
+	if !isDelim {
+		return s.scalar(tok, depth, path, prefix)
+	}
+
+	closer := byte('}')
+	if delim == '[' {
+		closer = ']'
+	}
+	open := append(prefix, byte(delim))
+	if !s.fits(append(open, closer)) {
+		return false, s.skip()
+	}
+	if err := s.write(open); err != nil {
+		return false, err
+	}
+	s.reserve++
+
+	var sep []byte
+	for i := 0; ; i++ {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return false, err
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			break
+		}
+
+		var childPath []string
+		var childPrefix []byte
+		if delim == '{' {
+			key := tok.(string) // json.Decoder guarantees a string key here
+			childPath = append(path, key)
+			if newKey, ok := s.t.renameKey(childPath); ok {
+				key = newKey
+			}
+			encodedKey, _ := json.Marshal(key)
+			childPrefix = append(append(sep, encodedKey...), ':')
+			if tok, err = s.dec.Token(); err != nil {
+				return false, err
+			}
+		} else {
+			childPath = append(path, strconv.Itoa(i))
+			childPrefix = sep
+		}
+
+		written, err := s.value(tok, depth+1, childPath, childPrefix)
+		if err != nil {
+			return false, err
+		}
+		if written {
+			sep = []byte{','}
+		}
+	}
+
+	s.reserve--
+	return true, s.write([]byte{closer})
+}
+
+// scalar trims a single non-container token and writes it if it still fits.
+func (s *tokenStream) scalar(tok json.Token, depth int, path []string, prefix []byte) (bool, error) {
+	v := s.t.trimFields(tok, depth, path)
+	if v == removed {
+		return false, nil // Dropped by field trimming
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+	b := append(prefix, encoded...)
+	if !s.fits(b) {
+		return false, nil
+	}
+	return true, s.write(b)
+}
+
+// skip consumes the rest of a container whose opening delimiter was read.
+func (s *tokenStream) skip() error {
+	for open := 1; open > 0; {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			open++
+		case json.Delim('}'), json.Delim(']'):
+			open--
+		}
+	}
+	return nil
+}
+
+// withTotalLimit returns a shallow copy of t with a different TotalLimit.
+func (t *Trimmer) withTotalLimit(limit int) *Trimmer {
+	cp := *t.current()
+	cp.live = nil
+	cp.cfg.TotalLimit = limit
+	return &cp
+}
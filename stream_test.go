@@ -0,0 +1,96 @@
+package jsontrim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTrimStreamShared(t *testing.T) {
+	var in strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&in, `{"seq":%d,"msg":"%s"}`+"\n", i, strings.Repeat("x", 40))
+	}
+
+	var out bytes.Buffer
+	trimmer := New(Config{TotalLimit: 1024})
+	if err := trimmer.TrimStreamShared(strings.NewReader(in.String()), &out, 500); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() > 500 {
+		t.Errorf("Stream over shared budget: %d > 500", out.Len())
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var summary map[string]int
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+		t.Fatalf("Last line is not JSON: %v", err)
+	}
+	if kept := len(lines) - 1; summary[summaryKey]+kept != 50 {
+		t.Errorf("Expected kept+dropped == 50, got %d+%d", kept, summary[summaryKey])
+	}
+}
+
+func TestTrimStreamSharedUnderBudget(t *testing.T) {
+	in := `{"a":1}` + "\n\n" + `{"b":2}` + "\n"
+	var out bytes.Buffer
+	if err := New(Config{}).TrimStreamShared(strings.NewReader(in), &out, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != `{"a":1}`+"\n"+`{"b":2}`+"\n" {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+}
+
+func TestTrimStream(t *testing.T) {
+	var in strings.Builder
+	in.WriteString(`{"meta":{"id":7,"token":"secret"},"events":[`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			in.WriteString(",")
+		}
+		fmt.Fprintf(&in, `{"seq":%d,"msg":"%s"}`, i, strings.Repeat("x", 40))
+	}
+	in.WriteString(`],"blob":"` + strings.Repeat("b", 100) + `"}`)
+
+	var out bytes.Buffer
+	trimmer := New(Config{TotalLimit: 300, FieldLimit: 50, Blacklist: []string{"meta.token"}})
+	if err := trimmer.TrimStream(strings.NewReader(in.String()), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() > 300 {
+		t.Errorf("Output over limit: %d", out.Len())
+	}
+
+	var got struct {
+		Meta   map[string]interface{}
+		Events []map[string]interface{}
+		Blob   *string
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if _, ok := got.Meta["token"]; ok || got.Meta["id"] != 7.0 {
+		t.Errorf("Unexpected meta: %v", got.Meta)
+	}
+	if len(got.Events) == 0 || got.Events[0]["seq"] != 0.0 {
+		t.Errorf("Expected leading events to be kept in order, got %v", got.Events)
+	}
+	if got.Blob != nil {
+		t.Error("Oversized string should be dropped by FieldLimit")
+	}
+}
+
+func TestTrimStreamRootTooLarge(t *testing.T) {
+	var out bytes.Buffer
+	err := New(Config{TotalLimit: 1}).TrimStream(strings.NewReader(`{"a":1}`), &out)
+	if !errors.Is(err, ErrCannotTrim) || out.Len() != 0 {
+		t.Errorf("Expected ErrCannotTrim and no output, got %v, %q", err, out.String())
+	}
+	if err := New(Config{}).TrimStream(strings.NewReader(`{} {}`), &out); err == nil {
+		t.Error("Expected error for trailing data")
+	}
+}
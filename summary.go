@@ -0,0 +1,129 @@
+package jsontrim
+
+import "sort"
+
+// summarySample caps the sample strings and array items Summarize includes.
+const (
+	summarySampleLen   = 32
+	summarySampleItems = 3
+)
+
+// Summarize describes raw instead of trimming it, for documents too large to
+// trim meaningfully: its type and size, and for an object each top-level
+// field's type, size, length (arrays and objects) and a short sample value,
+// largest fields first, as many as fit TotalLimit. Fields that don't fit are
+// counted under "omitted". Blacklisted and non-whitelisted paths are stripped
+// first, so samples never show them:
+//
+//	{"type":"object","size":48213,"length":3,"fields":{
+//	  "items":{"type":"array","size":48100,"length":500,"sample":[1,2,3]},
+//	  "name":{"type":"string","size":12,"sample":"checkout"}}}
+func (t *Trimmer) Summarize(raw []byte) ([]byte, error) {
+	t = t.current()
+	if t.err != nil {
+		return nil, t.err
+	}
+	v, err := t.cfg.Codec.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	v = t.stripBlacklisted(v)
+
+	summary := t.describe(v)
+	if m, ok := v.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(m))
+		sizes := make(map[string]int, len(m))
+		for k, val := range m {
+			keys = append(keys, k)
+			sizes[k] = t.sizeOf(val)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if sizes[keys[i]] != sizes[keys[j]] {
+				return sizes[keys[i]] > sizes[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+
+		fields := make(map[string]interface{})
+		summary["fields"] = fields
+		summary["omitted"] = len(keys) // Reserves room for the final count
+		omitted := 0
+		for _, k := range keys {
+			fields[k] = t.describe(m[k])
+			if encoded, err := t.encode(summary); err != nil || t.measure(encoded) > t.cfg.TotalLimit {
+				delete(fields, k)
+				omitted++
+			}
+		}
+		if summary["omitted"] = omitted; omitted == 0 {
+			delete(summary, "omitted")
+		}
+	}
+
+	out, err := t.encode(summary)
+	if err != nil {
+		return nil, err
+	}
+	if t.measure(out) > t.cfg.TotalLimit {
+		return nil, ErrCannotTrim
+	}
+	return out, nil
+}
+
+// describe returns the summary entry for v, without nested fields.
+func (t *Trimmer) describe(v interface{}) map[string]interface{} {
+	d := map[string]interface{}{"type": typeName(v), "size": t.sizeOf(v)}
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		d["length"] = len(vv)
+	case []interface{}:
+		d["length"] = len(vv)
+		var sample []interface{}
+		for _, item := range vv {
+			if len(sample) == summarySampleItems {
+				break
+			}
+			if s, ok := t.sampleOf(item); ok {
+				sample = append(sample, s)
+			}
+		}
+		if len(sample) > 0 {
+			d["sample"] = sample
+		}
+	default:
+		if s, ok := t.sampleOf(v); ok {
+			d["sample"] = s
+		}
+	}
+	return d
+}
+
+// sampleOf returns a short stand-in for the scalar v; containers have none.
+func (t *Trimmer) sampleOf(v interface{}) (interface{}, bool) {
+	switch vv := v.(type) {
+	case map[string]interface{}, []interface{}:
+		return nil, false
+	case string:
+		if t.strLen(vv) > summarySampleLen {
+			return t.truncate(vv, summarySampleLen) + "...", true
+		}
+	}
+	return v, true
+}
+
+// typeName is the JSON type of v.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return "number"
+}
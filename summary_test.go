@@ -0,0 +1,51 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	items := make([]interface{}, 500)
+	for i := range items {
+		items[i] = i
+	}
+	raw, _ := json.Marshal(map[string]interface{}{
+		"items": items, "name": strings.Repeat("n", 100), "ok": true, "password": "hunter2",
+		"meta": map[string]interface{}{"a": 1, "b": 2},
+	})
+
+	out, err := New(Config{TotalLimit: 400, Blacklist: []string{"password"}}).Summarize(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 400 || strings.Contains(string(out), "hunter2") {
+		t.Fatalf("Expected a summary under 400 bytes without blacklisted values, got %s", out)
+	}
+	var got struct {
+		Type   string
+		Length int
+		Fields map[string]struct {
+			Type   string
+			Size   int
+			Length int
+			Sample interface{}
+		}
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	items2, name := got.Fields["items"], got.Fields["name"]
+	if got.Type != "object" || got.Length != 4 || items2.Type != "array" || items2.Length != 500 || items2.Size < 1000 {
+		t.Errorf("Unexpected summary %s", out)
+	}
+	if s, _ := name.Sample.(string); len(s) != summarySampleLen+3 {
+		t.Errorf("Expected a shortened sample, got %v", name.Sample)
+	}
+
+	out, _ = New(Config{TotalLimit: 150}).Summarize(raw)
+	if !strings.Contains(string(out), `"omitted":`) || len(out) > 150 {
+		t.Errorf("Expected fields that don't fit counted as omitted, got %s", out)
+	}
+}
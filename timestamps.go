@@ -0,0 +1,266 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the string forms recognized as timestamps.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// timestampKeys are key names (or suffixes) whose numeric values are taken
+// as epoch timestamps.
+var timestampKeys = []string{"ts", "time", "timestamp", "@timestamp", "date", "datetime", "created", "updated", "_at", "_ts", "_time"}
+
+// isTimestamp reports whether the value at path is a timestamp protected by
+// ProtectTimestamps or TimestampPaths.
+func (t *Trimmer) isTimestamp(path []string, v interface{}) bool {
+	if len(t.cfg.TimestampPaths) > 0 && matchesAnyPattern(t.cfg.TimestampPaths, path) {
+		return true
+	}
+	if !t.cfg.ProtectTimestamps {
+		return false
+	}
+	switch vv := v.(type) {
+	case string:
+		return looksLikeTimestamp(vv)
+	case float64:
+		return len(path) > 0 && timestampKey(path[len(path)-1]) && plausibleEpoch(vv)
+	case json.Number:
+		f, err := vv.Float64()
+		return err == nil && len(path) > 0 && timestampKey(path[len(path)-1]) && plausibleEpoch(f)
+	}
+	return false
+}
+
+// looksLikeTimestamp reports whether s parses as one of timestampLayouts.
+func looksLikeTimestamp(s string) bool {
+	if len(s) < 19 || len(s) > 40 || s[4] != '-' && !strings.Contains(s, ",") {
+		return false
+	}
+	for _, layout := range timestampLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// timestampKey reports whether a key names a timestamp.
+func timestampKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range timestampKeys {
+		if key == k || (strings.HasPrefix(k, "_") && strings.HasSuffix(key, k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// plausibleEpoch reports whether f is an epoch time in seconds,
+// milliseconds, microseconds or nanoseconds between 2001 and 2286.
+func plausibleEpoch(f float64) bool {
+	for _, scale := range []float64{1, 1e3, 1e6, 1e9} {
+		if f >= 1e9*scale && f < 1e10*scale {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutTimestamps returns the top-level entries of v that aren't
+// timestamps, so they are removed first. It reports false when there is
+// nothing to hide or nothing else left to remove.
+func (t *Trimmer) withoutTimestamps(v interface{}) (map[string]interface{}, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	candidates := make(map[string]interface{}, len(m))
+	for k, val := range m {
+		if !t.isTimestamp([]string{k}, val) {
+			candidates[k] = val
+		}
+	}
+	if len(candidates) == 0 || len(candidates) == len(m) {
+		return nil, false
+	}
+	return candidates, true
+}
+
+// Formats for NormalizeTimestamps besides time layouts.
+const (
+	UnixSeconds = "unix"
+	UnixMillis  = "unix_ms"
+)
+
+// NormalizeTimestamps rewrites recognized timestamps into one format:
+// UnixMillis (the default), UnixSeconds, or any time layout such as
+// time.RFC3339, in UTC. Strings in RFC3339-like and RFC1123 forms are recognized, as
+// are epoch numbers (seconds to nanoseconds) under timestamp-like keys such
+// as "ts" or "created_at". With Paths set only values at those paths are
+// rewritten.
+type NormalizeTimestamps struct {
+	Format string   // (default: UnixMillis)
+	Paths  []string // Optional paths to restrict normalization to
+}
+
+// Transform implements Transformer.
+func (n NormalizeTimestamps) Transform(path []string, v interface{}) interface{} {
+	if len(n.Paths) > 0 && !matchesAnyPattern(n.Paths, path) {
+		return v
+	}
+	var (
+		ts time.Time
+		ok bool
+	)
+	switch vv := v.(type) {
+	case string:
+		if !looksLikeTimestamp(vv) {
+			return v
+		}
+		for _, layout := range timestampLayouts {
+			var err error
+			if ts, err = time.Parse(layout, vv); err == nil {
+				break
+			}
+		}
+	case float64:
+		if ts, ok = epochAt(path, vv); !ok {
+			return v
+		}
+	case json.Number:
+		f, err := vv.Float64()
+		if err != nil {
+			return v
+		}
+		if ts, ok = epochAt(path, f); !ok {
+			return v
+		}
+	default:
+		return v
+	}
+
+	switch n.Format {
+	case "", UnixMillis:
+		return epochValue(v, ts.UnixMilli())
+	case UnixSeconds:
+		return epochValue(v, ts.Unix())
+	default:
+		return ts.UTC().Format(n.Format)
+	}
+}
+
+// epochAt converts f to a time if it's a plausible epoch under a
+// timestamp-like key, inferring the unit from its magnitude.
+func epochAt(path []string, f float64) (time.Time, bool) {
+	if len(path) == 0 || !timestampKey(path[len(path)-1]) {
+		return time.Time{}, false
+	}
+	return epochTime(f)
+}
+
+// epochTime converts f to a time if it's a plausible epoch, inferring the
+// unit from its magnitude.
+func epochTime(f float64) (time.Time, bool) {
+	if !plausibleEpoch(f) {
+		return time.Time{}, false
+	}
+	switch {
+	case f >= 1e18:
+		return time.Unix(0, int64(f)), true
+	case f >= 1e15:
+		return time.UnixMicro(int64(f)), true
+	case f >= 1e12:
+		return time.UnixMilli(int64(f)), true
+	}
+	return time.Unix(0, int64(f*1e9)), true
+}
+
+// epochValue returns n as the number type of the original value.
+func epochValue(orig interface{}, n int64) interface{} {
+	if _, ok := orig.(json.Number); ok {
+		return json.Number(strconv.FormatInt(n, 10))
+	}
+	return float64(n)
+}
+
+// KeepNewest evicts the oldest elements of arrays of objects first, going by
+// the timestamp under Key (RFC3339-like strings or epoch numbers), so a
+// trimmed events list keeps the most recent events rather than whatever the
+// size heuristic picks. Elements without a readable timestamp go before any
+// dated one, earliest index first. Objects are left to Fallback.
+type KeepNewest struct {
+	Key      string
+	Fallback TruncStrategy // (default: RemoveLargest)
+}
+
+// SelectNextToRemove for KeepNewest: The oldest element.
+func (s KeepNewest) SelectNextToRemove(v interface{}) string {
+	arr, ok := v.([]interface{})
+	if !ok {
+		fallback := s.Fallback
+		if fallback == nil {
+			fallback = RemoveLargest{}
+		}
+		return fallback.SelectNextToRemove(v)
+	}
+	if order := s.RemovalOrder(arr); len(order) > 0 {
+		return "idx:" + strconv.Itoa(order[0])
+	}
+	return ""
+}
+
+// RemovalOrder for KeepNewest: Undated elements, then oldest first; ties keep
+// index order.
+func (s KeepNewest) RemovalOrder(arr []interface{}) []int {
+	times := make([]time.Time, len(arr))
+	dated := make([]bool, len(arr))
+	order := make([]int, len(arr))
+	for i, item := range arr {
+		order[i] = i
+		if m, ok := item.(map[string]interface{}); ok {
+			times[i], dated[i] = parseTimestamp(m[s.Key])
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if dated[i] != dated[j] {
+			return !dated[i]
+		}
+		return times[i].Before(times[j])
+	})
+	return order
+}
+
+// parseTimestamp reads v as a timestamp: a string in one of
+// timestampLayouts or a plausible epoch number.
+func parseTimestamp(v interface{}) (time.Time, bool) {
+	switch vv := v.(type) {
+	case string:
+		if looksLikeTimestamp(vv) {
+			for _, layout := range timestampLayouts {
+				if ts, err := time.Parse(layout, vv); err == nil {
+					return ts, true
+				}
+			}
+		}
+	case float64:
+		return epochTime(vv)
+	case json.Number:
+		if f, err := vv.Float64(); err == nil {
+			return epochTime(f)
+		}
+	}
+	return time.Time{}, false
+}
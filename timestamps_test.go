@@ -0,0 +1,87 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProtectTimestamps(t *testing.T) {
+	raw := []byte(`{"created_at":1712345678901,"logged":"2024-04-05T19:34:38.123456789+02:00","count":1712345678901,"msg":"` + strings.Repeat("m", 40) + `","when":"yesterday"}`)
+	out, err := New(Config{FieldLimit: 20, TotalLimit: 4096, TruncateStrings: true, ProtectTimestamps: true}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(out, &got)
+	if got["logged"] != "2024-04-05T19:34:38.123456789+02:00" || got["created_at"] != 1712345678901.0 {
+		t.Errorf("Timestamps should be kept whole, got %s", out)
+	}
+	if len(got["msg"].(string)) > 20 {
+		t.Errorf("Other strings should still be truncated, got %s", out)
+	}
+
+	// Over TotalLimit, timestamps are removed last, even when they're the largest
+	raw = []byte(`{"a":"aaaaaaaaaa","b":"bbbbbbbbbbbbbbb","seen":"2024-04-05 19:34:38"}`)
+	out, err = New(Config{TotalLimit: 40, ProtectTimestamps: true}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"seen":"2024-04-05 19:34:38"}` {
+		t.Errorf("Got %s", out)
+	}
+
+	out, err = New(Config{FieldLimit: 5, TimestampPaths: []string{"t"}}).Trim([]byte(`{"t":"05/04/2024 19:34","u":"05/04/2024 19:34"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"t":"05/04/2024 19:34"}` {
+		t.Errorf("TimestampPaths should be protected, got %s", out)
+	}
+}
+
+func TestNormalizeTimestamps(t *testing.T) {
+	raw := []byte(`{"ts":1712345678,"created_at":"2024-04-05T19:34:38.5Z","sent":"Fri, 05 Apr 2024 19:34:38 +0000","count":1712345678,"note":"2024"}`)
+	tests := []struct {
+		format, want string
+	}{
+		{"", `{"count":1712345678,"created_at":1712345678500,"note":"2024","sent":1712345678000,"ts":1712345678000}`},
+		{UnixSeconds, `{"count":1712345678,"created_at":1712345678,"note":"2024","sent":1712345678,"ts":1712345678}`},
+		{time.RFC3339, `{"count":1712345678,"created_at":"2024-04-05T19:34:38Z","note":"2024","sent":"2024-04-05T19:34:38Z","ts":"2024-04-05T19:34:38Z"}`},
+	}
+	for _, tt := range tests {
+		tr := New(Config{TotalLimit: 4096, Transformers: []Transformer{NormalizeTimestamps{Format: tt.format}}})
+		out, err := tr.Trim(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.format, out, tt.want)
+		}
+	}
+}
+
+func TestKeepNewest(t *testing.T) {
+	raw := []byte(`{"id":"run-1","events":[` +
+		`{"ts":"2026-01-01T00:00:03Z","msg":"c"},` +
+		`{"ts":"2026-01-01T00:00:01Z","msg":"a"},` +
+		`{"msg":"undated"},` +
+		`{"ts":1767225602000,"msg":"b"}]}`)
+	limit := len(`{"events":[{"msg":"c","ts":"2026-01-01T00:00:03Z"},{"msg":"b","ts":1767225602000}],"id":"run-1"}`)
+	trimmer := New(Config{TotalLimit: limit, Strategies: map[string]TruncStrategy{"events": KeepNewest{Key: "ts"}}})
+	out, err := trimmer.Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"events":[{"msg":"c","ts":"2026-01-01T00:00:03Z"},{"msg":"b","ts":1767225602000}],"id":"run-1"}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+
+	if order := (KeepNewest{Key: "ts"}).RemovalOrder([]interface{}{
+		map[string]interface{}{"ts": 1767225603.0}, "x", map[string]interface{}{"ts": 1767225601.0},
+	}); !reflect.DeepEqual(order, []int{1, 2, 0}) {
+		t.Errorf("Expected undated, then oldest first, got %v", order)
+	}
+}
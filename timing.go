@@ -0,0 +1,65 @@
+package jsontrim
+
+import "time"
+
+// PhaseTimings is how long each phase of one trim took. Hooks aren't counted.
+type PhaseTimings struct {
+	Decode    time.Duration // Decoding, including Blacklist when it's applied while decoding
+	Blacklist time.Duration // Keys, Blacklist, Whitelist and DropIf
+	FieldTrim time.Duration // FieldLimit, MaxDepth, FieldHooks and Transformers
+	Enforce   time.Duration // SubtreeLimits, MaxFields, Passes, Weights, TotalLimit and Required
+	Encode    time.Duration
+}
+
+// Total is the sum of the phases.
+func (p PhaseTimings) Total() time.Duration {
+	return p.Decode + p.Blacklist + p.FieldTrim + p.Enforce + p.Encode
+}
+
+// timesPhases reports whether a trim collecting a result (or not) times its phases.
+func (t *Trimmer) timesPhases(collect bool) bool {
+	return collect || t.cfg.OnTimings != nil || t.cfg.Hooks.AfterTrim != nil
+}
+
+// startPhase returns the start time of the next phase, or the zero time when
+// phases aren't timed.
+func (t *Trimmer) startPhase() time.Time {
+	if t.timings == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// phase names a PhaseTimings field.
+type phase int
+
+const (
+	phaseDecode phase = iota
+	phaseBlacklist
+	phaseFieldTrim
+	phaseEnforce
+	phaseEncode
+)
+
+// endPhase adds the time since start to phase p and returns the start of the
+// next phase.
+func (t *Trimmer) endPhase(p phase, start time.Time) time.Time {
+	if t.timings == nil {
+		return start
+	}
+	now := time.Now()
+	d := now.Sub(start)
+	switch p {
+	case phaseDecode:
+		t.timings.Decode += d
+	case phaseBlacklist:
+		t.timings.Blacklist += d
+	case phaseFieldTrim:
+		t.timings.FieldTrim += d
+	case phaseEnforce:
+		t.timings.Enforce += d
+	case phaseEncode:
+		t.timings.Encode += d
+	}
+	return now
+}
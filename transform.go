@@ -0,0 +1,560 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StackTrace shortens stack traces found in string values. It keeps the
+// exception/panic message and the first Frames frames of each trace (Java and
+// Go), or the last Frames frames for Python tracebacks, which print the
+// innermost call last. Blind prefix truncation usually cuts exactly the part
+// that matters; this keeps it.
+type StackTrace struct {
+	Frames int // Frames to keep per trace (default: 5)
+}
+
+// traceLine tags a line of a stack trace with the frame it belongs to.
+type traceLine struct {
+	section int // Trace (or "Caused by"/goroutine block) the line belongs to
+	frame   int // Frame index within the section, -1 for non-frame lines
+}
+
+// Transform implements Transformer.
+func (s StackTrace) Transform(path []string, v interface{}) interface{} {
+	str, ok := v.(string)
+	if !ok || !strings.Contains(str, "\n") {
+		return v
+	}
+	keep := s.Frames
+	if keep <= 0 {
+		keep = 5
+	}
+
+	lines := strings.Split(str, "\n")
+	var tags []traceLine
+	fromEnd := false
+	switch {
+	case strings.Contains(str, "Traceback (most recent call last):"):
+		tags = tagPythonTrace(lines)
+		fromEnd = true
+	case strings.Contains(str, "goroutine ") && strings.Contains(str, ".go:"):
+		tags = tagGoTrace(lines)
+	default:
+		tags = tagJavaTrace(lines)
+	}
+
+	totals := map[int]int{}
+	for _, tag := range tags {
+		if tag.frame >= 0 && tag.frame+1 > totals[tag.section] {
+			totals[tag.section] = tag.frame + 1
+		}
+	}
+
+	out := make([]string, 0, len(lines))
+	omitted, lastOmitted := 0, -1
+	flush := func() {
+		if omitted > 0 {
+			out = append(out, "\t... "+strconv.Itoa(omitted)+" frames omitted")
+			omitted, lastOmitted = 0, -1
+		}
+	}
+	for i, line := range lines {
+		tag := tags[i]
+		kept := tag.frame < 0
+		if !kept {
+			if fromEnd {
+				kept = tag.frame >= totals[tag.section]-keep
+			} else {
+				kept = tag.frame < keep
+			}
+		}
+		if kept {
+			flush()
+			out = append(out, line)
+			continue
+		}
+		if tag.frame != lastOmitted {
+			omitted++
+			lastOmitted = tag.frame
+		}
+	}
+	flush()
+
+	if len(out) == len(lines) {
+		return v
+	}
+	return strings.Join(out, "\n")
+}
+
+// tagJavaTrace marks "at ..." lines as frames. Every "Caused by:" (or other
+// non-frame line) starts a new section, so each cause keeps its own frames.
+func tagJavaTrace(lines []string) []traceLine {
+	tags := make([]traceLine, len(lines))
+	section, frame := 0, 0
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "at ") {
+			tags[i] = traceLine{section: section, frame: frame}
+			frame++
+			continue
+		}
+		if frame > 0 {
+			section++
+			frame = 0
+		}
+		tags[i] = traceLine{section: section, frame: -1}
+	}
+	return tags
+}
+
+// tagGoTrace marks the function/file line pairs inside "goroutine N [...]:"
+// blocks as frames.
+func tagGoTrace(lines []string) []traceLine {
+	tags := make([]traceLine, len(lines))
+	section, frame := 0, -1
+	inBlock := false
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "goroutine ") && strings.HasSuffix(line, ":"):
+			section++
+			frame = -1
+			inBlock = true
+			tags[i] = traceLine{section: section, frame: -1}
+		case inBlock && strings.TrimSpace(line) == "":
+			inBlock = false
+			tags[i] = traceLine{section: section, frame: -1}
+		case inBlock && strings.HasPrefix(line, "\t") && frame >= 0:
+			tags[i] = traceLine{section: section, frame: frame}
+		case inBlock:
+			frame++
+			tags[i] = traceLine{section: section, frame: frame}
+		default:
+			tags[i] = traceLine{section: section, frame: -1}
+		}
+	}
+	return tags
+}
+
+// tagPythonTrace marks `File "..."` lines and their indented source lines as
+// frames within each "Traceback (most recent call last):" block.
+func tagPythonTrace(lines []string) []traceLine {
+	tags := make([]traceLine, len(lines))
+	section, frame := 0, -1
+	inBlock := false
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "Traceback (most recent call last):"):
+			section++
+			frame = -1
+			inBlock = true
+			tags[i] = traceLine{section: section, frame: -1}
+		case inBlock && strings.HasPrefix(line, "  File "):
+			frame++
+			tags[i] = traceLine{section: section, frame: frame}
+		case inBlock && frame >= 0 && strings.HasPrefix(line, "    "):
+			tags[i] = traceLine{section: section, frame: frame}
+		default:
+			inBlock = false
+			tags[i] = traceLine{section: section, frame: -1}
+		}
+	}
+	return tags
+}
+
+// Base64Blob replaces long base64-looking strings with a short summary such as
+// "[BINARY ~12 KB]". Truncated base64 is useless to a reader while still eating
+// budget, so it is better to say what was there.
+type Base64Blob struct {
+	MinSize int    // Minimum string length to consider (default: 256)
+	Format  string // Replacement; %d is the decoded size in KB (default: "[BINARY ~%d KB]")
+}
+
+// Transform implements Transformer.
+func (b Base64Blob) Transform(path []string, v interface{}) interface{} {
+	str, ok := v.(string)
+	if !ok {
+		return v
+	}
+	minSize := b.MinSize
+	if minSize <= 0 {
+		minSize = 256
+	}
+	if len(str) < minSize {
+		return v
+	}
+
+	payload := str
+	// Data URIs: data:image/png;base64,....
+	if strings.HasPrefix(payload, "data:") {
+		if i := strings.Index(payload, ";base64,"); i >= 0 {
+			payload = payload[i+len(";base64,"):]
+		}
+	}
+	if !looksBase64(payload) {
+		return v
+	}
+
+	format := b.Format
+	if format == "" {
+		format = "[BINARY ~%d KB]"
+	}
+	kb := (len(payload)*3/4 + 1023) / 1024
+	return fmt.Sprintf(format, kb)
+}
+
+// looksBase64 reports whether s is made only of (std or URL-safe) base64
+// characters with optional padding and MIME line breaks, and mixes character
+// classes the way encoded binary does rather than the way prose does.
+func looksBase64(s string) bool {
+	var upper, lower, digit bool
+	padding := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '=':
+			padding++
+			continue
+		case padding > 0 && c != '\r' && c != '\n':
+			return false // Padding only at the end
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		case c == '+' || c == '/' || c == '-' || c == '_' || c == '\r' || c == '\n':
+		default:
+			return false
+		}
+	}
+	return padding <= 2 && upper && lower && digit
+}
+
+// emailPattern matches a whole value that is a single email address.
+var emailPattern = regexp.MustCompile(`^[^@\s]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`)
+
+// MaskEmail masks email addresses as "j***@example.com", keeping the domain
+// for grouping while hiding the mailbox. With Paths set only values at those
+// (wildcard) paths are masked; otherwise any string that is an email is.
+type MaskEmail struct {
+	Paths []string // Optional paths to restrict masking to (e.g., "users.*.email")
+}
+
+// Transform implements Transformer.
+func (m MaskEmail) Transform(path []string, v interface{}) interface{} {
+	str, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if len(m.Paths) > 0 && !matchesAnyPattern(m.Paths, path) {
+		return v
+	}
+	if !emailPattern.MatchString(str) {
+		return v
+	}
+	at := strings.LastIndexByte(str, '@')
+	_, size := utf8.DecodeRuneInString(str)
+	return str[:size] + "***" + str[at:]
+}
+
+// AnonymizeIP pseudonymizes IP addresses by zeroing the last octet of IPv4
+// addresses and truncating IPv6 addresses to their /64 network, which keeps
+// coarse location/ISP information while meeting GDPR pseudonymization
+// guidance. "host:port" forms keep their port. With Paths set only values at
+// those paths are rewritten; otherwise any string that parses as an IP is.
+type AnonymizeIP struct {
+	Paths []string // Optional paths to restrict anonymization to (e.g., "request.client_ip")
+}
+
+// Transform implements Transformer.
+func (a AnonymizeIP) Transform(path []string, v interface{}) interface{} {
+	str, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if len(a.Paths) > 0 && !matchesAnyPattern(a.Paths, path) {
+		return v
+	}
+	if addr, err := netip.ParseAddr(str); err == nil {
+		return anonymizeAddr(addr).String()
+	}
+	if ap, err := netip.ParseAddrPort(str); err == nil {
+		return netip.AddrPortFrom(anonymizeAddr(ap.Addr()), ap.Port()).String()
+	}
+	return v
+}
+
+// anonymizeAddr masks addr to /24 (IPv4) or /64 (IPv6).
+func anonymizeAddr(addr netip.Addr) netip.Addr {
+	addr = addr.WithZone("").Unmap()
+	bits := 64
+	if addr.Is4() {
+		bits = 24
+	}
+	return netip.PrefixFrom(addr, bits).Masked().Addr()
+}
+
+// CollapseWhitespace replaces each run of whitespace (spaces, tabs,
+// newlines) in long string values with a single space and trims both ends,
+// recovering budget from pretty-printed XML, SQL or JSON embedded as strings
+// before it is truncated. With Paths set only values at those paths are
+// rewritten.
+type CollapseWhitespace struct {
+	MinSize int      // Strings shorter than this many bytes are left alone (default: 256)
+	Paths   []string // Optional paths to restrict collapsing to
+}
+
+// Transform implements Transformer.
+func (c CollapseWhitespace) Transform(path []string, v interface{}) interface{} {
+	str, ok := v.(string)
+	if !ok {
+		return v
+	}
+	minSize := c.MinSize
+	if minSize <= 0 {
+		minSize = 256
+	}
+	if len(str) < minSize {
+		return v
+	}
+	if len(c.Paths) > 0 && !matchesAnyPattern(c.Paths, path) {
+		return v
+	}
+	return strings.Join(strings.Fields(str), " ")
+}
+
+// StripHTML reduces HTML string values (e.g., rendered email bodies) to
+// their text: tags and comments are removed, script and style contents
+// dropped, block elements become line breaks and entities are decoded. With
+// Paths set only values at those paths are rewritten; otherwise any string
+// containing a tag is.
+type StripHTML struct {
+	Paths []string // Optional paths to restrict stripping to (e.g., "email.html_body")
+}
+
+var (
+	htmlTag       = regexp.MustCompile(`(?s)<!--.*?-->|<[a-zA-Z/!][^>]*>`)
+	htmlHidden    = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>`)
+	htmlBlock     = regexp.MustCompile(`(?i)^</?(p|div|br|li|ul|ol|tr|table|h[1-6]|blockquote|pre|hr|section|article|header|footer)\b`)
+	htmlSpaces    = regexp.MustCompile(`[ \t\r\f\x{a0}]+`)
+	htmlLineBreak = regexp.MustCompile(`\s*\n\s*`)
+)
+
+// Transform implements Transformer.
+func (h StripHTML) Transform(path []string, v interface{}) interface{} {
+	str, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if len(h.Paths) > 0 {
+		if !matchesAnyPattern(h.Paths, path) {
+			return v
+		}
+	} else if !htmlTag.MatchString(str) {
+		return v
+	}
+
+	str = htmlHidden.ReplaceAllString(str, "")
+	str = htmlTag.ReplaceAllStringFunc(str, func(tag string) string {
+		if htmlBlock.MatchString(tag) {
+			return "\n"
+		}
+		return ""
+	})
+	str = html.UnescapeString(str)
+	str = htmlSpaces.ReplaceAllString(str, " ")
+	str = htmlLineBreak.ReplaceAllString(str, "\n")
+	return strings.TrimSpace(str)
+}
+
+// RoundFloats rounds non-integer numbers to Digits significant digits
+// (1.2345678901234567 -> 1.2346 with 5), which recovers a lot of budget in
+// telemetry full of high-precision metrics. Whole numbers are never changed,
+// so IDs and counters are safe. With Paths set only values at those paths are
+// rounded.
+type RoundFloats struct {
+	Digits int      // Significant digits to keep (default: 6)
+	Paths  []string // Optional paths to restrict rounding to (e.g., "metrics.*")
+}
+
+// Transform implements Transformer.
+func (r RoundFloats) Transform(path []string, v interface{}) interface{} {
+	var f float64
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case json.Number:
+		var err error
+		if f, err = n.Float64(); err != nil {
+			return v
+		}
+	default:
+		return v
+	}
+	if f == math.Trunc(f) || math.IsInf(f, 0) {
+		return v
+	}
+	if len(r.Paths) > 0 && !matchesAnyPattern(r.Paths, path) {
+		return v
+	}
+	digits := r.Digits
+	if digits <= 0 {
+		digits = 6
+	}
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', digits, 64), 64)
+	if _, ok := v.(json.Number); ok {
+		return json.Number(strconv.FormatFloat(rounded, 'g', -1, 64))
+	}
+	return rounded
+}
+
+// GeoPrecision shrinks GeoJSON-like geometry, which routinely dominates
+// payload size. In every object with a "coordinates" member, numbers are
+// rounded to Decimals places, and with KeepEvery set, lists of points longer
+// than MinPoints keep only every k-th point plus the last (so polygon rings
+// stay closed). With Paths set only objects at those paths are rewritten.
+type GeoPrecision struct {
+	Decimals  int      // Decimal places to keep; 6 is about 11 cm (default: 6)
+	KeepEvery int      // Keep every k-th point of long point lists (default: 0, keep all)
+	MinPoints int      // Point lists this short are never thinned (default: 16)
+	Paths     []string // Optional paths to restrict rewriting to (e.g., "features.*.geometry")
+}
+
+// Transform implements Transformer.
+func (g GeoPrecision) Transform(path []string, v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	coords, ok := m["coordinates"].([]interface{})
+	if !ok {
+		return v
+	}
+	if len(g.Paths) > 0 && !matchesAnyPattern(g.Paths, path) {
+		return v
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, val := range m {
+		out[k] = val
+	}
+	out["coordinates"] = g.coordinates(coords)
+	return out
+}
+
+// coordinates rewrites a position, a list of positions or a nesting of them.
+func (g GeoPrecision) coordinates(arr []interface{}) []interface{} {
+	decimals := g.Decimals
+	if decimals <= 0 {
+		decimals = 6
+	}
+	scale := math.Pow(10, float64(decimals))
+
+	points := len(arr) > 0
+	for _, item := range arr {
+		if _, ok := item.([]interface{}); !ok {
+			points = false
+			break
+		}
+	}
+	minPoints := g.MinPoints
+	if minPoints <= 0 {
+		minPoints = 16
+	}
+	if points && g.KeepEvery > 1 && len(arr) > minPoints && isPosition(arr[0].([]interface{})) {
+		thinned := make([]interface{}, 0, len(arr)/g.KeepEvery+2)
+		for i := 0; i < len(arr)-1; i += g.KeepEvery {
+			thinned = append(thinned, arr[i])
+		}
+		arr = append(thinned, arr[len(arr)-1])
+	}
+
+	out := make([]interface{}, len(arr))
+	for i, item := range arr {
+		switch n := item.(type) {
+		case []interface{}:
+			out[i] = g.coordinates(n)
+		case float64:
+			out[i] = math.Round(n*scale) / scale
+		case json.Number:
+			if f, err := n.Float64(); err == nil {
+				out[i] = json.Number(strconv.FormatFloat(math.Round(f*scale)/scale, 'f', -1, 64))
+			} else {
+				out[i] = n
+			}
+		default:
+			out[i] = item
+		}
+	}
+	return out
+}
+
+// isPosition reports whether arr is a GeoJSON position: an array of numbers.
+func isPosition(arr []interface{}) bool {
+	for _, item := range arr {
+		switch item.(type) {
+		case float64, json.Number:
+		default:
+			return false
+		}
+	}
+	return len(arr) > 0
+}
+
+// stripControl removes ANSI escape sequences (CSI such as colors and cursor
+// movement, OSC such as terminal titles and hyperlinks) and every control
+// character except newline and tab from s.
+func stripControl(s string) string {
+	clean := true
+	for _, r := range s {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == 0x1b && i+1 < len(s) && s[i+1] == '[', r == 0x9b: // CSI: parameters, then a final byte
+			if r == 0x1b {
+				size++
+			}
+			j := i + size
+			for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+				j++
+			}
+			i = min(j+1, len(s))
+			continue
+		case r == 0x1b && i+1 < len(s) && s[i+1] == ']': // OSC: ends with BEL or ESC \
+			j := i + 2
+			for j < len(s) && s[j] != 0x07 && !(s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\') {
+				j++
+			}
+			if j < len(s) && s[j] == 0x1b {
+				j++
+			}
+			i = min(j+1, len(s))
+			continue
+		case r == 0x1b && i+1 < len(s): // Two-character escape
+			i += 2
+			continue
+		case r == '\n' || r == '\t' || !unicode.IsControl(r):
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
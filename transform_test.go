@@ -0,0 +1,256 @@
+package jsontrim
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStackTraceJava(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("java.lang.IllegalStateException: boom\n")
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&b, "\tat com.example.Foo.bar%d(Foo.java:%d)\n", i, i)
+	}
+	b.WriteString("Caused by: java.io.IOException: disk\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&b, "\tat com.example.Disk.read%d(Disk.java:%d)\n", i, i)
+	}
+
+	out := StackTrace{Frames: 3}.Transform(nil, b.String()).(string)
+	if !strings.HasPrefix(out, "java.lang.IllegalStateException: boom\n") {
+		t.Errorf("Lost exception line: %q", out)
+	}
+	if !strings.Contains(out, "bar2(") || strings.Contains(out, "bar3(") {
+		t.Errorf("Expected exactly the first 3 frames: %q", out)
+	}
+	if !strings.Contains(out, "... 17 frames omitted") {
+		t.Errorf("Missing omission note: %q", out)
+	}
+	if !strings.Contains(out, "Caused by: java.io.IOException: disk") || !strings.Contains(out, "read0(") {
+		t.Errorf("Lost cause section: %q", out)
+	}
+}
+
+func TestStackTraceGo(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("panic: runtime error: index out of range\n\ngoroutine 1 [running]:\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&b, "main.f%d(...)\n\t/src/main.go:%d +0x1d\n", i, i)
+	}
+
+	out := StackTrace{Frames: 2}.Transform(nil, b.String()).(string)
+	if !strings.Contains(out, "panic: runtime error") || !strings.Contains(out, "main.f1(") {
+		t.Errorf("Lost panic or kept frames: %q", out)
+	}
+	if strings.Contains(out, "main.f2(") || !strings.Contains(out, "... 8 frames omitted") {
+		t.Errorf("Expected 8 frames dropped: %q", out)
+	}
+}
+
+func TestStackTracePythonKeepsInnermost(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("Traceback (most recent call last):\n")
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(&b, "  File \"app.py\", line %d, in f%d\n    f%d()\n", i, i, i+1)
+	}
+	b.WriteString("ValueError: bad")
+
+	out := StackTrace{Frames: 2}.Transform(nil, b.String()).(string)
+	if strings.Contains(out, "in f0") || !strings.Contains(out, "in f5") || !strings.Contains(out, "in f4") {
+		t.Errorf("Expected innermost two frames: %q", out)
+	}
+	if !strings.HasSuffix(out, "ValueError: bad") {
+		t.Errorf("Lost exception line: %q", out)
+	}
+}
+
+func TestStackTraceLeavesPlainText(t *testing.T) {
+	in := "line one\nline two"
+	if out := (StackTrace{}).Transform(nil, in); out != in {
+		t.Errorf("Plain text changed: %q", out)
+	}
+}
+
+func TestBase64Blob(t *testing.T) {
+	blob := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef, 0x01}, 800))
+
+	if out := (Base64Blob{}).Transform(nil, blob); out != "[BINARY ~4 KB]" {
+		t.Errorf("Expected summary, got %.40q", out)
+	}
+	if out := (Base64Blob{Format: "<%dk>"}).Transform(nil, "data:image/png;base64,"+blob); out != "<4k>" {
+		t.Errorf("Data URI not summarized: %.40q", out)
+	}
+
+	prose := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 10)
+	if out := (Base64Blob{}).Transform(nil, prose); out != prose {
+		t.Error("Prose mistaken for base64")
+	}
+	short := "SGVsbG8gV29ybGQ="
+	if out := (Base64Blob{}).Transform(nil, short); out != short {
+		t.Error("Short base64 below MinSize was replaced")
+	}
+}
+
+func TestMaskEmail(t *testing.T) {
+	if out := (MaskEmail{}).Transform(nil, "jane.doe@example.com"); out != "j***@example.com" {
+		t.Errorf("Unexpected mask: %v", out)
+	}
+	if out := (MaskEmail{}).Transform(nil, "contact me at jane@example.com"); out != "contact me at jane@example.com" {
+		t.Errorf("Free text should not be masked: %v", out)
+	}
+
+	m := MaskEmail{Paths: []string{"users.*.email"}}
+	if out := m.Transform([]string{"users", "0", "email"}, "bob@corp.io"); out != "b***@corp.io" {
+		t.Errorf("Configured path not masked: %v", out)
+	}
+	if out := m.Transform([]string{"owner"}, "bob@corp.io"); out != "bob@corp.io" {
+		t.Errorf("Path outside Paths was masked: %v", out)
+	}
+}
+
+func TestTransformersRunDuringTrim(t *testing.T) {
+	raw := []byte(`{"users":[{"email":"ann@a.com"},{"email":"ben@b.com"}],"note":"x"}`)
+	out, err := New(Config{Transformers: []Transformer{MaskEmail{Paths: []string{"users.*.email"}}}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(out); !strings.Contains(s, "a***@a.com") || !strings.Contains(s, "b***@b.com") {
+		t.Errorf("Transformer not applied to array items: %s", s)
+	}
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.77":                    "203.0.113.0",
+		"2001:db8:85a3:1:2:8a2e:370:7334": "2001:db8:85a3:1::",
+		"::ffff:198.51.100.9":             "198.51.100.0",
+		"203.0.113.77:8443":               "203.0.113.0:8443",
+		"not an ip":                       "not an ip",
+	}
+	for in, want := range cases {
+		if got := (AnonymizeIP{}).Transform(nil, in); got != want {
+			t.Errorf("AnonymizeIP(%q) = %v, want %q", in, got, want)
+		}
+	}
+
+	a := AnonymizeIP{Paths: []string{"client_ip"}}
+	if got := a.Transform([]string{"server_ip"}, "10.1.2.3"); got != "10.1.2.3" {
+		t.Errorf("Path outside Paths was rewritten: %v", got)
+	}
+}
+
+func TestStripControlChars(t *testing.T) {
+	cases := map[string]string{
+		"\x1b[31mERROR\x1b[0m: disk full":               "ERROR: disk full",
+		"\x1b]0;title\x07prompt":                        "prompt",
+		"\x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\ done": "link done",
+		"bell\x07 and\x00 null\r\nnext\tcol":            "bell and null\nnext\tcol",
+		"héllo\u009b1mworld":                            "hélloworld",
+		"plain":                                         "plain",
+	}
+	for in, want := range cases {
+		if got := stripControl(in); got != want {
+			t.Errorf("stripControl(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	out, err := New(Config{StripControlChars: true}).Trim([]byte(`{"msg":"\u001b[1mbold\u001b[0m"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"msg":"bold"}` {
+		t.Errorf("Got %s", out)
+	}
+}
+
+func TestCollapseWhitespace(t *testing.T) {
+	sql := "SELECT id,\n       name\n  FROM users\n\tWHERE id = 1\n"
+	c := CollapseWhitespace{MinSize: 10}
+	if got := c.Transform(nil, sql); got != "SELECT id, name FROM users WHERE id = 1" {
+		t.Errorf("Got %q", got)
+	}
+	if got := c.Transform(nil, "a   b"); got != "a   b" {
+		t.Errorf("Strings under MinSize should be left alone, got %q", got)
+	}
+
+	pretty := "(\n" + strings.Repeat("    value,\n", 40) + ")"
+	raw, _ := json.Marshal(map[string]string{"body": pretty})
+	out, err := New(Config{FieldLimit: 400, Transformers: []Transformer{CollapseWhitespace{}}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "( value, value,") {
+		t.Errorf("Expected the collapsed body to fit the field limit, got %s", out)
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	body := `<html><head><style>p{color:red}</style></head><body>
+<h1>Welcome,&nbsp;Ann</h1><p>Your order <b>#42</b> has   shipped.</p>
+<!-- tracking --><script>track()</script><p>Thanks &amp; bye</p></body></html>`
+	want := "Welcome, Ann\nYour order #42 has shipped.\nThanks & bye"
+	if got := (StripHTML{}).Transform(nil, body); got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+	if got := (StripHTML{}).Transform(nil, "a < b && c > d"); got != "a < b && c > d" {
+		t.Errorf("Text without tags should be left alone, got %q", got)
+	}
+	if got := (StripHTML{Paths: []string{"html"}}).Transform([]string{"note"}, "<b>x</b>"); got != "<b>x</b>" {
+		t.Errorf("Path outside Paths was rewritten: %v", got)
+	}
+}
+
+func TestRoundFloats(t *testing.T) {
+	r := RoundFloats{Digits: 5}
+	cases := map[interface{}]interface{}{
+		1.2345678901234567:            1.2346,
+		0.000123456789:                0.00012346,
+		float64(9007199254740993):     float64(9007199254740993),
+		json.Number("3.14159265358"):  json.Number("3.1416"),
+		json.Number("12345678901234"): json.Number("12345678901234"),
+		"1.23456789":                  "1.23456789",
+	}
+	for in, want := range cases {
+		if got := r.Transform(nil, in); got != want {
+			t.Errorf("RoundFloats(%v) = %v, want %v", in, got, want)
+		}
+	}
+
+	out, err := New(Config{Transformers: []Transformer{RoundFloats{Digits: 3, Paths: []string{"cpu"}}}}).Trim([]byte(`{"cpu":0.87654321,"mem":0.12345}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"cpu":0.877,"mem":0.12345}` {
+		t.Errorf("Got %s", out)
+	}
+}
+
+func TestGeoPrecision(t *testing.T) {
+	raw := []byte(`{"type":"Feature","geometry":{"type":"Point","coordinates":[-122.419415567,37.774929123]},"properties":{"coordinates_note":1.23456789}}`)
+	out, err := New(Config{TotalLimit: 4096, Transformers: []Transformer{GeoPrecision{Decimals: 4}}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"geometry":{"coordinates":[-122.4194,37.7749],"type":"Point"},"properties":{"coordinates_note":1.23456789},"type":"Feature"}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+
+	ring := make([]interface{}, 21)
+	for i := range ring {
+		ring[i] = []interface{}{float64(i), float64(i)}
+	}
+	ring[20] = ring[0]
+	poly := map[string]interface{}{"type": "Polygon", "coordinates": []interface{}{ring}}
+	got := GeoPrecision{KeepEvery: 5}.Transform(nil, poly).(map[string]interface{})
+	thinned := got["coordinates"].([]interface{})[0].([]interface{})
+	if len(thinned) != 5 || fmt.Sprint(thinned[0]) != "[0 0]" || fmt.Sprint(thinned[4]) != "[0 0]" || fmt.Sprint(thinned[1]) != "[5 5]" {
+		t.Errorf("Expected every 5th point with the ring kept closed, got %v", thinned)
+	}
+	if len(ring) != 21 {
+		t.Error("Transform must not modify its input")
+	}
+}
@@ -0,0 +1,382 @@
+package jsontrim
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCycle indicates a value given to TrimValue refers back to itself.
+var ErrCycle = errors.New("value contains a cycle")
+
+// TrimValue trims an in-memory Go value, such as a struct about to be logged.
+// v is converted to the tree encoding/json would produce (exported fields,
+// json tags, omitempty, json.Marshaler and encoding.TextMarshaler), then
+// trimmed and encoded with Codec like any other input.
+//
+// A pointer, map or slice that leads back to itself would recurse until
+// MaxDepth at best. Conversion tracks the ones it's inside of instead, and a
+// cycle fails with ErrCycle, or becomes Marker with ReplaceWithMarker.
+func (t *Trimmer) TrimValue(v interface{}) ([]byte, error) {
+	cur := t.current()
+	if cur.err != nil {
+		return nil, cur.err
+	}
+	tree, err := cur.valueTree(v)
+	if err != nil {
+		return nil, cur.failed(err)
+	}
+	raw, err := cur.cfg.Codec.Encode(tree)
+	if err != nil {
+		return nil, cur.failed(err)
+	}
+	return cur.Trim(raw)
+}
+
+// valueTree converts v to the generic tree.
+func (t *Trimmer) valueTree(v interface{}) (interface{}, error) {
+	w := valueWalker{t: t, inside: make(map[visit]bool)}
+	return w.walk(reflect.ValueOf(v))
+}
+
+// visit identifies a pointer, map or slice being converted. Slices also need
+// their length and type: a slice and its first element share a pointer.
+type visit struct {
+	p   uintptr
+	n   int
+	typ reflect.Type
+}
+
+// valueWalker converts Go values to the generic tree, remembering the
+// references on the path to the current value.
+type valueWalker struct {
+	t      *Trimmer
+	inside map[visit]bool
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	stringerType      = reflect.TypeFor[fmt.Stringer]()
+)
+
+// bytesValue returns b as encoding/json writes it, in base64, which is a
+// third larger than b. With SummarizeBytes it returns "[N bytes]" instead.
+func (t *Trimmer) bytesValue(b []byte) string {
+	if t.cfg.SummarizeBytes {
+		return fmt.Sprintf("[%d bytes]", len(b))
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// plainValue converts the values trimming can't look into but can reason
+// about as strings: time.Time and []byte become the strings encoding/json
+// writes (see bytesValue), and with Stringers, a fmt.Stringer that doesn't marshal itself
+// becomes its String(). Other values are returned as is.
+func (t *Trimmer) plainValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case nil, string, bool, float64, json.Number, markerString, map[string]interface{}, []interface{}:
+		return v
+	case time.Time:
+		return vv.Format(time.RFC3339Nano)
+	case []byte:
+		return t.bytesValue(vv)
+	case json.Marshaler, encoding.TextMarshaler:
+		return v
+	case fmt.Stringer:
+		if t.cfg.Stringers {
+			return vv.String()
+		}
+	}
+	return v
+}
+
+func (w *valueWalker) walk(rv reflect.Value) (interface{}, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	if rv.CanInterface() { // Not for fields promoted through unexported embedded structs
+		if tm, ok := rv.Interface().(time.Time); ok {
+			return tm.Format(time.RFC3339Nano), nil
+		}
+		if out, ok, err := w.marshaled(rv); ok {
+			return out, err
+		}
+		if w.t.cfg.Stringers && rv.Type().Implements(stringerType) && !(rv.Kind() == reflect.Pointer && rv.IsNil()) {
+			return rv.Interface().(fmt.Stringer).String(), nil
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Number(strconv.FormatUint(rv.Uint(), 10)), nil
+	case reflect.Float32:
+		if f := rv.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return json.Number(formatFloat32(f)), nil
+		}
+		return w.t.finite(rv.Float()), nil
+	case reflect.Float64:
+		return w.t.finite(rv.Float()), nil
+	case reflect.String:
+		return rv.String(), nil
+
+	case reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return w.walk(rv.Elem())
+
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return w.enter(visit{rv.Pointer(), 0, rv.Type()}, func() (interface{}, error) { return w.walk(rv.Elem()) })
+
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return w.t.bytesValue(rv.Bytes()), nil
+		}
+		return w.enter(visit{rv.Pointer(), rv.Len(), rv.Type()}, func() (interface{}, error) { return w.items(rv) })
+
+	case reflect.Array:
+		return w.items(rv)
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return w.enter(visit{rv.Pointer(), 0, rv.Type()}, func() (interface{}, error) { return w.entries(rv) })
+
+	case reflect.Struct:
+		out := make(map[string]interface{})
+		for _, f := range structFields(rv.Type()) {
+			fv, err := rv.FieldByIndexErr(f.index)
+			if err != nil || f.omitEmpty && isEmptyValue(fv) {
+				continue // Nil embedded pointer, or empty
+			}
+			val, err := w.walk(fv)
+			if err != nil {
+				return nil, err
+			}
+			out[f.name] = val
+		}
+		return out, nil
+	}
+	return nil, &json.UnsupportedTypeError{Type: rv.Type()}
+}
+
+// formatFloat32 returns the shortest text that reads back as the float32 f,
+// in encoding/json's format, rather than the float64 digits of its widening.
+func formatFloat32(f float64) string {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	s := strconv.FormatFloat(f, format, -1, 32)
+	if format == 'e' {
+		// Clean up e-09 to e-9, as encoding/json does
+		if n := len(s); n >= 4 && s[n-4] == 'e' && s[n-3] == '-' && s[n-2] == '0' {
+			s = s[:n-2] + s[n-1:]
+		}
+	}
+	return s
+}
+
+// enter converts a reference with fn, unless it's already being converted
+// further up: then it's a cycle.
+func (w *valueWalker) enter(v visit, fn func() (interface{}, error)) (interface{}, error) {
+	if w.inside[v] {
+		if w.t.cfg.ReplaceWithMarker {
+			return Marker, nil
+		}
+		return nil, fmt.Errorf("%w via %s", ErrCycle, v.typ)
+	}
+	w.inside[v] = true
+	defer delete(w.inside, v)
+	return fn()
+}
+
+// marshaled converts values that encode themselves, as encoding/json would.
+// ok is false for other values.
+func (w *valueWalker) marshaled(rv reflect.Value) (out interface{}, ok bool, err error) {
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, false, nil
+	}
+	if rv.Type().Implements(jsonMarshalerType) {
+		b, err := rv.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, true, err
+		}
+		out, err = JSONCodec{UseNumber: true}.Decode(b)
+		return out, true, err
+	}
+	if rv.Type().Implements(textMarshalerType) {
+		b, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), true, err
+	}
+	return nil, false, nil
+}
+
+// items converts the elements of a slice or array.
+func (w *valueWalker) items(rv reflect.Value) (interface{}, error) {
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		val, err := w.walk(rv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = val
+	}
+	return out, nil
+}
+
+// entries converts a map, with its keys as encoding/json writes them.
+func (w *valueWalker) entries(rv reflect.Value) (interface{}, error) {
+	out := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		var key string
+		switch {
+		case k.Kind() == reflect.String:
+			key = k.String()
+		case k.Type().Implements(textMarshalerType):
+			b, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			key = string(b)
+		case k.CanInt():
+			key = strconv.FormatInt(k.Int(), 10)
+		case k.CanUint():
+			key = strconv.FormatUint(k.Uint(), 10)
+		case k.Kind() == reflect.Interface && (k.IsNil() || k.Elem().CanInterface()):
+			key = fmt.Sprint(k.Interface()) // As stringKeys formats YAML-style keys
+		default:
+			return nil, &json.UnsupportedTypeError{Type: rv.Type()}
+		}
+		val, err := w.walk(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		out[key] = val
+	}
+	return out, nil
+}
+
+// fieldInfo is a struct field as encoding/json sees it.
+type fieldInfo struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	tagged    bool
+}
+
+var fieldCache sync.Map // reflect.Type -> []fieldInfo
+
+// structFields returns the encoded fields of struct type typ, including
+// those promoted from embedded structs. As with encoding/json, a shallower
+// field hides deeper ones of the same name, and of two at the same depth a
+// tagged one wins; otherwise both are left out.
+func structFields(typ reflect.Type) []fieldInfo {
+	if cached, ok := fieldCache.Load(typ); ok {
+		return cached.([]fieldInfo)
+	}
+	var all []fieldInfo
+	var collect func(typ reflect.Type, index []int, seen map[reflect.Type]bool)
+	collect = func(typ reflect.Type, index []int, seen map[reflect.Type]bool) {
+		if seen[typ] {
+			return
+		}
+		seen[typ] = true
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			idx := append(index[:len(index):len(index)], i)
+			if sf.Anonymous && name == "" {
+				ft := sf.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					collect(ft, idx, seen)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			f := fieldInfo{name: name, index: idx, tagged: name != ""}
+			if name == "" {
+				f.name = sf.Name
+			}
+			f.omitEmpty = strings.Contains(","+opts+",", ",omitempty,")
+			all = append(all, f)
+		}
+	}
+	collect(typ, nil, make(map[reflect.Type]bool))
+
+	// Dominant field per name: shallowest, then tagged
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].name != all[j].name {
+			return all[i].name < all[j].name
+		}
+		if len(all[i].index) != len(all[j].index) {
+			return len(all[i].index) < len(all[j].index)
+		}
+		return all[i].tagged && !all[j].tagged
+	})
+	fields := make([]fieldInfo, 0, len(all))
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].name == all[i].name {
+			j++
+		}
+		first := all[i]
+		if j == i+1 || len(all[i+1].index) > len(first.index) || first.tagged && !all[i+1].tagged {
+			fields = append(fields, first)
+		}
+		i = j
+	}
+	fieldCache.Store(typ, fields)
+	return fields
+}
+
+// isEmptyValue reports whether v is empty for omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
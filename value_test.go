@@ -0,0 +1,108 @@
+package jsontrim
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type node struct {
+	Name   string `json:"name"`
+	Note   string `json:"note,omitempty"`
+	Next   *node  `json:"next,omitempty"`
+	secret string // Unexported, never encoded
+	Parent *node  `json:"-"`
+	Tags   []string
+}
+
+func TestTrimValue(t *testing.T) {
+	n := &node{Name: "a", Tags: []string{"x", strings.Repeat("y", 100)}, secret: "s"}
+	n.Parent = n // Skipped by its tag, so no cycle
+	out, err := New(Config{FieldLimit: 50}).TrimValue(n)
+	if err != nil || string(out) != `{"Tags":["x"],"name":"a"}` {
+		t.Errorf("Expected the struct converted as encoding/json would, got %s (%v)", out, err)
+	}
+}
+
+func TestTrimValueCycle(t *testing.T) {
+	n := &node{Name: "loop"}
+	n.Next = n
+	if _, err := New(Config{}).TrimValue(n); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected ErrCycle, got %v", err)
+	}
+	out, err := New(Config{ReplaceWithMarker: true}).TrimValue(n)
+	if err != nil || string(out) != `{"Tags":null,"name":"loop","next":"[TRIMMED]"}` {
+		t.Errorf("Expected the cycle marked, got %s (%v)", out, err)
+	}
+
+	m := map[string]interface{}{"k": 1}
+	m["self"] = m
+	if _, err := New(Config{}).TrimValue(m); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected ErrCycle for a map, got %v", err)
+	}
+}
+
+type level int
+
+func (l level) String() string { return [...]string{"debug", "info"}[l] }
+
+type event struct {
+	At    time.Time `json:"at"`
+	Level level     `json:"level"`
+	Msg   string    `json:"msg"`
+}
+
+func TestTrimValueTimesAndStringers(t *testing.T) {
+	e := event{At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Level: 1, Msg: "ok"}
+	out, err := New(Config{}).TrimValue(e)
+	if err != nil || string(out) != `{"at":"2026-01-02T03:04:05Z","level":1,"msg":"ok"}` {
+		t.Errorf("Expected time as RFC 3339 and level as a number, got %s (%v)", out, err)
+	}
+	out, err = New(Config{Stringers: true}).TrimValue(e)
+	if err != nil || string(out) != `{"at":"2026-01-02T03:04:05Z","level":"info","msg":"ok"}` {
+		t.Errorf("Expected level as its String(), got %s (%v)", out, err)
+	}
+
+	if got, want := estimateSize(e.At), len(`"2026-01-02T03:04:05Z"`); got != want {
+		t.Errorf("Expected time.Time estimated as its string, got %d, want %d", got, want)
+	}
+	hooked := New(Config{FieldLimit: 10, TruncateStrings: true, Hooks: Hooks{PreTrim: func(interface{}) interface{} {
+		return map[string]interface{}{"at": e.At}
+	}}})
+	if out, err := hooked.Trim([]byte(`{}`)); err != nil || string(out) != `{"at":"2026..."}` {
+		t.Errorf("Expected time.Time truncated as a string, got %s (%v)", out, err)
+	}
+}
+
+func TestTrimValueBytes(t *testing.T) {
+	blob := make([]byte, 300)
+	if got := estimateSize(blob); got != 402 {
+		t.Errorf("Expected []byte estimated as base64, got %d", got)
+	}
+	v := struct {
+		ID   string `json:"id"`
+		Body []byte `json:"body"`
+	}{"a1", blob}
+
+	out, err := New(Config{FieldLimit: 100}).TrimValue(v)
+	if err != nil || string(out) != `{"id":"a1"}` {
+		t.Errorf("Expected the base64 body over FieldLimit, got %s (%v)", out, err)
+	}
+	out, err = New(Config{FieldLimit: 100, SummarizeBytes: true}).TrimValue(v)
+	if err != nil || string(out) != `{"body":"[300 bytes]","id":"a1"}` {
+		t.Errorf("Expected the body summarized, got %s (%v)", out, err)
+	}
+}
+
+func TestTrimValueFloat32AndAnyKeys(t *testing.T) {
+	out, err := New(Config{}).TrimValue(map[string]float32{"a": 0.1, "b": 1e-7, "c": 1e6})
+	if err != nil || string(out) != `{"a":0.1,"b":1e-7,"c":1000000}` {
+		t.Errorf("Expected float32 values written as encoding/json writes them, got %s (%v)", out, err)
+	}
+
+	out, err = New(Config{}).TrimValue(map[interface{}]interface{}{"name": "svc", 1: "one", true: "yes"})
+	if err != nil || string(out) != `{"1":"one","name":"svc","true":"yes"}` {
+		t.Errorf("Expected interface keys formatted, got %s (%v)", out, err)
+	}
+}
@@ -0,0 +1,62 @@
+package jsontrim
+
+import "strings"
+
+// NoiseFirst removes top-level keys starting with one of Prefixes (e.g.,
+// "debug." or "debug.*") before any other key, for wide events where
+// low-value diagnostic fields should go before sampled, high-value ones.
+// Fallback picks among the noise keys, and among the rest once they are gone.
+type NoiseFirst struct {
+	Prefixes []string
+	Fallback TruncStrategy // (default: RemoveLargest)
+}
+
+// SelectNextToRemove for NoiseFirst: Noise keys first, then the fallback.
+func (s NoiseFirst) SelectNextToRemove(v interface{}) string {
+	fallback := s.Fallback
+	if fallback == nil {
+		fallback = RemoveLargest{}
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		noise := make(map[string]interface{})
+		for k, val := range m {
+			if s.isNoise(k) {
+				noise[k] = val
+			}
+		}
+		if len(noise) > 0 {
+			return fallback.SelectNextToRemove(noise)
+		}
+	}
+	return fallback.SelectNextToRemove(v)
+}
+
+// isNoise reports whether key starts with one of the prefixes.
+func (s NoiseFirst) isNoise(key string) bool {
+	for _, p := range s.Prefixes {
+		if strings.HasPrefix(key, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceMaxFields removes top-level fields of an object past MaxFields,
+// picked by the strategy. Fields are always deleted, even with
+// ReplaceWithMarker, since a marker would still count as a field.
+func (t *Trimmer) enforceMaxFields(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for len(m) > t.cfg.MaxFields {
+		k := t.selectNext(m, 0)
+		val, ok := m[k]
+		if !ok {
+			break
+		}
+		delete(m, k)
+		t.record([]string{k}, reasonFieldCount, val)
+	}
+	return m
+}
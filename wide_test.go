@@ -0,0 +1,49 @@
+package jsontrim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMaxFields(t *testing.T) {
+	event := map[string]interface{}{"trace.id": "t", "duration_ms": 12, "user.id": "u"}
+	for i := 0; i < 5; i++ {
+		event[fmt.Sprintf("debug.step%d", i)] = i
+	}
+	raw, _ := json.Marshal(event)
+
+	out, res, err := New(Config{TotalLimit: 4096, MaxFields: 4, Strategy: NoiseFirst{Prefixes: []string{"debug.*"}}}).TrimWithResult(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || got["trace.id"] != "t" || got["user.id"] != "u" || got["duration_ms"] != 12.0 {
+		t.Errorf("Expected the debug fields dropped first, got %v", got)
+	}
+	if len(res.PathsAffected) != 4 {
+		t.Errorf("Expected 4 removals recorded, got %v", res.PathsAffected)
+	}
+}
+
+func TestNoiseFirst(t *testing.T) {
+	raw := []byte(`{"debug.sql":"` + strings.Repeat("q", 100) + `","debug.n":1,"body":"` + strings.Repeat("b", 300) + `","id":1}`)
+	out, err := New(Config{TotalLimit: 340, FieldLimit: 1000, Strategy: NoiseFirst{Prefixes: []string{"debug."}}}).Trim(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(out, &got)
+	if _, ok := got["body"]; !ok || got["debug.sql"] != nil {
+		t.Errorf("Expected noise removed before the larger body, got %s", out)
+	}
+
+	cfg := Profiles.WideEvents()
+	if err := validateConfig(cfg); err != nil {
+		t.Error(err)
+	}
+}
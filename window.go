@@ -0,0 +1,29 @@
+package jsontrim
+
+import "strconv"
+
+// keepLast applies KeepLast to the array arr at path: if a rule matches, the
+// elements before the newest N are dropped, except Required ones. When
+// several rules match, the smallest N wins.
+func (t *Trimmer) keepLast(arr []interface{}, path []string) []interface{} {
+	n, found := 0, false
+	for _, r := range t.keepLastRules {
+		if matchParts(r.parts, path) && (!found || r.limit < n) {
+			n, found = r.limit, true
+		}
+	}
+	if !found || len(arr) <= n {
+		return arr
+	}
+	cut := len(arr) - n
+	out := make([]interface{}, 0, n)
+	for i, item := range arr[:cut] {
+		childPath := append(path, strconv.Itoa(i))
+		if t.protects(childPath) {
+			out = append(out, item)
+			continue
+		}
+		t.record(childPath, reasonKeepLast, item)
+	}
+	return append(out, arr[cut:]...)
+}
@@ -0,0 +1,31 @@
+package jsontrim
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestKeepLast(t *testing.T) {
+	raw := []byte(`{"events":[1,2,3,4,5],"tags":[1,2,3,4,5],"runs":[{"log":[1,2,3]},{"log":[4,5,6]}]}`)
+	trimmer := New(Config{KeepLast: map[string]int{"events": 2, "runs.*.log": 1}})
+	out, res, err := trimmer.TrimWithResult(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"events":[4,5],"runs":[{"log":[3]},{"log":[6]}],"tags":[1,2,3,4,5]}`; string(out) != want {
+		t.Errorf("Got %s, want %s", out, want)
+	}
+	if want := []string{"events.0", "events.1", "events.2", "runs.0.log.0", "runs.0.log.1", "runs.1.log.0", "runs.1.log.1"}; !reflect.DeepEqual(slices.Sorted(slices.Values(res.PathsAffected)), want) {
+		t.Errorf("Got removed paths %v, want %v", res.PathsAffected, want)
+	}
+
+	required := New(Config{KeepLast: map[string]int{"events": 1}, Required: []string{"events.0"}})
+	if out, err := required.Trim(raw); err != nil || string(out)[:20] != `{"events":[1,5],"run` {
+		t.Errorf("Expected the Required first event kept, got %s (%v)", out, err)
+	}
+
+	if _, err := NewBuilder().KeepLast("events", -1).Build(); err == nil {
+		t.Error("Expected a negative KeepLast count rejected")
+	}
+}